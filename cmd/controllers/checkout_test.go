@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/database"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/lockout"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/mailer"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/ratelimit"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+/**
+ * @brief Opens the PostgreSQL database given by TEST_DATABASE_URL and creates its tables.
+ *
+ * The test is skipped when the variable is not set, e.g.
+ * TEST_DATABASE_URL="host=localhost user=postgres password=postgres dbname=newworld_test port=5432 sslmode=disable".
+ * Row locking is what is being tested, so it cannot be replaced by an in-memory database.
+ *
+ * @param t The test.
+ * @return The database connection.
+ */
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	database.Migrate(db)
+	return db
+}
+
+/**
+ * @brief Builds the application with every route registered on the given database and no rate limits.
+ *
+ * @param t The test.
+ * @param db The database connection.
+ * @return The Fiber application.
+ */
+func newTestApp(t *testing.T, db *gorm.DB) *fiber.App {
+	t.Helper()
+
+	keyRing, err := middleware.NewEphemeralKeyRing()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	middleware.SetKeyRing(keyRing)
+
+	guard := lockout.NewGuard(lockout.NewMemoryStore(), lockout.Config{
+		AccountThreshold: 5,
+		IPThreshold:      20,
+		Window:           15 * time.Minute,
+		BaseDelay:        time.Second,
+		MaxDelay:         time.Minute,
+		LockoutDuration:  15 * time.Minute,
+	})
+	tokenConfig := service.TokenConfig{
+		AccessTokenTTL:       15 * time.Minute,
+		RefreshTokenTTL:      time.Hour,
+		EmailVerificationTTL: time.Hour,
+		PasswordResetTTL:     time.Hour,
+	}
+
+	auditRepo := repository.NewAuditRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userService := service.NewUserService(repository.NewUserRepository(db), repository.NewTokenRepository(db), repository.NewSessionRepository(db), auditRepo, repository.NewIdentityRepository(db), repository.NewTwoFactorRepository(db), apiKeyRepo, guard, nil, mailer.NewLogMailer(os.DevNull), tokenConfig)
+	offerService := service.NewOfferService(repository.NewOfferRepository(db))
+	orderService := service.NewOrderService(repository.NewOrderRepository(db))
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, auditRepo)
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	RegisterRoutes(app, userService, offerService, orderService, apiKeyService, ratelimit.NewMemoryStore(), ratelimit.Config{})
+	return app
+}
+
+/**
+ * @brief Creates a buyer with a verified email and a session, and returns an access token of it.
+ *
+ * @param t The test.
+ * @param db The database connection.
+ * @param name A name unique to the test run, used as username and in the email.
+ * @return The user and the access token.
+ */
+func createTestBuyer(t *testing.T, db *gorm.DB, name string) (*models.User, string) {
+	t.Helper()
+
+	now := time.Now()
+	user := &models.User{Username: name, Email: name + "@example.com", Role: models.RoleBuyer, EmailVerifiedAt: &now}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	session := &models.Session{UserID: user.ID, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+	if err := db.Create(session).Error; err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+
+	token, err := middleware.GenerateJWT(user.ID, session.ID, user.Email, user.Role, false, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	return user, token.Token
+}
+
+/**
+ * @brief Fires concurrent checkouts at one offer with limited stock and checks that it is never oversold.
+ */
+func TestCheckoutDoesNotOversellUnderConcurrency(t *testing.T) {
+	db := openTestDB(t)
+	app := newTestApp(t, db)
+
+	const (
+		initialStock = 25
+		buyers       = 40
+	)
+
+	name := fmt.Sprintf("checkout_%d", time.Now().UnixNano())
+	user, token := createTestBuyer(t, db, name)
+	offer := &models.Offer{Name: name, Quantity: initialStock, Price: 7, Category: "test"}
+	if err := db.Create(offer).Error; err != nil {
+		t.Fatalf("failed to create offer: %v", err)
+	}
+	t.Cleanup(func() {
+		orders := db.Model(&models.Order{}).Select("id").Where("user_id = ?", user.ID)
+		db.Unscoped().Where("order_id IN (?)", orders).Delete(&models.OrderItem{})
+		db.Unscoped().Where("order_id IN (?)", orders).Delete(&models.OrderStatusHistory{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Order{})
+		db.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Session{})
+		db.Unscoped().Delete(offer)
+		db.Unscoped().Delete(user)
+	})
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		ordered int
+	)
+	for i := 0; i < buyers; i++ {
+		quantity := i%3 + 1
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			body, _ := json.Marshal(models.CheckoutRequest{OrderItems: []models.OrderItemRequest{{ProductID: offer.ID, Quantity: quantity}}})
+			req := httptest.NewRequest(http.MethodPost, "/auth/checkout", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", token)
+
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Errorf("checkout request failed: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+
+			switch resp.StatusCode {
+			case http.StatusOK:
+				mu.Lock()
+				ordered += quantity
+				mu.Unlock()
+			case http.StatusConflict:
+			default:
+				t.Errorf("unexpected checkout status %d", resp.StatusCode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var remaining models.Offer
+	if err := db.First(&remaining, offer.ID).Error; err != nil {
+		t.Fatalf("failed to reload offer: %v", err)
+	}
+	if remaining.Quantity < 0 {
+		t.Fatalf("stock went negative: %d", remaining.Quantity)
+	}
+
+	var stored int
+	err := db.Model(&models.OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND order_items.product_id = ?", user.ID, offer.ID).
+		Select("COALESCE(SUM(order_items.quantity), 0)").
+		Row().Scan(&stored)
+	if err != nil {
+		t.Fatalf("failed to sum ordered quantities: %v", err)
+	}
+
+	if sold := initialStock - remaining.Quantity; stored != sold || ordered != sold {
+		t.Fatalf("stock decreased by %d, but %d units were stored in orders and %d were confirmed", sold, stored, ordered)
+	}
+	if ordered == 0 {
+		t.Fatalf("no checkout succeeded")
+	}
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+/**
+ * @brief Central error handler of the application, turning the errors returned by handlers into responses.
+ *
+ * Domain errors are reported with the HTTP status of their kind and their
+ * machine-readable code, and with a Retry-After header if they can be
+ * retried. Errors raised by Fiber itself, such as unknown routes, keep their
+ * status. Any other error is logged and reported as an internal error, without
+ * leaking its details to the client.
+ *
+ * @param c The Fiber context.
+ * @param err The error returned by the handler.
+ * @return An error if the response cannot be written.
+ */
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		if appErr.RetryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.FormatInt(int64(math.Ceil(appErr.RetryAfter.Seconds())), 10))
+		}
+		return writeError(c, appErr.Status(), appErr.Code, appErr.Message, appErr.Fields)
+	}
+
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) && fiberErr.Code < fiber.StatusInternalServerError {
+		return writeError(c, fiberErr.Code, statusErrorCode(fiberErr.Code), fiberErr.Message, nil)
+	}
+
+	log.Printf("Unhandled error on %s %s: %v", c.Method(), c.Path(), err)
+	return writeError(c, fiber.StatusInternalServerError, "internal_error", "internal server error", nil)
+}
+
+/**
+ * @brief Writes the error envelope shared by every failed request.
+ *
+ * @param c The Fiber context.
+ * @param status The HTTP status of the response.
+ * @param code The machine-readable error code.
+ * @param message The human readable message.
+ * @param fields The failing fields of a request that failed validation, if any.
+ * @return An error if the response cannot be written.
+ */
+func writeError(c *fiber.Ctx, status int, code, message string, fields []models.FieldError) error {
+	return c.Status(status).JSON(models.ErrorResponse{
+		Code:    strconv.Itoa(status),
+		Error:   code,
+		Message: message,
+		Errors:  fields,
+	})
+}
+
+/**
+ * @brief Derives a machine-readable error code from an HTTP status, e.g. "method_not_allowed" for 405.
+ *
+ * @param status The HTTP status.
+ * @return The error code.
+ */
+func statusErrorCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(utils.StatusMessage(status)), " ", "_")
+}
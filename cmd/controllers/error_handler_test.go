@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+/**
+ * @brief Checks that a rate limited request is answered with 429, its RateLimit-* headers and a Retry-After header.
+ */
+func TestRateLimitedResponse(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Post("/auth/login", middleware.RateLimit(ratelimit.NewMemoryStore(), "login", ratelimit.Limit{Requests: 2, Period: time.Minute}, middleware.ClientIPKey), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	var resp *http.Response
+	for i := 0; i < 3; i++ {
+		var err error
+		resp, err = app.Test(httptest.NewRequest(http.MethodPost, "/auth/login", nil), -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", resp.StatusCode)
+	}
+	want := map[string]string{
+		"Retry-After":         "30",
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "60",
+		"RateLimit-Policy":    "2;w=60",
+	}
+	for name, value := range want {
+		if got := resp.Header.Get(name); got != value {
+			t.Errorf("expected %s %q, got %q", name, value, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks the status, envelope and Retry-After header the error handler answers each kind of error with.
+ */
+func TestErrorHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		status     int
+		body       models.ErrorResponse
+		retryAfter string
+	}{
+		{
+			"domain error",
+			apperror.NotFound("order_not_found", "order not found"),
+			http.StatusNotFound,
+			models.ErrorResponse{Code: "404", Error: "order_not_found", Message: "order not found"},
+			"",
+		},
+		{
+			"wrapped domain error",
+			fmt.Errorf("checkout: %w", apperror.InsufficientStock("insufficient_stock", "not enough stock")),
+			http.StatusConflict,
+			models.ErrorResponse{Code: "409", Error: "insufficient_stock", Message: "not enough stock"},
+			"",
+		},
+		{
+			"validation error",
+			apperror.Validation([]models.FieldError{{Field: "orderItems[0].quantity", Reason: "must be greater than 0"}}),
+			http.StatusBadRequest,
+			models.ErrorResponse{Code: "400", Error: "validation_failed", Message: "request validation failed", Errors: []models.FieldError{{Field: "orderItems[0].quantity", Reason: "must be greater than 0"}}},
+			"",
+		},
+		{
+			"retry after whole seconds",
+			apperror.TooManyRequests("rate_limited", "too many requests", 2*time.Second),
+			http.StatusTooManyRequests,
+			models.ErrorResponse{Code: "429", Error: "rate_limited", Message: "too many requests"},
+			"2",
+		},
+		{
+			"retry after a fraction of a second",
+			apperror.TooManyRequests("rate_limited", "too many requests", 100*time.Millisecond),
+			http.StatusTooManyRequests,
+			models.ErrorResponse{Code: "429", Error: "rate_limited", Message: "too many requests"},
+			"1",
+		},
+		{
+			"retry after rounded up",
+			apperror.TooManyRequests("too_many_login_attempts", "too many failed login attempts", 2*time.Second+time.Millisecond),
+			http.StatusTooManyRequests,
+			models.ErrorResponse{Code: "429", Error: "too_many_login_attempts", Message: "too many failed login attempts"},
+			"3",
+		},
+		{
+			"fiber error",
+			fiber.ErrMethodNotAllowed,
+			http.StatusMethodNotAllowed,
+			models.ErrorResponse{Code: "405", Error: "method_not_allowed", Message: "Method Not Allowed"},
+			"",
+		},
+		{
+			"unexpected error",
+			errors.New("pq: connection refused"),
+			http.StatusInternalServerError,
+			models.ErrorResponse{Code: "500", Error: "internal_error", Message: "internal server error"},
+			"",
+		},
+	}
+	for _, tc := range cases {
+		app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+		err := tc.err
+		app.Get("/", func(c *fiber.Ctx) error {
+			return err
+		})
+
+		resp, reqErr := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+		if reqErr != nil {
+			t.Fatalf("%s: request failed: %v", tc.name, reqErr)
+		}
+		var body models.ErrorResponse
+		decodeErr := json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if decodeErr != nil {
+			t.Fatalf("%s: failed to decode body: %v", tc.name, decodeErr)
+		}
+
+		if resp.StatusCode != tc.status {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.status, resp.StatusCode)
+		}
+		if !reflect.DeepEqual(body, tc.body) {
+			t.Errorf("%s: expected body %+v, got %+v", tc.name, tc.body, body)
+		}
+		if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tc.retryAfter {
+			t.Errorf("%s: expected Retry-After %q, got %q", tc.name, tc.retryAfter, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+/**
+ * @brief Checks that an error reported by the identity provider is answered with a fixed message rather than echoed.
+ */
+func TestOIDCCallbackDoesNotEchoProviderError(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/auth/oidc/callback", OIDCCallback)
+
+	query := url.Values{}
+	query.Set("error", "<script>alert(1)</script>")
+	query.Set("error_description", "call +1 555 0100 to unlock your account")
+	query.Set("state", "state")
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query.Encode(), nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var body models.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	want := models.ErrorResponse{Code: "401", Error: "oidc_login_failed", Message: "identity provider login failed"}
+	if resp.StatusCode != http.StatusUnauthorized || body.Code != want.Code || body.Error != want.Error || body.Message != want.Message {
+		t.Fatalf("expected %+v, got status %d and %+v", want, resp.StatusCode, body)
+	}
+}
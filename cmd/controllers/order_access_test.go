@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apikey"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/service"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type stubUserRepository struct {
+	repository.UserRepository
+	users map[uint]*models.User
+}
+
+func (r *stubUserRepository) GetUserByID(id uint) (*models.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type stubTokenRepository struct {
+	repository.TokenRepository
+}
+
+func (r *stubTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	return false, nil
+}
+
+type stubSessionRepository struct {
+	repository.SessionRepository
+}
+
+func (r *stubSessionRepository) TouchSession(id, userID uint, lastSeenAt time.Time, interval time.Duration) error {
+	return nil
+}
+
+type stubAPIKeyRepository struct {
+	repository.APIKeyRepository
+	keys map[string]*models.APIKey
+}
+
+func (r *stubAPIKeyRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	if key, ok := r.keys[prefix]; ok {
+		return key, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *stubAPIKeyRepository) TouchAPIKey(id uint, usedAt time.Time, interval time.Duration) error {
+	return nil
+}
+
+/**
+ * @struct stubOrderRepository
+ * @brief Order repository holding a single order, enough to read and cancel it.
+ */
+type stubOrderRepository struct {
+	repository.OrderRepository
+	order *models.Order
+}
+
+func (r *stubOrderRepository) Transaction(fn func(repo repository.OrderRepository) error) error {
+	return fn(r)
+}
+
+func (r *stubOrderRepository) GetOrderWithItems(id uint) (*models.Order, error) {
+	if id != r.order.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.order, nil
+}
+
+func (r *stubOrderRepository) GetOrderForUpdate(id uint) (*models.Order, error) {
+	return r.GetOrderWithItems(id)
+}
+
+func (r *stubOrderRepository) GetStatusHistory(orderID uint) ([]models.OrderStatusHistory, error) {
+	return nil, nil
+}
+
+func (r *stubOrderRepository) IncrementOfferQuantity(id uint, quantity int) error {
+	return nil
+}
+
+func (r *stubOrderRepository) CancelOrder(id uint, cancelledBy uint, reason string, cancelledAt time.Time) error {
+	r.order.Status = models.OrderStatusCancelled
+	return nil
+}
+
+func (r *stubOrderRepository) CreateStatusHistory(entry *models.OrderStatusHistory) error {
+	return nil
+}
+
+/**
+ * @brief Builds an application serving the order routes of a buyer's order, with an admin who owns an API key.
+ *
+ * @param t The test.
+ * @return The application, the ID of the admin, the admin's API key and the stored order.
+ */
+func newOrderAccessApp(t *testing.T) (*fiber.App, uint, string, *models.Order) {
+	t.Helper()
+
+	keyRing, err := middleware.NewEphemeralKeyRing()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	middleware.SetKeyRing(keyRing)
+
+	now := time.Now()
+	admin := &models.User{Model: gorm.Model{ID: 1}, Email: "admin@example.com", Role: models.RoleAdmin, EmailVerifiedAt: &now, TOTPEnabledAt: &now}
+	buyer := &models.User{Model: gorm.Model{ID: 2}, Email: "buyer@example.com", Role: models.RoleBuyer, EmailVerifiedAt: &now}
+	order := &models.Order{Model: gorm.Model{ID: 10}, UserID: buyer.ID, Status: models.OrderStatusPending}
+
+	generated, err := apikey.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+	key := &models.APIKey{
+		Model:   gorm.Model{ID: 5},
+		UserID:  admin.ID,
+		Prefix:  generated.Prefix,
+		KeyHash: generated.Hash,
+		Scopes:  models.ScopeOrdersRead + " " + models.ScopeOrdersWrite,
+	}
+
+	orderService = service.NewOrderService(&stubOrderRepository{order: order})
+	auth := middleware.RequireAuthOrAPIKey(
+		&stubUserRepository{users: map[uint]*models.User{admin.ID: admin, buyer.ID: buyer}},
+		&stubTokenRepository{},
+		&stubSessionRepository{},
+		&stubAPIKeyRepository{keys: map[string]*models.APIKey{key.Prefix: key}},
+	)
+
+	app := fiber.New(fiber.Config{ErrorHandler: ErrorHandler})
+	app.Get("/auth/orders/:id", auth, middleware.RequireScope(models.ScopeOrdersRead), GetOrder)
+	app.Post("/auth/orders/:id/cancel", auth, middleware.RequireScope(models.ScopeOrdersWrite), CancelOrder)
+	return app, admin.ID, generated.Key, order
+}
+
+/**
+ * @brief Sends a request to the application with the given credential header.
+ *
+ * @param t The test.
+ * @param app The application.
+ * @param method The HTTP method.
+ * @param path The path of the route.
+ * @param header The name of the header carrying the credential.
+ * @param credential The JWT or API key.
+ * @return The status of the response.
+ */
+func orderRequest(t *testing.T, app *fiber.App, method, path, header, credential string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set(header, credential)
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request to %s failed: %v", path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+/**
+ * @brief Checks that admins only reach the orders of other users after a two-factor login, and never with an API key.
+ */
+func TestAdminAccessToOtherOrdersRequiresTwoFactor(t *testing.T) {
+	app, adminID, key, order := newOrderAccessApp(t)
+
+	passwordOnly, err := middleware.GenerateJWT(adminID, 1, "admin@example.com", models.RoleAdmin, false, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	twoFactor, err := middleware.GenerateJWT(adminID, 1, "admin@example.com", models.RoleAdmin, true, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		header     string
+		credential string
+		want       int
+	}{
+		{"password-only JWT reads", http.MethodGet, "/auth/orders/10", "Authorization", passwordOnly.Token, http.StatusNotFound},
+		{"password-only JWT cancels", http.MethodPost, "/auth/orders/10/cancel", "Authorization", passwordOnly.Token, http.StatusNotFound},
+		{"API key reads", http.MethodGet, "/auth/orders/10", middleware.APIKeyHeader, key, http.StatusNotFound},
+		{"API key cancels", http.MethodPost, "/auth/orders/10/cancel", middleware.APIKeyHeader, key, http.StatusNotFound},
+		{"two-factor JWT reads", http.MethodGet, "/auth/orders/10", "Authorization", twoFactor.Token, http.StatusOK},
+	}
+	for _, tc := range cases {
+		if got := orderRequest(t, app, tc.method, tc.path, tc.header, tc.credential); got != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, got)
+		}
+	}
+	if order.Status != models.OrderStatusPending {
+		t.Fatalf("expected the order to stay pending, got %q", order.Status)
+	}
+
+	if got := orderRequest(t, app, http.MethodPost, "/auth/orders/10/cancel", "Authorization", twoFactor.Token); got != http.StatusOK {
+		t.Fatalf("expected a two-factor admin to cancel the order, got status %d", got)
+	}
+}
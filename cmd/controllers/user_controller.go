@@ -1,17 +1,24 @@
 package controllers
 
 import (
+	"log"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/ratelimit"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/service"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/validation"
 	"github.com/gofiber/fiber/v2"
 )
 
 var (
-	userService  service.UserService
-	offerService service.OfferService
-	orderService service.OrderService
+	userService   service.UserService
+	offerService  service.OfferService
+	orderService  service.OrderService
+	apiKeyService service.APIKeyService
 )
 
 /**
@@ -21,38 +28,165 @@ var (
  * @param us The user service to handle user-related operations.
  * @param os The offer service to handle offer-related operations.
  * @param ords The order service to handle order-related operations.
+ * @param aks The API key service to handle API key-related operations.
+ * @param limiter The store of the rate limit buckets.
+ * @param limits The rate limits applied to the routes.
  */
-func RegisterRoutes(app *fiber.App, us service.UserService, os service.OfferService, ords service.OrderService) {
+func RegisterRoutes(app *fiber.App, us service.UserService, os service.OfferService, ords service.OrderService, aks service.APIKeyService, limiter ratelimit.Store, limits ratelimit.Config) {
 	userService = us
 	offerService = os
 	orderService = ords
+	apiKeyService = aks
+
+	userRepo := repository.NewUserRepository(us.GetDB())
+	tokenRepo := repository.NewTokenRepository(us.GetDB())
+	sessionRepo := repository.NewSessionRepository(us.GetDB())
+	requireAuth := middleware.RequireAuth(userRepo, tokenRepo, sessionRepo)
+	requireAuthOrAPIKey := middleware.RequireAuthOrAPIKey(userRepo, tokenRepo, sessionRepo, repository.NewAPIKeyRepository(us.GetDB()))
+	userLimit := middleware.RateLimit(limiter, "user", limits.User, middleware.UserKey)
+
+	app.Use(middleware.RateLimit(limiter, "global", limits.Global, middleware.ClientIPKey))
+
+	app.Get("/.well-known/jwks.json", GetJWKS)
+	app.Post("/auth/register", middleware.RateLimit(limiter, "register", limits.Register, middleware.ClientIPKey), Register)
+	app.Post("/auth/login", middleware.RateLimit(limiter, "login", limits.Login, middleware.ClientIPKey), Login)
+	app.Get("/auth/oidc/login", middleware.RateLimit(limiter, "oidc_login", limits.Login, middleware.ClientIPKey), StartOIDCLogin)
+	app.Get("/auth/oidc/callback", middleware.RateLimit(limiter, "oidc_callback", limits.Login, middleware.ClientIPKey), OIDCCallback)
+	app.Post("/auth/login/totp", middleware.RateLimit(limiter, "login_totp", limits.Login, middleware.ClientIPKey), TwoFactorLogin)
+	app.Post("/auth/verify-email", middleware.RateLimit(limiter, "verify_email", limits.EmailToken, middleware.ClientIPKey), VerifyEmail)
+	app.Post("/auth/verify-email/resend", middleware.Protected(), requireAuth, userLimit, ResendVerificationEmail)
+	app.Post("/auth/refresh", middleware.RateLimit(limiter, "refresh", limits.Refresh, middleware.ClientIPKey), Refresh)
+	app.Post("/auth/password/forgot", middleware.RateLimit(limiter, "forgot_password", limits.ForgotPassword, middleware.ClientIPKey), middleware.RateLimit(limiter, "forgot_password_email", limits.ForgotPasswordEmail, middleware.BodyEmailKey), ForgotPassword)
+	app.Post("/auth/password/reset", middleware.RateLimit(limiter, "password_reset", limits.EmailToken, middleware.ClientIPKey), ResetPassword)
+	app.Post("/auth/logout", middleware.Protected(), requireAuth, userLimit, Logout)
+	app.Post("/auth/logout/all", middleware.Protected(), requireAuth, userLimit, LogoutAll)
+	app.Get("/auth/sessions", middleware.Protected(), requireAuth, userLimit, GetSessions)
+	app.Delete("/auth/sessions/:id", middleware.Protected(), requireAuth, userLimit, RevokeSession)
+	app.Get("/auth/me", middleware.Protected(), requireAuth, userLimit, GetProfile)
+	app.Patch("/auth/me", middleware.Protected(), requireAuth, userLimit, UpdateProfile)
+	app.Delete("/auth/me", middleware.Protected(), requireAuth, userLimit, DeleteAccount)
+	app.Post("/auth/password/change", middleware.Protected(), requireAuth, userLimit, ChangePassword)
+	app.Post("/auth/totp/enroll", middleware.Protected(), requireAuth, userLimit, EnrollTOTP)
+	app.Post("/auth/totp/confirm", middleware.Protected(), requireAuth, userLimit, ConfirmTOTP)
+	app.Delete("/auth/totp", middleware.Protected(), requireAuth, userLimit, DisableTOTP)
+	app.Post("/auth/totp/recovery-codes", middleware.Protected(), requireAuth, userLimit, RegenerateRecoveryCodes)
+	app.Post("/auth/api-keys", middleware.Protected(), requireAuth, userLimit, middleware.RequireTwoFactorFor(models.RoleAdmin), CreateAPIKey)
+	app.Get("/auth/api-keys", middleware.Protected(), requireAuth, userLimit, GetAPIKeys)
+	app.Delete("/auth/api-keys/:id", middleware.Protected(), requireAuth, userLimit, RevokeAPIKey)
+	app.Get("/auth/offers", requireAuthOrAPIKey, userLimit, middleware.RequireScope(models.ScopeOffersRead), GetOffers)
+	app.Post("/auth/checkout", requireAuthOrAPIKey, userLimit, middleware.RequireScope(models.ScopeOrdersWrite), middleware.RequireVerifiedEmail(), middleware.RateLimit(limiter, "checkout", limits.Checkout, middleware.UserKey), Checkout)
+	app.Get("/auth/orders", requireAuthOrAPIKey, userLimit, middleware.RequireScope(models.ScopeOrdersRead), GetOrders)
+	app.Get("/auth/orders/:id", requireAuthOrAPIKey, userLimit, middleware.RequireScope(models.ScopeOrdersRead), GetOrder)
+	app.Post("/auth/orders/:id/cancel", requireAuthOrAPIKey, userLimit, middleware.RequireScope(models.ScopeOrdersWrite), CancelOrder)
+
+	admin := app.Group("/admin", middleware.Protected(), requireAuth, userLimit, middleware.RequireRole(models.RoleAdmin), middleware.RequireTwoFactor())
+	admin.Get("/dashboard", AdminDashboard)
+	admin.Patch("/orders/:id", UpdateOrderStatus)
+	admin.Get("/orders/:id/history", GetOrderStatusHistory)
+	admin.Get("/users", SearchUsers)
+	admin.Delete("/users", RemoveCustomer)
+	admin.Get("/users/deleted", SearchDeletedUsers)
+	admin.Get("/users/:id", GetUser)
+	admin.Patch("/users/:id", UpdateUser)
+	admin.Post("/users/:id/suspend", SuspendUser)
+	admin.Post("/users/:id/reactivate", ReactivateUser)
+	admin.Post("/users/:id/password-reset", ForcePasswordReset)
+	admin.Put("/users/:id/role", UpdateUserRole)
+	admin.Get("/users/:id/sessions", GetUserSessions)
+	admin.Post("/users/:id/unlock", UnlockUser)
+	admin.Post("/users/:id/restore", RestoreUser)
+	admin.Delete("/users/:id/totp", ResetTOTP)
+	admin.Get("/audit", GetAuditLog)
+
+}
+
+/**
+ * @brief Parses the JSON body of a request and validates it against the rules declared on its structure.
+ *
+ * @param c The Fiber context.
+ * @param request A pointer to the request structure to fill.
+ * @return A validation error listing every failing field, or nil if the body is valid.
+ */
+func parseBody(c *fiber.Ctx, request interface{}) error {
+	if err := c.BodyParser(request); err != nil {
+		return apperror.Validation([]models.FieldError{{Field: "body", Reason: "must be a valid JSON object"}})
+	}
+
+	if fieldErrors := validation.Struct(request); fieldErrors != nil {
+		return apperror.Validation(fieldErrors)
+	}
+	return nil
+}
+
+/**
+ * @brief Builds the error returned for a malformed date query parameter.
+ *
+ * @param name The name of the query parameter.
+ * @return A validation error for the parameter.
+ */
+func invalidDateParam(name string) error {
+	return apperror.Validation([]models.FieldError{{Field: name, Reason: "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"}})
+}
+
+/**
+ * @brief Parses a date query parameter, given either as YYYY-MM-DD or as an RFC 3339 timestamp.
+ *
+ * @param value The value of the query parameter.
+ * @param endOfDay Whether a plain date should be moved to the end of that day, making it an inclusive upper bound.
+ * @return The parsed time, or nil if the parameter is empty, and an error if the value is malformed.
+ */
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		if endOfDay {
+			date = date.AddDate(0, 0, 1)
+		}
+		return &date, nil
+	}
+
+	timestamp, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &timestamp, nil
+}
 
-	app.Post("/auth/register", Register)
-	app.Post("/auth/login", Login)
-	app.Get("/auth/offers", middleware.Protected(), GetOffers)
-	app.Post("/auth/checkout", middleware.Protected(), Checkout)
-	app.Get("/auth/orders/:id", middleware.Protected(), GetOrderStatus)
-	app.Get("/admin/dashboard", middleware.Protected(), AdminDashboard)
-	app.Patch("/admin/orders/:id", middleware.Protected(), UpdateOrderStatus)
-	app.Get("/admin/users", middleware.Protected(), GetAllBuyers)
-	app.Delete("/admin/users", middleware.Protected(), RemoveCustomer)
+// @Summary Get the token signing keys
+// @Description Get the public keys used to verify the JWTs issued by the API, as a JSON Web Key Set. The key of a token is given by its kid header.
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.JSONWebKeySet "keys"
+// @Failure 503 {object} models.ErrorResponse "Signing keys not loaded"
+// @Router /.well-known/jwks.json [get]
+func GetJWKS(c *fiber.Ctx) error {
+	keyRing := middleware.CurrentKeyRing()
+	if keyRing == nil {
+		return apperror.Unavailable("signing_keys_unavailable", "the token signing keys are not loaded")
+	}
 
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return c.Status(fiber.StatusOK).JSON(keyRing.JWKS())
 }
 
 // @Summary Register a new user
-// @Description Register a new user with the given details
+// @Description Register a new user with the given details. The account starts with an unverified email, and a verification link is sent to it.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param user body models.RegisterUserRequest true "User"
 // @Success 201 {object} models.Response "User added"
-// @Failure 400 {object} models.Response "Bad request"
-// @Failure 500 {object} models.Response "Bad server"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 409 {object} models.ErrorResponse "Email or username already in use"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
 // @Router /auth/register [post]
 func Register(c *fiber.Ctx) error {
 	request := new(models.RegisterUserRequest)
-	if err := c.BodyParser(request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.Response{Code: "400", Message: "Bad request"})
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
 	user := &models.User{
@@ -63,12 +197,60 @@ func Register(c *fiber.Ctx) error {
 
 	err := userService.CreateUser(user)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "400", Message: "Bad request"})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(models.Response{Code: "201", Message: "User added"})
 }
 
+// @Summary Verify the email of a user
+// @Description Verify the email of an account with the token of the link sent at registration
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param verifyEmail body models.VerifyEmailRequest true "Verify Email Request"
+// @Success 200 {object} models.Response "Email verified"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/verify-email [post]
+func VerifyEmail(c *fiber.Ctx) error {
+	request := new(models.VerifyEmailRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	err := userService.VerifyEmail(request.Token)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Email verified"})
+}
+
+// @Summary Resend the email verification link
+// @Description Send a new verification link to the email of the user, invalidating the previous one
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Success 202 {object} models.Response "Verification email sent"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Email already verified"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/verify-email/resend [post]
+func ResendVerificationEmail(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	err := userService.ResendVerificationEmail(principal.UserID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.Response{Code: "202", Message: "Verification email sent"})
+}
+
 // @Summary Login a user
 // @Description Login a user with the given credentials
 // @Tags auth
@@ -76,253 +258,1402 @@ func Register(c *fiber.Ctx) error {
 // @Produce json
 // @Param login body models.LoginRequest true "Login Request"
 // @Success 200 {object} models.LoginResponse "token"
-// @Failure 400 {object} models.Response "Bad request"
-// @Failure 500 {object} models.Response "Bad server"
+// @Success 202 {object} models.LoginChallengeResponse "Two-factor authentication required"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Invalid credentials"
+// @Failure 429 {object} models.ErrorResponse "Too many failed login attempts or rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
 // @Router /auth/login [post]
 func Login(c *fiber.Ctx) error {
 	login := new(models.LoginRequest)
-	if err := c.BodyParser(login); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.Response{Code: "400", Message: "Bad request"})
+	if err := parseBody(c, login); err != nil {
+		return err
 	}
 
-	token, err := userService.LoginUser(login)
+	tokens, err := userService.LoginUser(login, clientInfo(c))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "500", Message: "Bad server"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.LoginResponse{Code: "200", Auth: token})
+	if tokens.ChallengeToken != "" {
+		return c.Status(fiber.StatusAccepted).JSON(newLoginChallengeResponse(tokens))
+	}
+	return c.Status(fiber.StatusOK).JSON(newLoginResponse(tokens))
 }
 
-// @Summary Get available offers
-// @Description Get all available offers
+// @Summary Confirm a login with a second factor
+// @Description Complete the challenge returned by a login of a user who enrolled in two-factor authentication, with a code of their authenticator app or one of their recovery codes. Wrong codes count towards the login lockout.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param login body models.TwoFactorLoginRequest true "Two-Factor Login Request"
+// @Success 200 {object} models.LoginResponse "token"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Invalid challenge or code"
+// @Failure 403 {object} models.ErrorResponse "Account suspended"
+// @Failure 429 {object} models.ErrorResponse "Too many failed login attempts or rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/login/totp [post]
+func TwoFactorLogin(c *fiber.Ctx) error {
+	request := new(models.TwoFactorLoginRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	tokens, err := userService.CompleteTwoFactorLogin(request.ChallengeToken, request.Code, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newLoginResponse(tokens))
+}
+
+// @Summary Login with the identity provider
+// @Description Redirect the user to the configured OpenID Connect provider to log in. The login has to be completed at the callback within 10 minutes.
+// @Tags auth
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} models.ErrorResponse "No identity provider is configured"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/oidc/login [get]
+func StartOIDCLogin(c *fiber.Ctx) error {
+	url, err := userService.StartOIDCLogin()
+	if err != nil {
+		return err
+	}
+
+	return c.Redirect(url, fiber.StatusFound)
+}
+
+// @Summary Complete a login with the identity provider
+// @Description The identity provider sends the user back here after they log in. The identity is linked to the account with the same email if the provider verified it, otherwise a new account without a password is created. Returns the same tokens as a login with a password.
+// @Tags auth
+// @Produce json
+// @Param code query string false "Authorization code handed back by the provider"
+// @Param state query string true "State of the login handed back by the provider"
+// @Param error query string false "Error reported by the provider"
+// @Success 200 {object} models.LoginResponse "token"
+// @Success 202 {object} models.LoginChallengeResponse "Two-factor authentication required"
+// @Failure 400 {object} models.ErrorResponse "Bad request, unknown or expired login, or no email shared by the provider"
+// @Failure 401 {object} models.ErrorResponse "The provider rejected or did not confirm the login"
+// @Failure 403 {object} models.ErrorResponse "Account suspended or deleted"
+// @Failure 404 {object} models.ErrorResponse "No identity provider is configured"
+// @Failure 409 {object} models.ErrorResponse "Email used by an account the provider cannot be trusted with"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/oidc/callback [get]
+func OIDCCallback(c *fiber.Ctx) error {
+	// The error comes from the query string, so it is only logged, quoted, and
+	// never echoed back to the client.
+	if reason := c.Query("error"); reason != "" {
+		log.Printf("The identity provider rejected a login: %q (%q)", reason, c.Query("error_description"))
+		return service.ErrOIDCLoginFailed
+	}
+
+	var fieldErrors []models.FieldError
+	for _, name := range []string{"code", "state"} {
+		if c.Query(name) == "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: name, Reason: "is required"})
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return apperror.Validation(fieldErrors)
+	}
+
+	tokens, err := userService.CompleteOIDCLogin(c.Query("state"), c.Query("code"), clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	if tokens.ChallengeToken != "" {
+		return c.Status(fiber.StatusAccepted).JSON(newLoginChallengeResponse(tokens))
+	}
+	return c.Status(fiber.StatusOK).JSON(newLoginResponse(tokens))
+}
+
+// @Summary Refresh the tokens of a user
+// @Description Exchange a refresh token for a new short-lived access token and a new refresh token. Each refresh token can only be used once; reusing one revokes every token issued since the login it came from.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param refresh body models.RefreshRequest true "Refresh Request"
+// @Success 200 {object} models.LoginResponse "token"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/refresh [post]
+func Refresh(c *fiber.Ctx) error {
+	request := new(models.RefreshRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	tokens, err := userService.RefreshTokens(request.RefreshToken, clientInfo(c))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(newLoginResponse(tokens))
+}
+
+// @Summary Ask for a password reset
+// @Description Send a single-use, time-limited password reset link to the email of an account. The response is the same whether or not the email is registered.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param forgotPassword body models.ForgotPasswordRequest true "Forgot Password Request"
+// @Success 202 {object} models.Response "Password reset requested"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/password/forgot [post]
+func ForgotPassword(c *fiber.Ctx) error {
+	request := new(models.ForgotPasswordRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	if err := userService.RequestPasswordReset(request.Email); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.Response{Code: "202", Message: "Password reset requested"})
+}
+
+// @Summary Reset a password
+// @Description Set a new password with the token of a password reset link. The token can only be used once, every session of the user is logged out and their API keys are revoked.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param resetPassword body models.ResetPasswordRequest true "Reset Password Request"
+// @Success 200 {object} models.Response "Password reset"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/password/reset [post]
+func ResetPassword(c *fiber.Ctx) error {
+	request := new(models.ResetPasswordRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	err := userService.ResetPassword(request.Token, request.Password)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Password reset"})
+}
+
+// @Summary Log out of the current session
+// @Description Revoke the access token used for the request and the refresh token of its session.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Success 200 {object} models.Response "Logged out"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/logout [post]
+func Logout(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	err := userService.Logout(principal.UserID, principal.SessionID, principal.TokenID, principal.TokenExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Logged out"})
+}
+
+// @Summary Log out of every session
+// @Description Revoke every access and refresh token issued to the user so far.
 // @Tags auth
 // @Accept json
 // @Produce json
+// @Security ApiKeyAuth
 // @Param Authorization header string true "JWT <token>"
+// @Success 200 {object} models.Response "Logged out"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/logout/all [post]
+func LogoutAll(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	if err := userService.LogoutAll(principal.UserID); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Logged out"})
+}
+
+// @Summary Get the sessions of the user
+// @Description Get the devices the user is logged in on, most recently used first
+// @Tags auth
+// @Accept json
+// @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} models.OffersResponse "offers"
-// @Failure 401 {object} models.Response "Unauthorized"
-// @Failure 500 {object} models.Response "Bad server"
-// @Router /auth/offers [get]
-func GetOffers(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Param Authorization header string true "JWT <token>"
+// @Success 200 {object} models.SessionsResponse "sessions"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/sessions [get]
+func GetSessions(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAuthenticated(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response{Code: "401", Message: "Unauthorized"})
+	sessions, err := userService.GetSessions(principal.UserID)
+	if err != nil {
+		return err
 	}
 
-	offers, err := offerService.GetOffers()
+	return c.Status(fiber.StatusOK).JSON(models.SessionsResponse{Code: "200", Sessions: toSessionResponses(sessions, principal.SessionID)})
+}
+
+// @Summary Revoke a session of the user
+// @Description Log the user out of one of their devices
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.Response "Session revoked"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/sessions/{id} [delete]
+func RevokeSession(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	err := userService.RevokeSession(principal.UserID, c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "500", Message: "Bad server"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.OffersResponse{Code: "200", Message: offers})
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Session revoked"})
 }
 
-// @Summary Checkout
-// @Description Buy a list of orders. If you want to add more products, here is an example of the structure to follow:{"orderItems": [ { "productID": 1, "quantity": 2 }, { "productID": 2, "quantity": 1 }, { "productID": 3, "quantity": 5 } ] }
+// @Summary Get my account
+// @Description Get the account of the logged-in user
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param checkout body models.CheckoutRequest true "Checkout Request"
 // @Param Authorization header string true "JWT <token>"
-// @Success 200 {object} models.CheckoutResponse
-// @Failure 400 {object} models.Response "Bad request"
-// @Failure 401 {object} models.Response "Unauthorized"
-// @Failure 500 {object} models.Response "Bad server"
-// @Router /auth/checkout [post]
-func Checkout(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Success 200 {object} models.ProfileResponse "user"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/me [get]
+func GetProfile(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAuthenticated(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response{Code: "401", Message: "Unauthorized"})
+	user, err := userService.GetProfile(principal.UserID)
+	if err != nil {
+		return err
 	}
 
-	checkout := new(models.CheckoutRequest)
-	if err := c.BodyParser(checkout); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.Response{Code: "400", Message: "Bad request"})
+	return c.Status(fiber.StatusOK).JSON(models.ProfileResponse{Code: "200", User: toUserResponse(user)})
+}
+
+// @Summary Update my account
+// @Description Change the username and/or the email of the logged-in user. A new email has to be verified again.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param update body models.UpdateUserRequest true "Update User Request"
+// @Success 200 {object} models.ProfileResponse "user"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Email or username already in use"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/me [patch]
+func UpdateProfile(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	request := new(models.UpdateUserRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	orderID, total, err := orderService.Checkout(checkout)
+	user, err := userService.UpdateProfile(principal.UserID, request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "400", Message: "Bad request"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.CheckoutResponse{
-		Code: "200",
-		Message: models.CheckoutMessage{
-			Total:  total,
-			Status: "pending",
-		},
-		OrderID: orderID,
-	})
+	return c.Status(fiber.StatusOK).JSON(models.ProfileResponse{Code: "200", User: toUserResponse(user)})
 }
 
-// @Summary Get status of a specific order
-// @Description Get the status of a specific order by id
+// @Summary Change my password
+// @Description Change the password of the logged-in user, who is then logged out of every session and whose API keys are revoked.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param id path string true "Order ID"
 // @Param Authorization header string true "JWT <token>"
-// @Success 200 {object} models.OrderStatusResponse "status"
-// @Failure 401 {object} models.Response "Unauthorized"
-// @Failure 500 {object} models.Response "Bad server"
-// @Router /auth/orders/{id} [get]
-func GetOrderStatus(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Param password body models.ChangePasswordRequest true "Change Password Request"
+// @Success 200 {object} models.Response "Password changed"
+// @Failure 400 {object} models.ErrorResponse "Bad request or incorrect current password"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/password/change [post]
+func ChangePassword(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAuthenticated(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response{Code: "401", Message: "Unauthorized"})
+	request := new(models.ChangePasswordRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	orderId := c.Params("id")
-	status, err := orderService.GetOrderStatus(orderId)
+	err := userService.ChangePassword(principal.UserID, request.CurrentPassword, request.NewPassword)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "500", Message: "Bad server"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.OrderStatusResponse{Code: "200", Status: status})
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Password changed, log in again"})
 }
 
-// @Summary Admin dashboard
-// @Description Get the admin dashboard
-// @Tags admin
+// @Summary Delete my account
+// @Description Delete the account of the logged-in user. Their personal data is erased and their API keys are revoked, while their orders are kept for accounting.
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param Authorization header string true "JWT <token>"
-// @Success 200 {object} models.Response_d
-// @Failure 401 {object} models.Response_d "Unauthorized"
-// @Failure 500 {object} models.Response_d "Bad server"
-// @Router /admin/dashboard [get]
-func AdminDashboard(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Param delete body models.DeleteAccountRequest true "Delete Account Request"
+// @Success 200 {object} models.Response "Account deleted"
+// @Failure 400 {object} models.ErrorResponse "Bad request or incorrect password"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Last admin"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/me [delete]
+func DeleteAccount(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAdmin(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response_d{Code: "401", Message: "Unauthorized"})
+	request := new(models.DeleteAccountRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	dashboard, offers, orders, err := orderService.GetAdminDashboard()
+	err := userService.DeleteAccount(principal.UserID, request.Password)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response_d{Code: "500", Message: "Server error"})
+		return err
 	}
 
-	response := models.Response_d{
-		Code: "200",
-		Message: map[string]interface{}{
-			"dashboard": dashboard,
-			"offers":    offers,
-			"orders":    orders,
-		},
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Account deleted"})
+}
+
+// @Summary Start enrolling in two-factor authentication
+// @Description Generate a TOTP secret for the logged-in user to add to their authenticator app, by hand or as a QR code of the otpauth URL. It only takes effect once confirmed; starting again replaces it.
+// @Tags auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Success 200 {object} models.TOTPEnrollmentResponse "TOTP secret"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Two-factor authentication already enabled"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/totp/enroll [post]
+func EnrollTOTP(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	secret, url, err := userService.EnrollTOTP(principal.UserID)
+	if err != nil {
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(response)
+	return c.Status(fiber.StatusOK).JSON(models.TOTPEnrollmentResponse{Code: "200", Secret: secret, URL: url})
 }
 
-// @Summary Update the status of a specific order
-// @Description Update the status of a specific order by id. Valid statuses are "preparing", "processing", "shipped", "delivered".
-// @Tags admin
+// @Summary Confirm the enrollment in two-factor authentication
+// @Description Turn on two-factor authentication with a code of the authenticator app, and get the recovery codes, which are shown only this once. The current session counts as confirmed with two factors from its next refresh on.
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param Authorization header string true "JWT <token>"
-// @Param id path string true "Order ID"
-// @Param updateRequest body models.OrderStatusUpdateRequest true "Order Status Update Request"
-// @Success 200 {object} models.OrderStatusUpdateResponse "status"
-// @Failure 400 {object} models.Response "Bad request"
-// @Failure 401 {object} models.Response "Unauthorized"
-// @Failure 500 {object} models.Response "Bad server"
-// @Router /admin/orders/{id} [patch]
-func UpdateOrderStatus(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Param code body models.TOTPCodeRequest true "TOTP Code Request"
+// @Success 200 {object} models.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} models.ErrorResponse "Bad request or incorrect code"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Enrollment not started or already enabled"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/totp/confirm [post]
+func ConfirmTOTP(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAdmin(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response{Code: "401", Message: "Unauthorized"})
+	request := new(models.TOTPCodeRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	orderId := c.Params("id")
-	updateRequest := new(models.OrderStatusUpdateRequest)
-	if err := c.BodyParser(updateRequest); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.Response{Code: "400", Message: "Bad request"})
+	codes, err := userService.ConfirmTOTP(principal.UserID, principal.SessionID, request.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.RecoveryCodesResponse{Code: "200", RecoveryCodes: codes})
+}
+
+// @Summary Turn off two-factor authentication
+// @Description Turn off two-factor authentication for the logged-in user, confirmed with their password and a code of the authenticator app or a recovery code. Admins cannot turn it off.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param totp body models.DisableTOTPRequest true "Disable TOTP Request"
+// @Success 200 {object} models.Response "Two-factor authentication turned off"
+// @Failure 400 {object} models.ErrorResponse "Bad request, incorrect password or incorrect code"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Not enabled, or mandatory for admins"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/totp [delete]
+func DisableTOTP(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	request := new(models.DisableTOTPRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	validStatuses := map[string]bool{
-		"preparing":  true,
-		"processing": true,
-		"shipped":    true,
-		"delivered":  true,
+	if err := userService.DisableTOTP(principal.UserID, request.Password, request.Code); err != nil {
+		return err
 	}
 
-	if !validStatuses[updateRequest.Status] {
-		return c.Status(fiber.StatusBadRequest).JSON(models.Response{Code: "400", Message: "Bad request"})
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Two-factor authentication turned off"})
+}
+
+// @Summary Get new recovery codes
+// @Description Replace the recovery codes of the logged-in user, confirmed with a code of the authenticator app or a recovery code. The new codes are shown only this once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param code body models.TOTPCodeRequest true "TOTP Code Request"
+// @Success 200 {object} models.RecoveryCodesResponse "Recovery codes"
+// @Failure 400 {object} models.ErrorResponse "Bad request or incorrect code"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 409 {object} models.ErrorResponse "Two-factor authentication not enabled"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/totp/recovery-codes [post]
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	request := new(models.TOTPCodeRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	status, err := orderService.UpdateOrderStatus(orderId, updateRequest)
+	codes, err := userService.RegenerateRecoveryCodes(principal.UserID, request.Code)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "500", Message: "Bad server"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.OrderStatusUpdateResponse{Code: "200", Status: status})
+	return c.Status(fiber.StatusOK).JSON(models.RecoveryCodesResponse{Code: "200", RecoveryCodes: codes})
 }
 
-// @Summary Get all buyers
-// @Description Get all buyers, only for admins
-// @Tags admin
+// @Summary Create an API key
+// @Description Create an API key that lets scripts call the offer and order routes on behalf of the logged-in user, within the granted scopes. Keys act as a buyer, even those of admins, who have to log in with two-factor authentication to create one. The key is only shown in this response.
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param Authorization header string true "JWT <token>"
-// @Success 200 {object} models.UsersResponse "users"
-// @Failure 401 {object} models.Response "Unauthorized"
-// @Failure 500 {object} models.Response "Bad server"
-// @Router /admin/users [get]
-func GetAllBuyers(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Param apiKey body models.CreateAPIKeyRequest true "Create API Key Request"
+// @Success 201 {object} models.CreatedAPIKeyResponse "API key"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Admin without two-factor login"
+// @Failure 409 {object} models.ErrorResponse "Too many API keys"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/api-keys [post]
+func CreateAPIKey(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAdmin(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response{Code: "401", Message: "Unauthorized"})
+	request := new(models.CreateAPIKeyRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
 	}
 
-	users, err := userService.GetAllUsers()
+	key, secret, err := apiKeyService.CreateAPIKey(principal.UserID, request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "500", Message: "Bad server"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.UsersResponse{Code: "200", Users: users})
+	return c.Status(fiber.StatusCreated).JSON(models.CreatedAPIKeyResponse{Code: "201", Key: secret, APIKey: toAPIKeyResponse(key)})
 }
 
-// @Summary Remove a customer
-// @Description Remove a customer by email, only for admins
-// @Tags admin
+// @Summary List my API keys
+// @Description List the API keys of the logged-in user that have not been revoked, newest first
+// @Tags auth
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param Authorization header string true "JWT <token>"
-// @Param email body models.DeleteUserRequest true "Delete User Request"
-// @Success 200 {object} models.Response "success"
-// @Failure 400 {object} models.Response "Bad request"
-// @Failure 401 {object} models.Response "Unauthorized"
-// @Failure 500 {object} models.Response "Bad server"
-// @Router /admin/users [delete]
-func RemoveCustomer(c *fiber.Ctx) error {
-	userRepo := repository.NewUserRepository(userService.GetDB())
+// @Success 200 {object} models.APIKeysResponse "API keys"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/api-keys [get]
+func GetAPIKeys(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
 
-	if !middleware.IsAdmin(c, userRepo) {
-		return c.Status(fiber.StatusUnauthorized).JSON(models.Response{Code: "401", Message: "Unauthorized"})
+	keys, err := apiKeyService.GetAPIKeys(principal.UserID)
+	if err != nil {
+		return err
 	}
 
-	request := new(models.DeleteUserRequest)
-	if err := c.BodyParser(request); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(models.Response{Code: "400", Message: "Bad request"})
+	responses := make([]models.APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		responses = append(responses, toAPIKeyResponse(&keys[i]))
 	}
 
-	err := userService.DeleteUserByEmail(request.Email)
+	return c.Status(fiber.StatusOK).JSON(models.APIKeysResponse{Code: "200", APIKeys: responses})
+}
+
+// @Summary Revoke an API key
+// @Description Revoke an API key of the logged-in user, which can no longer be used
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "API key ID"
+// @Success 200 {object} models.Response "API key revoked"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/api-keys/{id} [delete]
+func RevokeAPIKey(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	err := apiKeyService.RevokeAPIKey(principal.UserID, c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(models.Response{Code: "500", Message: "Bad server"})
+		return err
 	}
 
-	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "success"})
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "API key revoked"})
+}
+
+/**
+ * @brief Describes the client a request comes from.
+ *
+ * @param c The Fiber context.
+ * @return The user agent and IP address of the request.
+ */
+func clientInfo(c *fiber.Ctx) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IPAddress: c.IP(),
+	}
+}
+
+/**
+ * @brief Converts sessions into their API representation.
+ *
+ * @param sessions The sessions to convert.
+ * @param currentID The ID of the session of the request, flagged as current.
+ * @return The session responses.
+ */
+func toSessionResponses(sessions []models.Session, currentID uint) []models.SessionResponse {
+	responses := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, models.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+	return responses
+}
+
+/**
+ * @brief Converts users into their API representation, leaving out their password hash and tokens.
+ *
+ * @param users The users to convert.
+ * @return The user responses.
+ */
+func toUserResponses(users []models.User) []models.UserResponse {
+	responses := make([]models.UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, toUserResponse(&users[i]))
+	}
+	return responses
+}
+
+/**
+ * @brief Converts a user into their API representation, leaving out their password hash and tokens.
+ *
+ * @param user The user to convert.
+ * @return The user response.
+ */
+func toUserResponse(user *models.User) models.UserResponse {
+	response := models.UserResponse{
+		ID:              user.ID,
+		Username:        user.Username,
+		Email:           user.Email,
+		Role:            user.Role,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		CreatedAt:       user.CreatedAt,
+		SuspendedAt:     user.SuspendedAt,
+		SuspendReason:   user.SuspendReason,
+		AnonymizedAt:    user.AnonymizedAt,
+		TwoFactor:       user.TOTPEnabledAt != nil,
+	}
+	if user.DeletedAt.Valid {
+		response.DeletedAt = &user.DeletedAt.Time
+	}
+	return response
+}
+
+/**
+ * @brief Converts an API key into its API representation, leaving out its hash.
+ *
+ * @param key The API key to convert.
+ * @return The API key response.
+ */
+func toAPIKeyResponse(key *models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+/**
+ * @brief Converts offers into their API representation.
+ *
+ * @param offers The offers to convert.
+ * @return The offer responses.
+ */
+func toOfferResponses(offers []models.Offer) []models.OfferResponse {
+	responses := make([]models.OfferResponse, 0, len(offers))
+	for _, offer := range offers {
+		responses = append(responses, models.OfferResponse{
+			ID:       offer.ID,
+			Name:     offer.Name,
+			Quantity: offer.Quantity,
+			Price:    offer.Price,
+			Category: offer.Category,
+		})
+	}
+	return responses
+}
+
+/**
+ * @brief Builds the response returned when tokens are issued to a user.
+ *
+ * @param tokens The issued tokens.
+ * @return The login response carrying the tokens.
+ */
+func newLoginResponse(tokens *models.AuthTokens) models.LoginResponse {
+	return models.LoginResponse{
+		Code:         "200",
+		Auth:         tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
+}
+
+/**
+ * @brief Builds the response of a login that still has to be confirmed with a second factor.
+ *
+ * @param tokens The login challenge.
+ * @return The login challenge response.
+ */
+func newLoginChallengeResponse(tokens *models.AuthTokens) models.LoginChallengeResponse {
+	return models.LoginChallengeResponse{
+		Code:           "202",
+		ChallengeToken: tokens.ChallengeToken,
+		ExpiresIn:      int64(tokens.ExpiresIn.Seconds()),
+		Message:        "Enter a code of your authenticator app or a recovery code at /auth/login/totp",
+	}
+}
+
+// @Summary Get available offers
+// @Description Get all available offers
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string false "JWT <token>, unless an API key is given"
+// @Param X-API-Key header string false "API key with the offers:read scope"
+// @Security ApiKeyAuth
+// @Success 200 {object} models.OffersResponse "offers"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Scope not granted to the API key"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/offers [get]
+func GetOffers(c *fiber.Ctx) error {
+	offers, err := offerService.GetOffers()
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OffersResponse{Code: "200", Message: toOfferResponses(offers)})
+}
+
+// @Summary Checkout
+// @Description Buy a list of orders. If you want to add more products, here is an example of the structure to follow:{"orderItems": [ { "productID": 1, "quantity": 2 }, { "productID": 2, "quantity": 1 }, { "productID": 3, "quantity": 5 } ] }
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param checkout body models.CheckoutRequest true "Checkout Request"
+// @Param Authorization header string false "JWT <token>, unless an API key is given"
+// @Param X-API-Key header string false "API key with the orders:write scope"
+// @Success 200 {object} models.CheckoutResponse
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Email not verified or scope not granted to the API key"
+// @Failure 404 {object} models.ErrorResponse "Offer not found"
+// @Failure 409 {object} models.ErrorResponse "Insufficient stock"
+// @Failure 429 {object} models.ErrorResponse "Rate limited"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/checkout [post]
+func Checkout(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	checkout := new(models.CheckoutRequest)
+	if err := parseBody(c, checkout); err != nil {
+		return err
+	}
+
+	orderID, total, err := orderService.Checkout(principal.UserID, checkout)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.CheckoutResponse{
+		Code: "200",
+		Message: models.CheckoutMessage{
+			Total:  total,
+			Status: models.OrderStatusPending,
+		},
+		OrderID: orderID,
+	})
+}
+
+// @Summary Get my orders
+// @Description Get the orders placed by the logged in buyer, newest first, with their items. Results can be filtered by status and by creation date, and are paginated.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string false "JWT <token>, unless an API key is given"
+// @Param X-API-Key header string false "API key with the orders:read scope"
+// @Param page query int false "Page number, starting at 1" default(1)
+// @Param limit query int false "Orders per page, at most 100" default(20)
+// @Param status query string false "Only orders in this status"
+// @Param from query string false "Only orders created on or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only orders created on or before this date (YYYY-MM-DD or RFC 3339)"
+// @Success 200 {object} models.OrderListResponse "orders"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Scope not granted to the API key"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/orders [get]
+func GetOrders(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	from, err := parseDateParam(c.Query("from"), false)
+	if err != nil {
+		return invalidDateParam("from")
+	}
+	to, err := parseDateParam(c.Query("to"), true)
+	if err != nil {
+		return invalidDateParam("to")
+	}
+
+	filter := &models.OrderFilter{
+		Status: c.Query("status"),
+		From:   from,
+		To:     to,
+		Page:   c.QueryInt("page", 1),
+		Limit:  c.QueryInt("limit", 0),
+	}
+
+	orders, total, err := orderService.GetUserOrders(principal.UserID, filter)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OrderListResponse{
+		Code:   "200",
+		Orders: orders,
+		Page:   filter.Page,
+		Limit:  filter.Limit,
+		Total:  total,
+	})
+}
+
+// @Summary Get a specific order
+// @Description Get the details of a specific order by id: its items with product name, unit price at purchase, quantity and line total, the order total, its timestamps and its status history. Buyers can only see their own orders, admins who logged in with two-factor authentication can see all of them.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Order ID"
+// @Param Authorization header string false "JWT <token>, unless an API key is given"
+// @Param X-API-Key header string false "API key with the orders:read scope"
+// @Success 200 {object} models.OrderDetailResponse "order"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Scope not granted to the API key"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/orders/{id} [get]
+func GetOrder(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	orderId := c.Params("id")
+	order, err := orderService.GetOrder(orderId, principal.UserID, principal.IsAdmin())
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OrderDetailResponse{Code: "200", Order: *order})
+}
+
+// @Summary Cancel an order
+// @Description Cancel an order and return its items to the stock. Buyers can cancel their own orders while they are still pending, admins who logged in with two-factor authentication can cancel any order before it is shipped.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Order ID"
+// @Param Authorization header string false "JWT <token>, unless an API key is given"
+// @Param X-API-Key header string false "API key with the orders:write scope"
+// @Param cancelRequest body models.CancelOrderRequest false "Cancel Order Request"
+// @Success 200 {object} models.OrderStatusUpdateResponse "status"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Scope not granted to the API key"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Order cannot be cancelled"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /auth/orders/{id}/cancel [post]
+func CancelOrder(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	cancelRequest := new(models.CancelOrderRequest)
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, cancelRequest); err != nil {
+			return err
+		}
+	}
+
+	err := orderService.CancelOrder(c.Params("id"), principal.UserID, principal.IsAdmin(), cancelRequest.Reason)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OrderStatusUpdateResponse{Code: "200", Status: models.OrderStatusCancelled})
+}
+
+// @Summary Admin dashboard
+// @Description Get the admin dashboard
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Success 200 {object} models.DashboardResponse "dashboard"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/dashboard [get]
+func AdminDashboard(c *fiber.Ctx) error {
+	dashboard, offers, orders, err := orderService.GetAdminDashboard()
+	if err != nil {
+		return err
+	}
+
+	response := models.DashboardResponse{
+		Code: "200",
+		Message: models.DashboardMessage{
+			Dashboard: dashboard,
+			Offers:    toOfferResponses(offers),
+			Orders:    orders,
+		},
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response)
+}
+
+// @Summary Update the status of a specific order
+// @Description Update the status of a specific order by id. Orders follow the lifecycle pending -> preparing -> processing -> shipped -> delivered and can be "cancelled" until they are shipped. Illegal transitions are rejected.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "Order ID"
+// @Param updateRequest body models.OrderStatusUpdateRequest true "Order Status Update Request"
+// @Success 200 {object} models.OrderStatusUpdateResponse "status"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Invalid status transition"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/orders/{id} [patch]
+func UpdateOrderStatus(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	orderId := c.Params("id")
+	updateRequest := new(models.OrderStatusUpdateRequest)
+	if err := parseBody(c, updateRequest); err != nil {
+		return err
+	}
+
+	status, err := orderService.UpdateOrderStatus(orderId, principal.UserID, updateRequest)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OrderStatusUpdateResponse{Code: "200", Status: status})
+}
+
+// @Summary Get the status history of a specific order
+// @Description Get every status change of a specific order by id, oldest first, including who made it and when. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "Order ID"
+// @Success 200 {object} models.OrderStatusHistoryResponse "history"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/orders/{id}/history [get]
+func GetOrderStatusHistory(c *fiber.Ctx) error {
+	history, err := orderService.GetOrderStatusHistory(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.OrderStatusHistoryResponse{Code: "200", History: history})
+}
+
+// @Summary Search users
+// @Description Get a page of the users, oldest first, optionally matching a text against their username or email and filtered by role. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param q query string false "Text matched against any part of the username or the email"
+// @Param role query string false "Only users with this role" Enums(buyer, admin)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Users per page, at most 100" default(20)
+// @Success 200 {object} models.UsersResponse "users"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users [get]
+func SearchUsers(c *fiber.Ctx) error {
+	filter := &models.UserFilter{
+		Query: c.Query("q"),
+		Role:  c.Query("role"),
+		Page:  c.QueryInt("page", 1),
+		Limit: c.QueryInt("limit", 0),
+	}
+
+	users, total, err := userService.SearchUsers(filter)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UsersResponse{
+		Code:  "200",
+		Users: toUserResponses(users),
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Total: total,
+	})
+}
+
+// @Summary Search deleted users
+// @Description Get a page of the deleted users, most recently deleted first, optionally matching a text against their username or email and filtered by role. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param q query string false "Text matched against any part of the username or the email"
+// @Param role query string false "Only users with this role" Enums(buyer, admin)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Users per page, at most 100" default(20)
+// @Success 200 {object} models.UsersResponse "users"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/deleted [get]
+func SearchDeletedUsers(c *fiber.Ctx) error {
+	filter := &models.UserFilter{
+		Query:   c.Query("q"),
+		Role:    c.Query("role"),
+		Deleted: true,
+		Page:    c.QueryInt("page", 1),
+		Limit:   c.QueryInt("limit", 0),
+	}
+
+	users, total, err := userService.SearchUsers(filter)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UsersResponse{
+		Code:  "200",
+		Users: toUserResponses(users),
+		Page:  filter.Page,
+		Limit: filter.Limit,
+		Total: total,
+	})
+}
+
+// @Summary Restore a deleted user
+// @Description Restore a deleted user, who can log in again. Users whose personal data was erased cannot be restored. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UpdatedUserResponse "user"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Not deleted, anonymized, or email or username in use again"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/restore [post]
+func RestoreUser(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	user, err := userService.RestoreUser(principal.UserID, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UpdatedUserResponse{Code: "200", User: toUserResponse(user)})
+}
+
+// @Summary Get a user
+// @Description Get a user by id together with statistics about their orders, only for admins
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UserDetailResponse "user"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id} [get]
+func GetUser(c *fiber.Ctx) error {
+	user, err := userService.GetUser(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	stats, err := orderService.GetUserOrderStats(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UserDetailResponse{Code: "200", User: toUserResponse(user), OrderStats: *stats})
+}
+
+// @Summary Update a user
+// @Description Update the username and/or the email of a user, only for admins. A new email has to be verified again.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Param update body models.UpdateUserRequest true "Update User Request"
+// @Success 200 {object} models.UpdatedUserResponse "user"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Email or username already in use"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id} [patch]
+func UpdateUser(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	request := new(models.UpdateUserRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	user, err := userService.UpdateUser(principal.UserID, c.Params("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UpdatedUserResponse{Code: "200", User: toUserResponse(user)})
+}
+
+// @Summary Suspend a user
+// @Description Suspend a user, who is logged out of every session, has their API keys revoked and cannot log in until reactivated. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Param suspend body models.SuspendUserRequest false "Suspend User Request"
+// @Success 200 {object} models.UpdatedUserResponse "user"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Already suspended or own account"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/suspend [post]
+func SuspendUser(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	request := new(models.SuspendUserRequest)
+	if len(c.Body()) > 0 {
+		if err := parseBody(c, request); err != nil {
+			return err
+		}
+	}
+
+	user, err := userService.SuspendUser(principal.UserID, c.Params("id"), request.Reason)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UpdatedUserResponse{Code: "200", User: toUserResponse(user)})
+}
+
+// @Summary Reactivate a user
+// @Description Lift the suspension of a user, only for admins
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 200 {object} models.UpdatedUserResponse "user"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Not suspended"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/reactivate [post]
+func ReactivateUser(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	user, err := userService.ReactivateUser(principal.UserID, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UpdatedUserResponse{Code: "200", User: toUserResponse(user)})
+}
+
+// @Summary Force a password reset
+// @Description Invalidate the password of a user, log them out of every session, revoke their API keys and email them a password reset link. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 202 {object} models.Response "Password reset email sent"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/password-reset [post]
+func ForcePasswordReset(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	err := userService.ForcePasswordReset(principal.UserID, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(models.Response{Code: "202", Message: "Password reset email sent"})
+}
+
+// @Summary Reset the two-factor authentication of a user
+// @Description Turn off two-factor authentication for a user who lost their authenticator app and recovery codes, and log them out of every session, so that they can enroll again. Only for admins, who cannot reset their own.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 200 {object} models.Response "Two-factor authentication reset"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Not enabled, or own account"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/totp [delete]
+func ResetTOTP(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	if err := userService.ResetTOTP(principal.UserID, c.Params("id")); err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "Two-factor authentication reset"})
+}
+
+// @Summary Remove a customer
+// @Description Remove a customer by email, only for admins. The user is logged out of every session, has their API keys revoked and can be restored until they are purged. The last admin cannot be removed.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param email body models.DeleteUserRequest true "Delete User Request"
+// @Success 200 {object} models.Response "success"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Cannot remove the last admin"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users [delete]
+func RemoveCustomer(c *fiber.Ctx) error {
+	request := new(models.DeleteUserRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	principal := middleware.CurrentPrincipal(c)
+	err := userService.DeleteUserByEmail(principal.UserID, request.Email)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "success"})
+}
+
+// @Summary Change the role of a user
+// @Description Grant or revoke the admin role of a user by id, only for admins. The user is logged out of every session and has to log in again. The last admin cannot be demoted.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Param roleRequest body models.UpdateUserRoleRequest true "Update User Role Request"
+// @Success 200 {object} models.UserRoleResponse "role"
+// @Failure 400 {object} models.ErrorResponse "Bad request"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 409 {object} models.ErrorResponse "Cannot remove the last admin"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/role [put]
+func UpdateUserRole(c *fiber.Ctx) error {
+	request := new(models.UpdateUserRoleRequest)
+	if err := parseBody(c, request); err != nil {
+		return err
+	}
+
+	principal := middleware.CurrentPrincipal(c)
+	user, err := userService.UpdateUserRole(principal.UserID, c.Params("id"), request.Role)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.UserRoleResponse{Code: "200", UserID: user.ID, Role: user.Role})
+}
+
+// @Summary Get the sessions of a user
+// @Description Get the devices a user is logged in on, most recently used first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 200 {object} models.SessionsResponse "sessions"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/sessions [get]
+func GetUserSessions(c *fiber.Ctx) error {
+	sessions, err := userService.GetUserSessions(c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.SessionsResponse{Code: "200", Sessions: toSessionResponses(sessions, 0)})
+}
+
+// @Summary Unlock a user
+// @Description Lift the login lockout of a user after too many failed login attempts, only for admins
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param id path string true "User ID"
+// @Success 200 {object} models.Response "User unlocked"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 404 {object} models.ErrorResponse "Not found"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/users/{id}/unlock [post]
+func UnlockUser(c *fiber.Ctx) error {
+	principal := middleware.CurrentPrincipal(c)
+
+	err := userService.UnlockUser(principal.UserID, c.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.Response{Code: "200", Message: "User unlocked"})
+}
+
+// @Summary Get the audit log
+// @Description Get the most recent security events, such as login lockouts, newest first. Only for admins.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param Authorization header string true "JWT <token>"
+// @Param action query string false "Only entries with this action, e.g. account_locked"
+// @Param limit query int false "Number of entries, at most 500" default(50)
+// @Success 200 {object} models.AuditEntriesResponse "entries"
+// @Failure 401 {object} models.ErrorResponse "Unauthorized"
+// @Failure 403 {object} models.ErrorResponse "Forbidden"
+// @Failure 500 {object} models.ErrorResponse "Bad server"
+// @Router /admin/audit [get]
+func GetAuditLog(c *fiber.Ctx) error {
+	entries, err := userService.GetAuditEntries(c.Query("action"), c.QueryInt("limit", 0))
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(models.AuditEntriesResponse{Code: "200", Entries: entries})
 }
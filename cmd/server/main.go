@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,18 +10,26 @@ import (
 	"math/rand"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/cmd/controllers"
 	_ "github.com/ICOMP-UNC/newworld-gastonsegura2908.git/docs"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/lockout"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/mailer"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/ratelimit"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/service"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/sso"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	fiberLogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/swagger"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/database"
@@ -73,9 +82,17 @@ func main() {
 		port = "3000"
 	}
 
+	keyRing, err := loadKeyRing()
+	if err != nil {
+		log.Fatalf("Error loading JWT signing keys: %v", err)
+	}
+	middleware.SetKeyRing(keyRing)
+
 	database.ResetDatabase()
 
-	app := fiber.New()
+	redisClient := newRedisClient()
+
+	app := fiber.New(newAppConfig())
 
 	db := database.InitDB()
 
@@ -93,8 +110,31 @@ func main() {
 	// Start a goroutine that sends POST requests every 30 seconds
 	go startPeriodicUpdates(db)
 
+	tokenConfig := service.TokenConfig{
+		AccessTokenTTL:       durationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:      durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		EmailVerificationTTL: durationFromEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+		EmailVerificationURL: os.Getenv("EMAIL_VERIFICATION_URL"),
+		PasswordResetTTL:     durationFromEnv("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetURL:     os.Getenv("PASSWORD_RESET_URL"),
+	}
+
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
+	tokenRepo := repository.NewTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	twoFactorRepo := repository.NewTwoFactorRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	userService := service.NewUserService(userRepo, tokenRepo, sessionRepo, auditRepo, identityRepo, twoFactorRepo, apiKeyRepo, newLoginGuard(redisClient), newIdentityProvider(), newMailer(), tokenConfig)
+
+	err = userService.BootstrapAdmin(os.Getenv("ADMIN_EMAIL"), os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD"))
+	if err != nil {
+		log.Fatalf("Error bootstrapping admin account: %v", err)
+	}
+
+	go startRevocationCleanup(userService)
+	go startDeletedUserPurge(userService, durationFromEnv("DELETED_USER_RETENTION", 30*24*time.Hour))
 
 	offerRepo := repository.NewOfferRepository(db)
 	offerService := service.NewOfferService(offerRepo)
@@ -102,6 +142,8 @@ func main() {
 	orderRepo := repository.NewOrderRepository(db)
 	orderService := service.NewOrderService(orderRepo)
 
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, auditRepo)
+
 	app.Use(fiberLogger.New(fiberLogger.Config{
 		Format:     "[${time}] ${status} - ${method} ${path}\n",
 		TimeFormat: "02-Jan-2006",
@@ -113,11 +155,123 @@ func main() {
 
 	app.Get("/swagger/*", swagger.HandlerDefault)
 
-	controllers.RegisterRoutes(app, userService, offerService, orderService)
+	controllers.RegisterRoutes(app, userService, offerService, orderService, apiKeyService, newRateLimitStore(redisClient), rateLimitsFromEnv())
 	log.Fatal(app.Listen(":" + port))
 
 }
 
+/**
+ * @brief Builds the configuration of the Fiber application.
+ *
+ * Behind a reverse proxy such as Traefik, the IP address of the client is read
+ * from the header given by PROXY_HEADER (e.g. X-Real-Ip), but only for requests
+ * coming from the proxies listed in TRUSTED_PROXIES, a comma separated list of
+ * IP addresses or CIDR ranges. Otherwise clients could forge their IP address
+ * and escape the rate limits.
+ *
+ * @return The configuration of the application.
+ */
+func newAppConfig() fiber.Config {
+	config := fiber.Config{ErrorHandler: controllers.ErrorHandler}
+
+	header := os.Getenv("PROXY_HEADER")
+	if header == "" {
+		return config
+	}
+
+	config.ProxyHeader = header
+	config.EnableTrustedProxyCheck = true
+	config.EnableIPValidation = true
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			config.TrustedProxies = append(config.TrustedProxies, proxy)
+		}
+	}
+	if len(config.TrustedProxies) == 0 {
+		log.Printf("PROXY_HEADER is set but TRUSTED_PROXIES is empty, the header will be ignored")
+	}
+	return config
+}
+
+/**
+ * @brief Reads a duration, such as "15m" or "720h", from an environment variable.
+ *
+ * @param name The name of the environment variable.
+ * @param fallback The duration used if the variable is not set.
+ * @return The configured duration, or the fallback if the variable is not set.
+ */
+func durationFromEnv(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Fatalf("Invalid duration %q in %s", value, name)
+	}
+	return duration
+}
+
+/**
+ * @brief Reads a positive integer from an environment variable.
+ *
+ * @param name The name of the environment variable.
+ * @param fallback The value used if the variable is not set.
+ * @return The configured value, or the fallback if the variable is not set.
+ */
+func intFromEnv(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		log.Fatalf("Invalid number %q in %s", value, name)
+	}
+	return number
+}
+
+/**
+ * @brief Reads a rate limit, such as "10/1m" or "off", from an environment variable.
+ *
+ * @param name The name of the environment variable.
+ * @param fallback The limit used if the variable is not set.
+ * @return The configured limit, or the fallback if the variable is not set.
+ */
+func limitFromEnv(name string, fallback ratelimit.Limit) ratelimit.Limit {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	limit, err := ratelimit.ParseLimit(value)
+	if err != nil {
+		log.Fatalf("Invalid rate limit in %s: %v", name, err)
+	}
+	return limit
+}
+
+/**
+ * @brief Reads the rate limits applied to the routes from the environment.
+ *
+ * @return The rate limits.
+ */
+func rateLimitsFromEnv() ratelimit.Config {
+	return ratelimit.Config{
+		Global:              limitFromEnv("RATE_LIMIT_GLOBAL", ratelimit.Limit{Requests: 300, Period: time.Minute}),
+		User:                limitFromEnv("RATE_LIMIT_USER", ratelimit.Limit{Requests: 120, Period: time.Minute}),
+		Register:            limitFromEnv("RATE_LIMIT_REGISTER", ratelimit.Limit{Requests: 5, Period: time.Hour}),
+		Login:               limitFromEnv("RATE_LIMIT_LOGIN", ratelimit.Limit{Requests: 10, Period: time.Minute}),
+		Checkout:            limitFromEnv("RATE_LIMIT_CHECKOUT", ratelimit.Limit{Requests: 10, Period: time.Minute}),
+		Refresh:             limitFromEnv("RATE_LIMIT_REFRESH", ratelimit.Limit{Requests: 30, Period: time.Minute}),
+		EmailToken:          limitFromEnv("RATE_LIMIT_EMAIL_TOKEN", ratelimit.Limit{Requests: 10, Period: time.Minute}),
+		ForgotPassword:      limitFromEnv("RATE_LIMIT_FORGOT_PASSWORD", ratelimit.Limit{Requests: 10, Period: time.Hour}),
+		ForgotPasswordEmail: limitFromEnv("RATE_LIMIT_FORGOT_PASSWORD_EMAIL", ratelimit.Limit{Requests: 3, Period: time.Hour}),
+	}
+}
+
 /**
  * @brief Fetches the supplies data from the given URL.
  *
@@ -205,6 +359,189 @@ func startPeriodicUpdates(db *gorm.DB) {
 	}
 }
 
+/**
+ * @brief Loads the keys used to sign and verify JWTs.
+ *
+ * The keys are read from the directory given by JWT_KEYS_DIR, and new tokens are
+ * signed with the key named by JWT_ACTIVE_KEY. Without a directory an ephemeral
+ * key is generated, so tokens do not survive a restart.
+ *
+ * @return The key ring and an error if the keys cannot be loaded.
+ */
+func loadKeyRing() (*middleware.KeyRing, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		log.Printf("JWT_KEYS_DIR is not set, signing tokens with an ephemeral key")
+		return middleware.NewEphemeralKeyRing()
+	}
+	return middleware.LoadKeyRing(dir, os.Getenv("JWT_ACTIVE_KEY"))
+}
+
+/**
+ * @brief Creates the OpenID Connect provider users can log in with.
+ *
+ * The provider is discovered from OIDC_ISSUER_URL and the API logs in with the
+ * client given by OIDC_CLIENT_ID and OIDC_CLIENT_SECRET. OIDC_REDIRECT_URL must
+ * point at the /auth/oidc/callback route, and OIDC_SCOPES optionally lists the
+ * scopes to request, separated by spaces.
+ *
+ * @return The provider, or nil if OIDC_ISSUER_URL is not set.
+ */
+func newIdentityProvider() sso.Provider {
+	issuer := os.Getenv("OIDC_ISSUER_URL")
+	if issuer == "" {
+		log.Printf("OIDC_ISSUER_URL is not set, login with an identity provider is disabled")
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	provider, err := sso.NewProvider(ctx, sso.Config{
+		IssuerURL:    issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+	})
+	if err != nil {
+		log.Fatalf("Error discovering identity provider %s: %v", issuer, err)
+	}
+	return provider
+}
+
+/**
+ * @brief Creates the mailer used to send emails to users.
+ *
+ * Emails are delivered through the SMTP server given by SMTP_HOST. Without it,
+ * they are appended to the file given by MAIL_LOG_FILE, or written to the log.
+ *
+ * @return The mailer.
+ */
+func newMailer() mailer.Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Printf("SMTP_HOST is not set, emails will not be delivered")
+		return mailer.NewLogMailer(os.Getenv("MAIL_LOG_FILE"))
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	return mailer.NewSMTPMailer(mailer.SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("MAIL_FROM"),
+	})
+}
+
+/**
+ * @brief Connects to the Redis server given by REDIS_URL, e.g. "redis://localhost:6379/0".
+ *
+ * @return The Redis client, or nil if REDIS_URL is not set.
+ */
+func newRedisClient() *redis.Client {
+	url := os.Getenv("REDIS_URL")
+	if url == "" {
+		return nil
+	}
+
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		log.Fatalf("Invalid REDIS_URL: %v", err)
+	}
+	return redis.NewClient(options)
+}
+
+/**
+ * @brief Creates the guard throttling failed login attempts.
+ *
+ * Attempts are kept in Redis when a client is given, so that they are shared
+ * by every instance of the API, and in memory otherwise.
+ *
+ * @param redisClient The Redis client, or nil to keep the attempts in memory.
+ * @return The login guard.
+ */
+func newLoginGuard(redisClient *redis.Client) lockout.Guard {
+	store := lockout.NewMemoryStore()
+	if redisClient != nil {
+		store = lockout.NewRedisStore(redisClient, "lockout:")
+	}
+
+	return lockout.NewGuard(store, lockout.Config{
+		AccountThreshold: intFromEnv("LOGIN_LOCKOUT_THRESHOLD", 5),
+		IPThreshold:      intFromEnv("LOGIN_IP_LOCKOUT_THRESHOLD", 20),
+		Window:           durationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		BaseDelay:        durationFromEnv("LOGIN_BACKOFF_BASE", time.Second),
+		MaxDelay:         durationFromEnv("LOGIN_BACKOFF_MAX", time.Minute),
+		LockoutDuration:  durationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+	})
+}
+
+/**
+ * @brief Creates the store of the rate limit buckets.
+ *
+ * Buckets are kept in Redis when a client is given, so that the limits hold
+ * across every instance of the API, and in memory otherwise.
+ *
+ * @param redisClient The Redis client, or nil to keep the buckets in memory.
+ * @return The rate limit store.
+ */
+func newRateLimitStore(redisClient *redis.Client) ratelimit.Store {
+	if redisClient == nil {
+		return ratelimit.NewMemoryStore()
+	}
+	return ratelimit.NewRedisStore(redisClient, "ratelimit:")
+}
+
+/**
+ * @brief Starts a periodic cleanup that removes expired access tokens from the revocation list every hour.
+ *
+ * @param userService The user service that owns the revocation list.
+ */
+func startRevocationCleanup(userService service.UserService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, err := userService.PurgeExpiredRevocations()
+		if err != nil {
+			log.Printf("Failed to purge expired token revocations: %v", err)
+			continue
+		}
+		if purged > 0 {
+			log.Printf("Purged %d expired token revocations", purged)
+		}
+	}
+}
+
+/**
+ * @brief Starts a periodic purge that permanently removes the users deleted longer ago than the retention period, every hour.
+ *
+ * Until then, deleted users can be restored by an admin.
+ *
+ * @param userService The user service that owns the users.
+ * @param retention How long deleted users are kept.
+ */
+func startDeletedUserPurge(userService service.UserService, retention time.Duration) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		purged, anonymized, err := userService.PurgeDeletedUsers(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Failed to purge deleted users: %v", err)
+			continue
+		}
+		if purged > 0 || anonymized > 0 {
+			log.Printf("Purged %d deleted users and anonymized %d deleted users with orders", purged, anonymized)
+		}
+	}
+}
+
 /**
  * @brief Fetches the current supplies from the database.
  *
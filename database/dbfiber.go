@@ -31,7 +31,7 @@ func InitDB() *gorm.DB {
 		log.Fatal("failed to connect to database:", err)
 	}
 
-	db.AutoMigrate(&models.User{}, &models.Offer{}, &models.Order{}, &models.OrderItem{})
+	Migrate(db)
 	return db
 }
 
@@ -58,5 +58,33 @@ func ResetDatabase() {
 		log.Fatalf("Failed to reset database schema: %v", err)
 	}
 
-	db.AutoMigrate(&models.User{}, &models.Offer{}, &models.Order{}, &models.OrderItem{})
+	Migrate(db)
+}
+
+/**
+ * @brief Creates or updates the tables and indexes used by the application.
+ *
+ * @param db The database connection.
+ */
+func Migrate(db *gorm.DB) {
+	hadEmailVerification := db.Migrator().HasColumn(&models.User{}, "email_verified_at")
+
+	db.AutoMigrate(&models.User{}, &models.Offer{}, &models.Order{}, &models.OrderItem{}, &models.OrderStatusHistory{}, &models.Session{}, &models.RefreshToken{}, &models.RevokedToken{}, &models.AuditEntry{}, &models.APIKey{}, &models.ExternalIdentity{}, &models.ProviderLogin{}, &models.RecoveryCode{}, &models.LoginChallenge{})
+
+	// Access tokens used to be stored in plain text on the user; they are now revoked by their JWT ID instead.
+	if db.Migrator().HasColumn(&models.User{}, "token") {
+		db.Migrator().DropColumn(&models.User{}, "token")
+	}
+
+	// The username and email used to be unique among deleted users too, which kept them from being registered again.
+	db.Exec("DROP INDEX IF EXISTS idx_users_username")
+	db.Exec("DROP INDEX IF EXISTS idx_users_email")
+
+	// Accounts created before email verification existed are trusted as they are.
+	if !hadEmailVerification {
+		db.Exec("UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL")
+	}
+
+	// Serves the order history of a buyer, newest first, without scanning the whole table.
+	db.Exec("CREATE INDEX IF NOT EXISTS idx_orders_user_created ON orders (user_id, created_at DESC, id DESC)")
 }
@@ -23,6 +23,100 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Get the public keys used to verify the JWTs issued by the API, as a JSON Web Key Set. The key of a token is given by its kid header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get the token signing keys",
+                "responses": {
+                    "200": {
+                        "description": "keys",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.JSONWebKeySet"
+                        }
+                    },
+                    "503": {
+                        "description": "Signing keys not loaded",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the most recent security events, such as login lockouts, newest first. Only for admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only entries with this action, e.g. account_locked",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 50,
+                        "description": "Number of entries, at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "entries",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.AuditEntriesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/admin/dashboard": {
             "get": {
                 "security": [
@@ -52,21 +146,27 @@ const docTemplate = `{
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "dashboard",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response_d"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DashboardResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response_d"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response_d"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
@@ -79,7 +179,7 @@ const docTemplate = `{
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Update the status of a specific order by id. Valid statuses are \"preparing\", \"processing\", \"shipped\", \"delivered\".",
+                "description": "Update the status of a specific order by id. Orders follow the lifecycle pending -\u003e preparing -\u003e processing -\u003e shipped -\u003e delivered and can be \"cancelled\" until they are shipped. Illegal transitions are rejected.",
                 "consumes": [
                     "application/json"
                 ],
@@ -125,32 +225,50 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Invalid status transition",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/admin/users": {
+        "/admin/orders/{id}/history": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get all buyers, only for admins",
+                "description": "Get every status change of a specific order by id, oldest first, including who made it and when. Only for admins.",
                 "consumes": [
                     "application/json"
                 ],
@@ -160,7 +278,7 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Get all buyers",
+                "summary": "Get the status history of a specific order",
                 "parameters": [
                     {
                         "type": "string",
@@ -168,36 +286,57 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "users",
+                        "description": "history",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UsersResponse"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusHistoryResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/users": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Remove a customer by email, only for admins",
+                "description": "Get a page of the users, oldest first, optionally matching a text against their username or email and filtered by role. Only for admins.",
                 "consumes": [
                     "application/json"
                 ],
@@ -207,7 +346,7 @@ const docTemplate = `{
                 "tags": [
                     "admin"
                 ],
-                "summary": "Remove a customer",
+                "summary": "Search users",
                 "parameters": [
                     {
                         "type": "string",
@@ -217,51 +356,76 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Delete User Request",
-                        "name": "email",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DeleteUserRequest"
-                        }
+                        "type": "string",
+                        "description": "Text matched against any part of the username or the email",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "buyer",
+                            "admin"
+                        ],
+                        "type": "string",
+                        "description": "Only users with this role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Users per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "success",
+                        "description": "users",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UsersResponse"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/checkout": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Buy a list of orders. If you want to add more products, here is an example of the structure to follow:{\"orderItems\": [ { \"productID\": 1, \"quantity\": 2 }, { \"productID\": 2, \"quantity\": 1 }, { \"productID\": 3, \"quantity\": 5 } ] }",
+                "description": "Remove a customer by email, only for admins. The user is logged out of every session, has their API keys revoked and can be restored until they are purged. The last admin cannot be removed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -269,58 +433,81 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Checkout",
+                "summary": "Remove a customer",
                 "parameters": [
-                    {
-                        "description": "Checkout Request",
-                        "name": "checkout",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutRequest"
-                        }
-                    },
                     {
                         "type": "string",
                         "description": "JWT \u003ctoken\u003e",
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "Delete User Request",
+                        "name": "email",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DeleteUserRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "success",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutResponse"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Cannot remove the last admin",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/login": {
-            "post": {
-                "description": "Login a user with the given credentials",
+        "/admin/users/deleted": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a page of the deleted users, most recently deleted first, optionally matching a text against their username or email and filtered by role. Only for admins.",
                 "consumes": [
                     "application/json"
                 ],
@@ -328,50 +515,90 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Login a user",
+                "summary": "Search deleted users",
                 "parameters": [
                     {
-                        "description": "Login Request",
-                        "name": "login",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginRequest"
-                        }
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Text matched against any part of the username or the email",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "buyer",
+                            "admin"
+                        ],
+                        "type": "string",
+                        "description": "Only users with this role",
+                        "name": "role",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Users per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "token",
+                        "description": "users",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UsersResponse"
                         }
                     },
                     "400": {
                         "description": "Bad request",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/offers": {
+        "/admin/users/{id}": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get all available offers",
+                "description": "Get a user by id together with statistics about their orders, only for admins",
                 "consumes": [
                     "application/json"
                 ],
@@ -379,9 +606,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Get available offers",
+                "summary": "Get a user",
                 "parameters": [
                     {
                         "type": "string",
@@ -389,38 +616,55 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "offers",
+                        "description": "user",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OffersResponse"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserDetailResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/orders/{id}": {
-            "get": {
+            },
+            "patch": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Get the status of a specific order by id",
+                "description": "Update the username and/or the email of a user, only for admins. A new email has to be verified again.",
                 "consumes": [
                     "application/json"
                 ],
@@ -428,331 +672,3559 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Get status of a specific order",
+                "summary": "Update a user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Order ID",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update User Request",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdatedUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or username already in use",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/password-reset": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Invalidate the password of a user, log them out of every session, revoke their API keys and email them a password reset link. Only for admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Force a password reset",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Password reset email sent",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
                     },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/reactivate": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lift the suspension of a user, only for admins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reactivate a user",
+                "parameters": [
                     {
                         "type": "string",
                         "description": "JWT \u003ctoken\u003e",
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "status",
+                        "description": "user",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusResponse"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdatedUserResponse"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Not suspended",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Bad server",
                         "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Restore a deleted user, who can log in again. Users whose personal data was erased cannot be restored. Only for admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Restore a deleted user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdatedUserResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Not deleted, anonymized, or email or username in use again",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Register a new user with the given details",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "auth"
-                ],
-                "summary": "Register a new user",
-                "parameters": [
-                    {
-                        "description": "User",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RegisterUserRequest"
-                        }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "User added",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
-                        }
-                    },
-                    "500": {
-                        "description": "Bad server",
-                        "schema": {
-                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
-                        }
+        "/admin/users/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Grant or revoke the admin role of a user by id, only for admins. The user is logged out of every session and has to log in again. The last admin cannot be demoted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Change the role of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update User Role Request",
+                        "name": "roleRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdateUserRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "role",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserRoleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Cannot remove the last admin",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the devices a user is logged in on, most recently used first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get the sessions of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "sessions",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SessionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/suspend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Suspend a user, who is logged out of every session, has their API keys revoked and cannot log in until reactivated. Only for admins.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Suspend a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Suspend User Request",
+                        "name": "suspend",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SuspendUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdatedUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Already suspended or own account",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/totp": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Turn off two-factor authentication for a user who lost their authenticator app and recovery codes, and log them out of every session, so that they can enroll again. Only for admins, who cannot reset their own.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset the two-factor authentication of a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication reset",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enabled, or own account",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lift the login lockout of a user after too many failed login attempts, only for admins",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unlock a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User unlocked",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "List the API keys of the logged-in user that have not been revoked, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List my API keys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API keys",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.APIKeysResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Create an API key that lets scripts call the offer and order routes on behalf of the logged-in user, within the granted scopes. Keys act as a buyer, even those of admins, who have to log in with two-factor authentication to create one. The key is only shown in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Create an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Create API Key Request",
+                        "name": "apiKey",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CreateAPIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CreatedAPIKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin without two-factor login",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Too many API keys",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke an API key of the logged-in user, which can no longer be used",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "API key revoked",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/checkout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Buy a list of orders. If you want to add more products, here is an example of the structure to follow:{\"orderItems\": [ { \"productID\": 1, \"quantity\": 2 }, { \"productID\": 2, \"quantity\": 1 }, { \"productID\": 3, \"quantity\": 5 } ] }",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Checkout",
+                "parameters": [
+                    {
+                        "description": "Checkout Request",
+                        "name": "checkout",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e, unless an API key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key with the orders:write scope",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Email not verified or scope not granted to the API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Offer not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Insufficient stock",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Login a user with the given credentials",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login a user",
+                "parameters": [
+                    {
+                        "description": "Login Request",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Two-factor authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed login attempts or rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login/totp": {
+            "post": {
+                "description": "Complete the challenge returned by a login of a user who enrolled in two-factor authentication, with a code of their authenticator app or one of their recovery codes. Wrong codes count towards the login lockout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Confirm a login with a second factor",
+                "parameters": [
+                    {
+                        "description": "Two-Factor Login Request",
+                        "name": "login",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TwoFactorLoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid challenge or code",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Account suspended",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed login attempts or rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke the access token used for the request and the refresh token of its session.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out of the current session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Logged out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout/all": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Revoke every access and refresh token issued to the user so far.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Log out of every session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Logged out",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/me": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the account of the logged-in user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get my account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ProfileResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Delete the account of the logged-in user. Their personal data is erased and their API keys are revoked, while their orders are kept for accounting.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Delete my account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Delete Account Request",
+                        "name": "delete",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Account deleted",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or incorrect password",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Last admin",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the username and/or the email of the logged-in user. A new email has to be verified again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Update my account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Update User Request",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "user",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ProfileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or username already in use",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/offers": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get all available offers",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get available offers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e, unless an API key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key with the offers:read scope",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "offers",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OffersResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Scope not granted to the API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/callback": {
+            "get": {
+                "description": "The identity provider sends the user back here after they log in. The identity is linked to the account with the same email if the provider verified it, otherwise a new account without a password is created. Returns the same tokens as a login with a password.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Complete a login with the identity provider",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization code handed back by the provider",
+                        "name": "code",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "State of the login handed back by the provider",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Error reported by the provider",
+                        "name": "error",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse"
+                        }
+                    },
+                    "202": {
+                        "description": "Two-factor authentication required",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginChallengeResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, unknown or expired login, or no email shared by the provider",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "The provider rejected or did not confirm the login",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Account suspended or deleted",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No identity provider is configured",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email used by an account the provider cannot be trusted with",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/oidc/login": {
+            "get": {
+                "description": "Redirect the user to the configured OpenID Connect provider to log in. The login has to be completed at the callback within 10 minutes.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login with the identity provider",
+                "responses": {
+                    "302": {
+                        "description": "Redirect to the identity provider"
+                    },
+                    "404": {
+                        "description": "No identity provider is configured",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/orders": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the orders placed by the logged in buyer, newest first, with their items. Results can be filtered by status and by creation date, and are paginated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get my orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e, unless an API key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key with the orders:read scope",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number, starting at 1",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Orders per page, at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only orders in this status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only orders created on or after this date (YYYY-MM-DD or RFC 3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only orders created on or before this date (YYYY-MM-DD or RFC 3339)",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "orders",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Scope not granted to the API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/orders/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the details of a specific order by id: its items with product name, unit price at purchase, quantity and line total, the order total, its timestamps and its status history. Buyers can only see their own orders, admins who logged in with two-factor authentication can see all of them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get a specific order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e, unless an API key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key with the orders:read scope",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "order",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderDetailResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Scope not granted to the API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/orders/{id}/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Cancel an order and return its items to the stock. Buyers can cancel their own orders while they are still pending, admins who logged in with two-factor authentication can cancel any order before it is shipped.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Cancel an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e, unless an API key is given",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "API key with the orders:write scope",
+                        "name": "X-API-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Cancel Order Request",
+                        "name": "cancelRequest",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CancelOrderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "status",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusUpdateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Scope not granted to the API key",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Order cannot be cancelled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password/change": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Change the password of the logged-in user, who is then logged out of every session and whose API keys are revoked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Change my password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Change Password Request",
+                        "name": "password",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password changed",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or incorrect current password",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password/forgot": {
+            "post": {
+                "description": "Send a single-use, time-limited password reset link to the email of an account. The response is the same whether or not the email is registered.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Ask for a password reset",
+                "parameters": [
+                    {
+                        "description": "Forgot Password Request",
+                        "name": "forgotPassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Password reset requested",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/password/reset": {
+            "post": {
+                "description": "Set a new password with the token of a password reset link. The token can only be used once, every session of the user is logged out and their API keys are revoked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Reset a password",
+                "parameters": [
+                    {
+                        "description": "Reset Password Request",
+                        "name": "resetPassword",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Password reset",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Exchange a refresh token for a new short-lived access token and a new refresh token. Each refresh token can only be used once; reusing one revokes every token issued since the login it came from.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh the tokens of a user",
+                "parameters": [
+                    {
+                        "description": "Refresh Request",
+                        "name": "refresh",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Register a new user with the given details. The account starts with an unverified email, and a verification link is sent to it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RegisterUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "User added",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email or username already in use",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get the devices the user is logged in on, most recently used first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get the sessions of the user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "sessions",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SessionsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sessions/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Log the user out of one of their devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Revoke a session of the user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Session revoked",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not found",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/totp": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Turn off two-factor authentication for the logged-in user, confirmed with their password and a code of the authenticator app or a recovery code. Admins cannot turn it off.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Turn off two-factor authentication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Disable TOTP Request",
+                        "name": "totp",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DisableTOTPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Two-factor authentication turned off",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request, incorrect password or incorrect code",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Not enabled, or mandatory for admins",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/totp/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Turn on two-factor authentication with a code of the authenticator app, and get the recovery codes, which are shown only this once. The current session counts as confirmed with two factors from its next refresh on.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Confirm the enrollment in two-factor authentication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "TOTP Code Request",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recovery codes",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or incorrect code",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Enrollment not started or already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/totp/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Generate a TOTP secret for the logged-in user to add to their authenticator app, by hand or as a QR code of the otpauth URL. It only takes effect once confirmed; starting again replaces it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Start enrolling in two-factor authentication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "TOTP secret",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TOTPEnrollmentResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication already enabled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/totp/recovery-codes": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replace the recovery codes of the logged-in user, confirmed with a code of the authenticator app or a recovery code. The new codes are shown only this once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get new recovery codes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "TOTP Code Request",
+                        "name": "code",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TOTPCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Recovery codes",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RecoveryCodesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request or incorrect code",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Two-factor authentication not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-email": {
+            "post": {
+                "description": "Verify the email of an account with the token of the link sent at registration",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify the email of a user",
+                "parameters": [
+                    {
+                        "description": "Verify Email Request",
+                        "name": "verifyEmail",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Email verified",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Rate limited",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-email/resend": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Send a new verification link to the email of the user, invalidating the previous one",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Resend the email verification link",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT \u003ctoken\u003e",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Verification email sent",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already verified",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Bad server",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.APIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.APIKeysResponse": {
+            "type": "object",
+            "properties": {
+                "api_keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.APIKeyResponse"
+                    }
+                },
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.AdminDashboardResponse": {
+            "type": "object",
+            "properties": {
+                "cancelled_orders": {
+                    "type": "integer"
+                },
+                "delivered_orders": {
+                    "type": "integer"
+                },
+                "pending_orders": {
+                    "type": "integer"
+                },
+                "preparing_orders": {
+                    "type": "integer"
+                },
+                "processing_orders": {
+                    "type": "integer"
+                },
+                "shipped_orders": {
+                    "type": "integer"
+                },
+                "total_orders": {
+                    "type": "integer"
+                },
+                "total_revenue": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.AuditEntriesResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.AuditEntry"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CancelOrderRequest": {
+            "type": "object",
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ChangePasswordRequest": {
+            "type": "object",
+            "required": [
+                "new_password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutMessage": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutRequest": {
+            "type": "object",
+            "required": [
+                "orderItems"
+            ],
+            "properties": {
+                "orderItems": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemRequest"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutMessage"
+                },
+                "order_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CreateAPIKeyRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "scopes"
+            ],
+            "properties": {
+                "expires_in_days": {
+                    "type": "integer",
+                    "maximum": 365,
+                    "minimum": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "scopes": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CreatedAPIKeyResponse": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.APIKeyResponse"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DashboardMessage": {
+            "type": "object",
+            "properties": {
+                "dashboard": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.AdminDashboardResponse"
+                },
+                "offers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OfferResponse"
+                    }
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderSummary"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DashboardResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DashboardMessage"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DeleteUserRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DisableTOTPRequest": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.FieldError"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.FieldError": {
+            "type": "object",
+            "properties": {
+                "field": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ForgotPasswordRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.JSONWebKey": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.JSONWebKeySet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.JSONWebKey"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginChallengeResponse": {
+            "type": "object",
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "auth": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "refresh_token": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OfferResponse": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OffersResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OfferResponse"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderDetail": {
+            "type": "object",
+            "properties": {
+                "cancel_reason": {
+                    "type": "string"
+                },
+                "cancelled_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "history": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusHistory"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemResponse"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderDetailResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "order": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderDetail"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemRequest": {
+            "type": "object",
+            "required": [
+                "productID"
+            ],
+            "properties": {
+                "productID": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemResponse": {
+            "type": "object",
+            "properties": {
+                "line_total": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "product_id": {
+                    "type": "integer"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "unit_price": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderListResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderSummary"
                     }
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
                 }
             }
-        }
-    },
-    "definitions": {
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutMessage": {
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusHistory": {
             "type": "object",
             "properties": {
-                "status": {
+                "changed_by": {
+                    "type": "integer"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "total": {
+                "from_status": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "order_id": {
                     "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "to_status": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutRequest": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusHistoryResponse": {
             "type": "object",
             "properties": {
-                "orderItems": {
+                "code": {
+                    "type": "string"
+                },
+                "history": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemRequest"
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusHistory"
                     }
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutResponse": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusUpdateRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "preparing",
+                        "processing",
+                        "shipped",
+                        "delivered",
+                        "cancelled"
+                    ]
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusUpdateResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "string"
                 },
-                "message": {
-                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.CheckoutMessage"
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderSummary": {
+            "type": "object",
+            "properties": {
+                "buyer_id": {
+                    "type": "integer"
                 },
-                "order_id": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemResponse"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                },
+                "total": {
                     "type": "integer"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.DeleteUserRequest": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ProfileResponse": {
             "type": "object",
             "properties": {
-                "email": {
+                "code": {
                     "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserResponse"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginRequest": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RecoveryCodesResponse": {
             "type": "object",
             "properties": {
-                "email": {
+                "code": {
+                    "type": "string"
+                },
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RefreshRequest": {
+            "type": "object",
+            "required": [
+                "refresh_token"
+            ],
+            "properties": {
+                "refresh_token": {
                     "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RegisterUserRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 254
                 },
                 "password": {
                     "type": "string"
+                },
+                "username": {
+                    "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.LoginResponse": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.ResetPasswordRequest": {
             "type": "object",
+            "required": [
+                "password",
+                "token"
+            ],
             "properties": {
-                "auth": {
+                "password": {
                     "type": "string"
                 },
-                "code": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Offer": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response": {
             "type": "object",
             "properties": {
-                "category": {
+                "code": {
                     "type": "string"
                 },
-                "createdAt": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
                     "type": "string"
                 },
-                "deletedAt": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                "current": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "name": {
+                "ip_address": {
                     "type": "string"
                 },
-                "price": {
-                    "type": "integer"
-                },
-                "quantity": {
-                    "type": "integer"
+                "last_seen_at": {
+                    "type": "string"
                 },
-                "updatedAt": {
+                "user_agent": {
                     "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OffersResponse": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SessionsResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "string"
                 },
-                "message": {
+                "sessions": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Offer"
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SessionResponse"
                     }
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderItemRequest": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.SuspendUserRequest": {
             "type": "object",
             "properties": {
-                "productID": {
-                    "type": "integer"
-                },
-                "quantity": {
-                    "type": "integer"
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusResponse": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TOTPCodeRequest": {
             "type": "object",
+            "required": [
+                "code"
+            ],
             "properties": {
                 "code": {
                     "type": "string"
-                },
-                "status": {
-                    "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusUpdateRequest": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TOTPEnrollmentResponse": {
             "type": "object",
             "properties": {
-                "status": {
+                "code": {
+                    "type": "string"
+                },
+                "otpauth_url": {
+                    "type": "string"
+                },
+                "secret": {
                     "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.OrderStatusUpdateResponse": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.TwoFactorLoginRequest": {
             "type": "object",
+            "required": [
+                "challenge_token",
+                "code"
+            ],
             "properties": {
-                "code": {
+                "challenge_token": {
                     "type": "string"
                 },
-                "status": {
+                "code": {
                     "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.RegisterUserRequest": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdateUserRequest": {
             "type": "object",
             "properties": {
                 "email": {
-                    "type": "string"
-                },
-                "password": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 254
                 },
                 "username": {
                     "type": "string"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdateUserRoleRequest": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "buyer",
+                        "admin"
+                    ]
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UpdatedUserResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "string"
                 },
-                "message": {
-                    "type": "string"
+                "user": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserResponse"
                 }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.Response_d": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserDetailResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "string"
                 },
-                "message": {}
+                "order_stats": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserOrderStats"
+                },
+                "user": {
+                    "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserResponse"
+                }
+            }
+        },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserOrderStats": {
+            "type": "object",
+            "properties": {
+                "last_order_at": {
+                    "type": "string"
+                },
+                "orders_by_status": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "total_orders": {
+                    "type": "integer"
+                },
+                "total_spent": {
+                    "type": "integer"
+                }
             }
         },
-        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.User": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserResponse": {
             "type": "object",
             "properties": {
-                "createdAt": {
+                "anonymized_at": {
+                    "description": "Only set once the personal data of a deleted account was erased.",
+                    "type": "string"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "deletedAt": {
-                    "$ref": "#/definitions/gorm.DeletedAt"
+                "deleted_at": {
+                    "description": "Only set for deleted accounts.",
+                    "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
+                "email_verified_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "password": {
+                "role": {
                     "type": "string"
                 },
-                "token": {
+                "suspend_reason": {
                     "type": "string"
                 },
-                "updatedAt": {
+                "suspended_at": {
+                    "description": "Only set for suspended accounts.",
                     "type": "string"
                 },
+                "two_factor": {
+                    "description": "Whether the user logs in with two-factor authentication.",
+                    "type": "boolean"
+                },
                 "username": {
                     "type": "string"
                 }
             }
         },
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserRoleResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UsersResponse": {
             "type": "object",
             "properties": {
                 "code": {
                     "type": "string"
                 },
+                "limit": {
+                    "type": "integer"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
                 "users": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.User"
+                        "$ref": "#/definitions/github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.UserResponse"
                     }
                 }
             }
         },
-        "gorm.DeletedAt": {
+        "github_com_ICOMP-UNC_newworld-gastonsegura2908_git_internal_models.VerifyEmailRequest": {
             "type": "object",
+            "required": [
+                "token"
+            ],
             "properties": {
-                "time": {
+                "token": {
                     "type": "string"
-                },
-                "valid": {
-                    "description": "Valid is true if Time is not NULL",
-                    "type": "boolean"
                 }
             }
         }
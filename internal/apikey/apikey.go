@@ -0,0 +1,101 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+/**
+ * @brief Marker every API key starts with, so that leaked keys are easy to recognize and scan for.
+ */
+const marker = "nwk_"
+
+/**
+ * @brief Number of random bytes of the public part of a key, which identifies it.
+ */
+const idLength = 6
+
+/**
+ * @brief Number of random bytes of the secret part of a key.
+ */
+const secretLength = 32
+
+/**
+ * @struct Key
+ * @brief A freshly generated API key.
+ *
+ * The full key is only ever shown once, to the user creating it. Only its
+ * public prefix, which identifies the key, and the hash of the full key are
+ * stored.
+ */
+type Key struct {
+	Key    string
+	Prefix string
+	Hash   string
+}
+
+/**
+ * @brief Generates a new random API key.
+ *
+ * Keys look like "nwk_<id>_<secret>", where "nwk_<id>" is the prefix.
+ *
+ * @return The key, its prefix and its hash, or an error if no random bytes are available.
+ */
+func Generate() (*Key, error) {
+	id := make([]byte, idLength)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+
+	prefix := marker + hex.EncodeToString(id)
+	key := prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return &Key{Key: key, Prefix: prefix, Hash: Hash(key)}, nil
+}
+
+/**
+ * @brief Extracts the prefix of an API key.
+ *
+ * @param key The API key.
+ * @return The prefix and true, or an empty string and false if the key is malformed.
+ */
+func Prefix(key string) (string, bool) {
+	if !strings.HasPrefix(key, marker) {
+		return "", false
+	}
+
+	i := strings.Index(key[len(marker):], "_")
+	if i != 2*idLength {
+		return "", false
+	}
+	return key[:len(marker)+i], true
+}
+
+/**
+ * @brief Hashes an API key so that it can be stored without keeping its value.
+ *
+ * @param key The API key.
+ * @return The hex-encoded SHA-256 hash of the key.
+ */
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+/**
+ * @brief Checks an API key against a stored hash in constant time.
+ *
+ * @param key The API key presented by the client.
+ * @param hash The stored hash.
+ * @return True if the key matches the hash.
+ */
+func Matches(key, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(hash)) == 1
+}
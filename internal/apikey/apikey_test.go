@@ -0,0 +1,95 @@
+package apikey
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+/**
+ * @brief Checks that generated keys have the documented format, their prefix and their hash.
+ */
+func TestGenerate(t *testing.T) {
+	key, err := Generate()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	if !regexp.MustCompile(`^nwk_[0-9a-f]{12}_[A-Za-z0-9_-]{43}$`).MatchString(key.Key) {
+		t.Fatalf("unexpected key %q", key.Key)
+	}
+	if !strings.HasPrefix(key.Key, key.Prefix+"_") || len(key.Prefix) != len(marker)+2*idLength {
+		t.Fatalf("unexpected prefix %q of key %q", key.Prefix, key.Key)
+	}
+	if prefix, ok := Prefix(key.Key); !ok || prefix != key.Prefix {
+		t.Fatalf("expected the prefix of the key to be %q, got %q", key.Prefix, prefix)
+	}
+	if key.Hash != Hash(key.Key) || len(key.Hash) != 64 {
+		t.Fatalf("unexpected hash %q", key.Hash)
+	}
+
+	other, err := Generate()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if other.Key == key.Key || other.Prefix == key.Prefix {
+		t.Fatalf("expected keys to be random")
+	}
+}
+
+/**
+ * @brief Checks that only keys of the form nwk_<12 hex characters>_<secret> yield a prefix.
+ */
+func TestPrefix(t *testing.T) {
+	cases := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"nwk_0123456789ab_secret", "nwk_0123456789ab", true},
+		{"nwk_0123456789ab_secret_with_underscores", "nwk_0123456789ab", true},
+		{"nwk_0123456789ab_", "nwk_0123456789ab", true},
+		{"nwk_0123456789a_secret", "", false},
+		{"nwk_0123456789abc_secret", "", false},
+		{"nwk_0123456789ab", "", false},
+		{"xyz_0123456789ab_secret", "", false},
+		{"NWK_0123456789ab_secret", "", false},
+		{"nwk__secret", "", false},
+		{"", "", false},
+	}
+	for _, tc := range cases {
+		got, ok := Prefix(tc.key)
+		if ok != tc.wantOK || got != tc.want {
+			t.Errorf("%q: expected %q, %v, got %q, %v", tc.key, tc.want, tc.wantOK, got, ok)
+		}
+	}
+}
+
+/**
+ * @brief Checks that a key only matches its own hash.
+ */
+func TestMatches(t *testing.T) {
+	key, err := Generate()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		key  string
+		hash string
+		want bool
+	}{
+		{"own hash", key.Key, key.Hash, true},
+		{"uppercase hash", key.Key, strings.ToUpper(key.Hash), false},
+		{"altered secret", key.Key + "x", key.Hash, false},
+		{"prefix only", key.Prefix, key.Hash, false},
+		{"hash as key", key.Hash, key.Hash, false},
+		{"empty hash", key.Key, "", false},
+	}
+	for _, tc := range cases {
+		if got := Matches(tc.key, tc.hash); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
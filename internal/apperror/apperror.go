@@ -0,0 +1,186 @@
+package apperror
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+)
+
+/**
+ * @brief Kind of failure an Error describes, which decides the HTTP status it is reported with.
+ */
+type Kind int
+
+const (
+	KindInvalid Kind = iota + 1
+	KindUnauthorized
+	KindInvalidCredentials
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindInsufficientStock
+	KindTooManyRequests
+	KindUnavailable
+)
+
+/**
+ * @struct Error
+ * @brief A domain error that can be reported to API clients.
+ *
+ * Code is a stable, machine-readable identifier such as "order_not_found" that
+ * clients can switch on, while Message is meant for humans. Validation errors
+ * also carry the failing fields, and throttled requests when they can be retried.
+ */
+type Error struct {
+	Kind       Kind
+	Code       string
+	Message    string
+	Fields     []models.FieldError
+	RetryAfter time.Duration
+}
+
+/**
+ * @brief Returns the human readable message of the error.
+ *
+ * @return The message of the error.
+ */
+func (e *Error) Error() string {
+	return e.Message
+}
+
+/**
+ * @brief Returns the HTTP status the error is reported with.
+ *
+ * @return The HTTP status code.
+ */
+func (e *Error) Status() int {
+	switch e.Kind {
+	case KindInvalid:
+		return http.StatusBadRequest
+	case KindUnauthorized, KindInvalidCredentials:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict, KindInsufficientStock:
+		return http.StatusConflict
+	case KindTooManyRequests:
+		return http.StatusTooManyRequests
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+/**
+ * @brief Creates an error for a request that is malformed or breaks a business rule on its input.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func Invalid(code, message string) *Error {
+	return &Error{Kind: KindInvalid, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for a request body that failed validation.
+ *
+ * @param fields The failing fields and why they were rejected.
+ * @return The error.
+ */
+func Validation(fields []models.FieldError) *Error {
+	return &Error{Kind: KindInvalid, Code: "validation_failed", Message: "request validation failed", Fields: fields}
+}
+
+/**
+ * @brief Creates an error for a request without valid authentication.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for a login with a wrong email or password.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func InvalidCredentials(code, message string) *Error {
+	return &Error{Kind: KindInvalidCredentials, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for an authenticated user who is not allowed to perform the request.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for a resource that does not exist or is not visible to the user.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for a request that conflicts with the current state of a resource.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for an order asking for more units than are in stock.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func InsufficientStock(code, message string) *Error {
+	return &Error{Kind: KindInsufficientStock, Code: code, Message: message}
+}
+
+/**
+ * @brief Creates an error for a request rejected because too many were made.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @param retryAfter How long the client has to wait before retrying.
+ * @return The error.
+ */
+func TooManyRequests(code, message string, retryAfter time.Duration) *Error {
+	return &Error{Kind: KindTooManyRequests, Code: code, Message: message, RetryAfter: retryAfter}
+}
+
+/**
+ * @brief Creates an error for a request that cannot be served until the server is ready for it.
+ *
+ * @param code The machine-readable code of the error.
+ * @param message The human readable message of the error.
+ * @return The error.
+ */
+func Unavailable(code, message string) *Error {
+	return &Error{Kind: KindUnavailable, Code: code, Message: message}
+}
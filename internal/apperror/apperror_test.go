@@ -0,0 +1,34 @@
+package apperror
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+/**
+ * @brief Checks the HTTP status every kind of error is reported with.
+ */
+func TestStatus(t *testing.T) {
+	cases := []struct {
+		err  *Error
+		want int
+	}{
+		{Invalid("invalid", "invalid"), http.StatusBadRequest},
+		{Validation(nil), http.StatusBadRequest},
+		{Unauthorized("unauthorized", "unauthorized"), http.StatusUnauthorized},
+		{InvalidCredentials("invalid_credentials", "invalid credentials"), http.StatusUnauthorized},
+		{Forbidden("forbidden", "forbidden"), http.StatusForbidden},
+		{NotFound("not_found", "not found"), http.StatusNotFound},
+		{Conflict("conflict", "conflict"), http.StatusConflict},
+		{InsufficientStock("insufficient_stock", "insufficient stock"), http.StatusConflict},
+		{TooManyRequests("rate_limited", "too many requests", time.Second), http.StatusTooManyRequests},
+		{Unavailable("unavailable", "unavailable"), http.StatusServiceUnavailable},
+		{&Error{Code: "unknown"}, http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		if got := tc.err.Status(); got != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.err.Code, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package lockout
+
+import (
+	"strings"
+	"time"
+)
+
+/**
+ * @struct Config
+ * @brief Thresholds and delays applied to failed login attempts.
+ *
+ * Every failure blocks further attempts on the account and from the IP address
+ * for BaseDelay, doubling with each failure up to MaxDelay. Once the failures of
+ * the current Window reach AccountThreshold or IPThreshold, the account or the IP
+ * address is locked out for LockoutDuration.
+ */
+type Config struct {
+	AccountThreshold int
+	IPThreshold      int
+	Window           time.Duration
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	LockoutDuration  time.Duration
+}
+
+/**
+ * @struct Result
+ * @brief Outcome of recording a failed login attempt.
+ *
+ * AccountLocked and IPLocked are only set by the failure that triggers the
+ * lockout, so that it is reported once.
+ */
+type Result struct {
+	AccountLocked bool
+	IPLocked      bool
+	LockedUntil   time.Time
+}
+
+/**
+ * @interface Guard
+ * @brief Interface for throttling login attempts per account and per IP address.
+ */
+type Guard interface {
+	BlockedUntil(email, ip string) (time.Time, error)
+	RecordFailure(email, ip string) (Result, error)
+	RecordSuccess(email string) error
+	Unlock(email string) error
+}
+
+/**
+ * @brief guard struct provides the implementation of Guard.
+ */
+type guard struct {
+	store  Store
+	config Config
+}
+
+/**
+ * @brief NewGuard creates a new Guard keeping its attempts in the given store.
+ *
+ * @param store The store of the failed attempts.
+ * @param config The thresholds and delays to apply.
+ * @return A new Guard instance.
+ */
+func NewGuard(store Store, config Config) Guard {
+	return &guard{store: store, config: config}
+}
+
+/**
+ * @brief Returns when login attempts on an account or from an IP address are allowed again.
+ *
+ * @param email The email the login is attempted with.
+ * @param ip The IP address the login is attempted from.
+ * @return The latest end of the blocks of the account and the IP address, which is
+ *         in the past or zero if attempts are allowed, and an error if the store fails.
+ */
+func (g *guard) BlockedUntil(email, ip string) (time.Time, error) {
+	accountUntil, err := g.store.BlockedUntil(accountKey(email))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ipUntil, err := g.store.BlockedUntil(ipKey(ip))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if ipUntil.After(accountUntil) {
+		return ipUntil, nil
+	}
+	return accountUntil, nil
+}
+
+/**
+ * @brief Records a failed login attempt and blocks the account and the IP address accordingly.
+ *
+ * @param email The email the login was attempted with.
+ * @param ip The IP address the login was attempted from.
+ * @return Whether the attempt locked out the account or the IP address, and an error if the store fails.
+ */
+func (g *guard) RecordFailure(email, ip string) (Result, error) {
+	var result Result
+
+	locked, until, err := g.fail(accountKey(email), g.config.AccountThreshold)
+	if err != nil {
+		return result, err
+	}
+	result.AccountLocked = locked
+	result.LockedUntil = until
+
+	locked, until, err = g.fail(ipKey(ip), g.config.IPThreshold)
+	if err != nil {
+		return result, err
+	}
+	result.IPLocked = locked
+	if until.After(result.LockedUntil) {
+		result.LockedUntil = until
+	}
+
+	return result, nil
+}
+
+/**
+ * @brief Records a failure against a key and blocks it for the resulting delay.
+ *
+ * @param key The key the failure is counted against.
+ * @param threshold The number of failures that locks the key out.
+ * @return Whether this failure locked the key out, when the block ends, and an error if the store fails.
+ */
+func (g *guard) fail(key string, threshold int) (bool, time.Time, error) {
+	failures, err := g.store.RecordFailure(key, g.config.Window)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	delay := g.config.LockoutDuration
+	if failures < threshold {
+		delay = g.backoff(failures)
+	}
+
+	until := time.Now().Add(delay)
+	if err := g.store.Block(key, until); err != nil {
+		return false, time.Time{}, err
+	}
+	return failures == threshold, until, nil
+}
+
+/**
+ * @brief Returns the delay imposed after the given number of failures, before the lockout threshold.
+ *
+ * @param failures The number of failures, starting at 1.
+ * @return BaseDelay doubled for every failure after the first one, capped at MaxDelay.
+ */
+func (g *guard) backoff(failures int) time.Duration {
+	delay := g.config.BaseDelay
+	for i := 1; i < failures && delay < g.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.config.MaxDelay {
+		delay = g.config.MaxDelay
+	}
+	return delay
+}
+
+/**
+ * @brief Forgets the failed attempts on an account after a successful login.
+ *
+ * Failures from the IP address are kept, so that logging into an account one
+ * controls does not reset the attempts made against other accounts.
+ *
+ * @param email The email of the account.
+ * @return An error if the store fails.
+ */
+func (g *guard) RecordSuccess(email string) error {
+	return g.store.Reset(accountKey(email))
+}
+
+/**
+ * @brief Lifts the lockout of an account and forgets its failed attempts.
+ *
+ * @param email The email of the account.
+ * @return An error if the store fails.
+ */
+func (g *guard) Unlock(email string) error {
+	return g.store.Reset(accountKey(email))
+}
+
+/**
+ * @brief Returns the key the attempts on an account are counted against.
+ *
+ * @param email The email of the account, compared case-insensitively.
+ * @return The key of the account.
+ */
+func accountKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+/**
+ * @brief Returns the key the attempts from an IP address are counted against.
+ *
+ * @param ip The IP address.
+ * @return The key of the IP address.
+ */
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,186 @@
+package lockout
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+var testConfig = Config{
+	AccountThreshold: 3,
+	IPThreshold:      5,
+	Window:           15 * time.Minute,
+	BaseDelay:        time.Second,
+	MaxDelay:         10 * time.Second,
+	LockoutDuration:  15 * time.Minute,
+}
+
+/**
+ * @brief Checks that a time lies within a second after the given offset from now.
+ *
+ * @param t The test.
+ * @param name The name of the checked time.
+ * @param got The checked time.
+ * @param offset The expected offset from now.
+ */
+func assertAfterNow(t *testing.T, name string, got time.Time, offset time.Duration) {
+	t.Helper()
+
+	want := time.Now().Add(offset)
+	if got.After(want) || want.Sub(got) > time.Second {
+		t.Fatalf("expected %s to be about %v from now, got %v", name, offset, time.Until(got))
+	}
+}
+
+/**
+ * @brief Checks that the delay doubles with every failure and is capped at MaxDelay.
+ */
+func TestBackoff(t *testing.T) {
+	g := &guard{config: testConfig}
+
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := g.backoff(tc.failures); got != tc.want {
+			t.Errorf("%d failures: expected %v, got %v", tc.failures, tc.want, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks that failures block the account with a growing delay until the threshold locks it out once.
+ */
+func TestRecordFailureLocksOutAtThreshold(t *testing.T) {
+	g := NewGuard(NewMemoryStore(), testConfig)
+
+	for i, delay := range []time.Duration{time.Second, 2 * time.Second} {
+		result, err := g.RecordFailure("dave@example.com", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("failed to record failure: %v", err)
+		}
+		if result.AccountLocked || result.IPLocked {
+			t.Fatalf("failure %d: expected no lockout before the threshold", i+1)
+		}
+		assertAfterNow(t, "the block", result.LockedUntil, delay)
+	}
+
+	result, err := g.RecordFailure("dave@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	if !result.AccountLocked || result.IPLocked {
+		t.Fatalf("expected the third failure to lock out the account only, got %+v", result)
+	}
+	assertAfterNow(t, "the lockout", result.LockedUntil, testConfig.LockoutDuration)
+
+	until, err := g.BlockedUntil("dave@example.com", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("failed to check block: %v", err)
+	}
+	assertAfterNow(t, "the block of the account", until, testConfig.LockoutDuration)
+
+	result, err = g.RecordFailure("dave@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to record failure: %v", err)
+	}
+	if result.AccountLocked {
+		t.Fatalf("expected the lockout to be reported only once")
+	}
+}
+
+/**
+ * @brief Checks that failures on different accounts from one IP address lock out the address.
+ */
+func TestRecordFailureLocksOutIP(t *testing.T) {
+	g := NewGuard(NewMemoryStore(), testConfig)
+
+	var result Result
+	for i := 0; i < testConfig.IPThreshold; i++ {
+		var err error
+		result, err = g.RecordFailure(fmt.Sprintf("user%d@example.com", i), "10.0.0.1")
+		if err != nil {
+			t.Fatalf("failed to record failure: %v", err)
+		}
+	}
+	if !result.IPLocked || result.AccountLocked {
+		t.Fatalf("expected the IP address to be locked out, got %+v", result)
+	}
+
+	until, err := g.BlockedUntil("other@example.com", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("failed to check block: %v", err)
+	}
+	assertAfterNow(t, "the block of the IP address", until, testConfig.LockoutDuration)
+}
+
+/**
+ * @brief Checks that the email of an account is compared case-insensitively and without surrounding spaces.
+ */
+func TestAccountKeyIgnoresCase(t *testing.T) {
+	g := NewGuard(NewMemoryStore(), testConfig)
+
+	for _, email := range []string{"Dave@Example.com", " dave@example.com", "DAVE@EXAMPLE.COM"} {
+		if _, err := g.RecordFailure(email, "10.0.0.1"); err != nil {
+			t.Fatalf("failed to record failure: %v", err)
+		}
+	}
+
+	until, err := g.BlockedUntil("dave@example.com", "10.0.0.2")
+	if err != nil {
+		t.Fatalf("failed to check block: %v", err)
+	}
+	assertAfterNow(t, "the block of the account", until, testConfig.LockoutDuration)
+}
+
+/**
+ * @brief Checks that a successful login and an admin unlock lift the block of the account but not of the IP address.
+ */
+func TestResetLiftsAccountBlock(t *testing.T) {
+	cases := map[string]func(g Guard) error{
+		"success": func(g Guard) error { return g.RecordSuccess("Dave@example.com") },
+		"unlock":  func(g Guard) error { return g.Unlock("Dave@example.com") },
+	}
+	for name, reset := range cases {
+		g := NewGuard(NewMemoryStore(), testConfig)
+		for i := 0; i < testConfig.AccountThreshold; i++ {
+			if _, err := g.RecordFailure("dave@example.com", "10.0.0.1"); err != nil {
+				t.Fatalf("%s: failed to record failure: %v", name, err)
+			}
+		}
+
+		if err := reset(g); err != nil {
+			t.Fatalf("%s: failed to reset: %v", name, err)
+		}
+
+		until, err := g.BlockedUntil("dave@example.com", "10.0.0.2")
+		if err != nil {
+			t.Fatalf("%s: failed to check block: %v", name, err)
+		}
+		if !until.IsZero() {
+			t.Fatalf("%s: expected the account to be unblocked, got %v", name, until)
+		}
+
+		until, err = g.BlockedUntil("dave@example.com", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("%s: failed to check block: %v", name, err)
+		}
+		if !until.After(time.Now()) {
+			t.Fatalf("%s: expected the IP address to stay blocked", name)
+		}
+
+		result, err := g.RecordFailure("dave@example.com", "10.0.0.2")
+		if err != nil {
+			t.Fatalf("%s: failed to record failure: %v", name, err)
+		}
+		assertAfterNow(t, "the block after the reset", result.LockedUntil, testConfig.BaseDelay)
+	}
+}
@@ -0,0 +1,140 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+/**
+ * @brief Minimum time between two sweeps of the expired entries of a memory store.
+ */
+const sweepInterval = time.Minute
+
+/**
+ * @struct memoryEntry
+ * @brief Failed attempts counted against a key of a memory store.
+ */
+type memoryEntry struct {
+	failures     int
+	windowEnds   time.Time
+	blockedUntil time.Time
+}
+
+/**
+ * @brief memoryStore struct provides an implementation of Store that keeps the attempts in memory.
+ */
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+/**
+ * @brief NewMemoryStore creates a new Store that keeps the attempts in memory.
+ *
+ * Attempts are lost when the process exits and are not shared between
+ * instances, so it is only suited to a single instance of the API.
+ *
+ * @return A new Store instance.
+ */
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+/**
+ * @brief Records a failed attempt against a key.
+ *
+ * @param key The key the attempt is counted against.
+ * @param window How long failures are remembered, counted from the first one.
+ * @return The number of failures within the current window.
+ */
+func (s *memoryStore) RecordFailure(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	if !now.Before(entry.windowEnds) {
+		entry.failures = 0
+		entry.windowEnds = now.Add(window)
+	}
+	entry.failures++
+	return entry.failures, nil
+}
+
+/**
+ * @brief Blocks a key until the given time.
+ *
+ * @param key The key to block.
+ * @param until When the block ends.
+ * @return Always nil.
+ */
+func (s *memoryStore) Block(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.blockedUntil = until
+	return nil
+}
+
+/**
+ * @brief Returns when the block of a key ends.
+ *
+ * @param key The key to check.
+ * @return The end of the block, which is in the past or zero if the key is not blocked.
+ */
+func (s *memoryStore) BlockedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok {
+		return time.Time{}, nil
+	}
+	return entry.blockedUntil, nil
+}
+
+/**
+ * @brief Forgets the failures and the block of a key.
+ *
+ * @param key The key to reset.
+ * @return Always nil.
+ */
+func (s *memoryStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
+
+/**
+ * @brief Removes the entries whose failures and block have both expired.
+ *
+ * Sweeps run at most once per sweepInterval. The caller must hold the lock.
+ *
+ * @param now The current time.
+ */
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.entries {
+		if !now.Before(entry.windowEnds) && !now.Before(entry.blockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
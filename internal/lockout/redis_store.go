@@ -0,0 +1,102 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/**
+ * @brief Increments the failure counter of a key, starting its window on the first failure.
+ */
+var recordFailureScript = redis.NewScript(`
+local failures = redis.call("INCR", KEYS[1])
+if failures == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return failures
+`)
+
+/**
+ * @brief redisStore struct provides an implementation of Store backed by Redis.
+ */
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+/**
+ * @brief NewRedisStore creates a new Store backed by Redis.
+ *
+ * Attempts are shared by every instance of the API using the same Redis server,
+ * and expire on their own, so nothing has to be cleaned up.
+ *
+ * @param client The Redis client to use.
+ * @param prefix The prefix of the Redis keys, e.g. "lockout:".
+ * @return A new Store instance.
+ */
+func NewRedisStore(client *redis.Client, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
+
+/**
+ * @brief Records a failed attempt against a key.
+ *
+ * @param key The key the attempt is counted against.
+ * @param window How long failures are remembered, counted from the first one.
+ * @return The number of failures within the current window and an error if Redis cannot be reached.
+ */
+func (s *redisStore) RecordFailure(key string, window time.Duration) (int, error) {
+	failures, err := recordFailureScript.Run(context.Background(), s.client,
+		[]string{s.prefix + "failures:" + key}, window.Milliseconds()).Int()
+	if err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+/**
+ * @brief Blocks a key until the given time.
+ *
+ * @param key The key to block.
+ * @param until When the block ends.
+ * @return An error if Redis cannot be reached.
+ */
+func (s *redisStore) Block(key string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.client.Set(context.Background(), s.prefix+"blocked:"+key,
+		strconv.FormatInt(until.UnixMilli(), 10), ttl).Err()
+}
+
+/**
+ * @brief Returns when the block of a key ends.
+ *
+ * @param key The key to check.
+ * @return The end of the block, or zero if the key is not blocked, and an error if Redis cannot be reached.
+ */
+func (s *redisStore) BlockedUntil(key string) (time.Time, error) {
+	until, err := s.client.Get(context.Background(), s.prefix+"blocked:"+key).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.UnixMilli(until), nil
+}
+
+/**
+ * @brief Forgets the failures and the block of a key.
+ *
+ * @param key The key to reset.
+ * @return An error if Redis cannot be reached.
+ */
+func (s *redisStore) Reset(key string) error {
+	return s.client.Del(context.Background(), s.prefix+"failures:"+key, s.prefix+"blocked:"+key).Err()
+}
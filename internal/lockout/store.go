@@ -0,0 +1,18 @@
+package lockout
+
+import "time"
+
+/**
+ * @interface Store
+ * @brief Interface for keeping track of failed login attempts.
+ *
+ * Keys identify what attempts are counted against, such as an account or an IP
+ * address. This interface is implemented by an in-memory store for a single
+ * instance of the API and by a Redis-backed store shared between instances.
+ */
+type Store interface {
+	RecordFailure(key string, window time.Duration) (int, error)
+	Block(key string, until time.Time) error
+	BlockedUntil(key string) (time.Time, error)
+	Reset(key string) error
+}
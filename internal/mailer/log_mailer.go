@@ -0,0 +1,58 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+/**
+ * @brief logMailer struct provides an implementation of Mailer that writes emails to a file or to the log instead of delivering them.
+ */
+type logMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+/**
+ * @brief NewLogMailer creates a new Mailer that writes emails instead of delivering them.
+ *
+ * Emails are appended to the given file, or written to the log if no file is
+ * given, so that links sent to users can be followed in local development and tests.
+ *
+ * @param path The file the emails are appended to, or an empty string to use the log.
+ * @return A new Mailer instance.
+ */
+func NewLogMailer(path string) Mailer {
+	return &logMailer{path: path}
+}
+
+/**
+ * @brief Writes an email to the file or to the log.
+ *
+ * @param message The email to write.
+ * @return An error if the file cannot be written.
+ */
+func (m *logMailer) Send(message Message) error {
+	entry := fmt.Sprintf("Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC1123Z), message.To, message.Subject, message.Body)
+
+	if m.path == "" {
+		log.Printf("Email not delivered, no mail server configured:\n%s", entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(entry)
+	return err
+}
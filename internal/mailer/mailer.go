@@ -0,0 +1,22 @@
+package mailer
+
+/**
+ * @struct Message
+ * @brief Structure representing a plain text email.
+ */
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+/**
+ * @interface Mailer
+ * @brief Interface for delivering emails to users.
+ *
+ * This interface is implemented by an SMTP mailer for production and by a
+ * log-based mailer for local development and tests.
+ */
+type Mailer interface {
+	Send(message Message) error
+}
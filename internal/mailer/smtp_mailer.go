@@ -0,0 +1,62 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+/**
+ * @struct SMTPConfig
+ * @brief Settings of the SMTP server used to deliver emails.
+ *
+ * Username and Password are optional; without them no authentication is attempted.
+ */
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+/**
+ * @brief smtpMailer struct provides an implementation of Mailer that delivers emails through an SMTP server.
+ */
+type smtpMailer struct {
+	config SMTPConfig
+}
+
+/**
+ * @brief NewSMTPMailer creates a new Mailer delivering emails through an SMTP server.
+ *
+ * @param config The settings of the SMTP server.
+ * @return A new Mailer instance.
+ */
+func NewSMTPMailer(config SMTPConfig) Mailer {
+	return &smtpMailer{config: config}
+}
+
+/**
+ * @brief Sends an email through the SMTP server.
+ *
+ * @param message The email to send.
+ * @return An error if the delivery fails.
+ */
+func (m *smtpMailer) Send(message Message) error {
+	if strings.ContainsAny(message.To+message.Subject, "\r\n") {
+		return fmt.Errorf("invalid email header")
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	body := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		m.config.From, message.To, message.Subject, message.Body)
+
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	return smtp.SendMail(addr, auth, m.config.From, []string{message.To}, []byte(body))
+}
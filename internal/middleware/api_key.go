@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apikey"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief Header carrying the API key of requests made by scripts.
+ */
+const APIKeyHeader = "X-API-Key"
+
+/**
+ * @brief Minimum time between two updates of the last-used time of an API key.
+ */
+const apiKeyTouchInterval = time.Minute
+
+/**
+ * @brief Middleware to authenticate requests with either an API key or a JWT.
+ *
+ * Requests carrying an X-API-Key header are authenticated with the key, which
+ * must exist, match its stored hash and be neither revoked nor expired. Other
+ * requests must carry a JWT, checked as Protected and RequireAuth do. Either
+ * way, the request is rejected with a 401 Unauthorized status if the credential
+ * is not valid or its user no longer exists, and with a 403 Forbidden status if
+ * the user has been suspended. Otherwise the authenticated Principal is
+ * attached to the request context. Routes using it should also use
+ * RequireScope, so that API keys are limited to what they were created for.
+ *
+ * @param userRepo The user repository to query the user data.
+ * @param tokenRepo The token repository to query the revoked tokens.
+ * @param sessionRepo The session repository to record the activity of the session.
+ * @param apiKeyRepo The API key repository to look up the keys.
+ * @return A fiber.Handler that authenticates the user.
+ */
+func RequireAuthOrAPIKey(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository, apiKeyRepo repository.APIKeyRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key := c.Get(APIKeyHeader); key != "" {
+			if err := authenticateAPIKey(c, key, userRepo, apiKeyRepo); err != nil {
+				return err
+			}
+			return c.Next()
+		}
+
+		claims, err := parseJWT(c)
+		if err != nil {
+			return err
+		}
+		c.Locals("user", claims)
+
+		if err := authenticateJWT(c, claims, userRepo, tokenRepo, sessionRepo); err != nil {
+			return err
+		}
+		return c.Next()
+	}
+}
+
+/**
+ * @brief Middleware to restrict routes to API keys granting the given scope.
+ *
+ * This middleware must run after RequireAuthOrAPIKey. Requests authenticated
+ * with a JWT always pass, while requests made with an API key that does not
+ * grant the scope are rejected with a 403 Forbidden status.
+ *
+ * @param scope The scope required by the route, e.g. "orders:write".
+ * @return A fiber.Handler that checks the scopes of the API key.
+ */
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			return apperror.Unauthorized("unauthorized", "authentication required")
+		}
+
+		if !principal.HasScope(scope) {
+			return apperror.Forbidden("insufficient_scope", "the API key does not grant the "+scope+" scope")
+		}
+		return c.Next()
+	}
+}
+
+/**
+ * @brief Identifies the user of an API key and attaches the Principal to the request context.
+ *
+ * @param c The Fiber context.
+ * @param key The API key presented by the client.
+ * @param userRepo The user repository to query the user data.
+ * @param apiKeyRepo The API key repository to look up the key.
+ * @return An error if the key is not valid, or its user no longer exists or is suspended.
+ */
+func authenticateAPIKey(c *fiber.Ctx, key string, userRepo repository.UserRepository, apiKeyRepo repository.APIKeyRepository) error {
+	invalid := apperror.Unauthorized("invalid_api_key", "invalid, expired or revoked API key")
+
+	prefix, ok := apikey.Prefix(key)
+	if !ok {
+		return invalid
+	}
+
+	stored, err := apiKeyRepo.GetAPIKeyByPrefix(prefix)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return invalid
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if !apikey.Matches(key, stored.KeyHash) || stored.RevokedAt != nil || (stored.ExpiresAt != nil && now.After(*stored.ExpiresAt)) {
+		return invalid
+	}
+
+	user, err := userRepo.GetUserByID(stored.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Unauthorized("unauthorized", "the user of the API key no longer exists")
+	}
+	if err != nil {
+		return err
+	}
+	if user.SuspendedAt != nil {
+		return apperror.Forbidden("account_suspended", "this account has been suspended")
+	}
+
+	if err := apiKeyRepo.TouchAPIKey(stored.ID, now, apiKeyTouchInterval); err != nil {
+		log.Printf("Failed to record use of API key %d: %v", stored.ID, err)
+	}
+
+	// Keys only grant access to the user's own offers and orders, so even the
+	// keys of admins act as buyers.
+	c.Locals(principalKey, &Principal{
+		UserID:        user.ID,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		Role:          models.RoleBuyer,
+		APIKeyID:      stored.ID,
+		Scopes:        stored.ScopeList(),
+	})
+	return nil
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apikey"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type stubAPIKeyRepository struct {
+	repository.APIKeyRepository
+	keys map[string]*models.APIKey
+}
+
+func (r *stubAPIKeyRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	if key, ok := r.keys[prefix]; ok {
+		return key, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *stubAPIKeyRepository) TouchAPIKey(id uint, usedAt time.Time, interval time.Duration) error {
+	return nil
+}
+
+/**
+ * @brief Generates an API key of a user and stores it in a repository.
+ *
+ * @param t The test.
+ * @param repo The repository to store the key in.
+ * @param id The ID of the key.
+ * @param userID The ID of the user owning the key.
+ * @param scopes The scopes granted by the key, separated by spaces.
+ * @return The key as presented by clients, and the stored key.
+ */
+func addAPIKey(t *testing.T, repo *stubAPIKeyRepository, id, userID uint, scopes string) (string, *models.APIKey) {
+	t.Helper()
+
+	generated, err := apikey.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+	stored := &models.APIKey{
+		Model:   gorm.Model{ID: id},
+		UserID:  userID,
+		Prefix:  generated.Prefix,
+		KeyHash: generated.Hash,
+		Scopes:  scopes,
+	}
+	repo.keys[stored.Prefix] = stored
+	return generated.Key, stored
+}
+
+/**
+ * @brief Checks which API keys are accepted, and that their principal acts as a buyer limited to the key's scopes.
+ */
+func TestRequireAuthOrAPIKey(t *testing.T) {
+	useEphemeralKeyRing(t)
+
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	admin := &models.User{Model: gorm.Model{ID: 1}, Email: "admin@example.com", Role: models.RoleAdmin}
+	suspended := &models.User{Model: gorm.Model{ID: 2}, Email: "mallory@example.com", Role: models.RoleBuyer, SuspendedAt: &past}
+	users := map[uint]*models.User{admin.ID: admin, suspended.ID: suspended}
+
+	keys := &stubAPIKeyRepository{keys: map[string]*models.APIKey{}}
+	valid, _ := addAPIKey(t, keys, 10, admin.ID, models.ScopeOrdersRead)
+	notExpired, notExpiredKey := addAPIKey(t, keys, 11, admin.ID, models.ScopeOrdersRead)
+	notExpiredKey.ExpiresAt = &future
+	expired, expiredKey := addAPIKey(t, keys, 12, admin.ID, models.ScopeOrdersRead)
+	expiredKey.ExpiresAt = &past
+	revoked, revokedKey := addAPIKey(t, keys, 13, admin.ID, models.ScopeOrdersRead)
+	revokedKey.RevokedAt = &past
+	ofSuspended, _ := addAPIKey(t, keys, 14, suspended.ID, models.ScopeOrdersRead)
+	ofDeleted, _ := addAPIKey(t, keys, 15, 99, models.ScopeOrdersRead)
+	offersOnly, _ := addAPIKey(t, keys, 16, admin.ID, models.ScopeOffersRead)
+	unknown, err := apikey.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate API key: %v", err)
+	}
+	prefix, _ := apikey.Prefix(valid)
+
+	token, err := GenerateJWT(admin.ID, 1, admin.Email, admin.Role, true, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	var principal *Principal
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		return c.SendStatus(errorStatus(err))
+	}})
+	app.Get("/",
+		RequireAuthOrAPIKey(&stubUserRepository{users: users}, &stubTokenRepository{}, &stubSessionRepository{}, keys),
+		RequireScope(models.ScopeOrdersRead),
+		func(c *fiber.Ctx) error {
+			principal = CurrentPrincipal(c)
+			return c.SendStatus(fiber.StatusOK)
+		},
+	)
+
+	cases := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"valid key", map[string]string{APIKeyHeader: valid}, http.StatusOK},
+		{"key expiring later", map[string]string{APIKeyHeader: notExpired}, http.StatusOK},
+		{"expired key", map[string]string{APIKeyHeader: expired}, http.StatusUnauthorized},
+		{"revoked key", map[string]string{APIKeyHeader: revoked}, http.StatusUnauthorized},
+		{"wrong secret", map[string]string{APIKeyHeader: prefix + "_wrong"}, http.StatusUnauthorized},
+		{"unknown key", map[string]string{APIKeyHeader: unknown.Key}, http.StatusUnauthorized},
+		{"malformed key", map[string]string{APIKeyHeader: "not-a-key"}, http.StatusUnauthorized},
+		{"key of a deleted user", map[string]string{APIKeyHeader: ofDeleted}, http.StatusUnauthorized},
+		{"key of a suspended user", map[string]string{APIKeyHeader: ofSuspended}, http.StatusForbidden},
+		{"key without the scope", map[string]string{APIKeyHeader: offersOnly}, http.StatusForbidden},
+		{"JWT", map[string]string{"Authorization": token.Token}, http.StatusOK},
+		{"no credential", nil, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		if got := statusOf(t, app, tc.headers); got != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	principal = nil
+	if got := statusOf(t, app, map[string]string{APIKeyHeader: valid}); got != http.StatusOK || principal == nil {
+		t.Fatalf("expected the valid key to be accepted, got status %d", got)
+	}
+	if principal.UserID != admin.ID || principal.APIKeyID != 10 || principal.Role != models.RoleBuyer || principal.IsAdmin() {
+		t.Fatalf("expected the key of the admin to act as a buyer, got %+v", principal)
+	}
+	if !principal.HasScope(models.ScopeOrdersRead) || principal.HasScope(models.ScopeOrdersWrite) {
+		t.Fatalf("expected the principal to be limited to the scopes of the key, got %v", principal.Scopes)
+	}
+}
@@ -1,124 +1,333 @@
 package middleware
 
 import (
-	"os"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strconv"
 	"time"
 
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 )
 
-var SecretKey = os.Getenv("SECRET_KEY")
-
 /**
  * @brief Middleware to protect routes with JWT authentication.
  *
  * This middleware checks for the presence of a valid JWT in the request's
- * Authorization header. If the JWT is missing, malformed, invalid, or expired,
- * the request is rejected with a 401 Unauthorized status.
+ * Authorization header, signed by one of the keys of the key ring. If the JWT is
+ * missing, malformed, invalid, or expired, the request is rejected with a 401
+ * Unauthorized status.
  *
  * @return A fiber.Handler that checks JWT authentication.
  */
 func Protected() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		tokenString := c.Get("Authorization")
-		if tokenString == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "error", "message": "Missing or malformed JWT"})
+		claims, err := parseJWT(c)
+		if err != nil {
+			return err
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(SecretKey), nil
-		})
+		c.Locals("user", claims)
+		return c.Next()
+	}
+}
+
+/**
+ * @brief Verifies the JWT in the Authorization header of a request.
+ *
+ * @param c The Fiber context.
+ * @return The claims of the JWT, or an error if it is missing, malformed, invalid or expired.
+ */
+func parseJWT(c *fiber.Ctx) (jwt.MapClaims, error) {
+	tokenString := c.Get("Authorization")
+	if tokenString == "" {
+		return nil, apperror.Unauthorized("missing_token", "missing or malformed JWT")
+	}
 
-		if err != nil || !token.Valid {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"status": "error", "message": "Invalid or expired JWT"})
+	if keyRing == nil {
+		return nil, apperror.Unauthorized("invalid_token", "invalid or expired JWT")
+	}
+
+	token, err := keyRing.Parse(tokenString)
+	if err != nil || !token.Valid {
+		return nil, apperror.Unauthorized("invalid_token", "invalid or expired JWT")
+	}
+
+	return token.Claims.(jwt.MapClaims), nil
+}
+
+/**
+ * @brief Middleware to restrict routes to users with one of the given roles.
+ *
+ * This middleware must run after RequireAuth. It reads the role of the
+ * authenticated Principal, taken from the JWT, and rejects the request with a
+ * 403 Forbidden status if it is not one of the allowed ones.
+ *
+ * @param roles The roles allowed to access the route.
+ * @return A fiber.Handler that checks the role of the user.
+ */
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			return apperror.Unauthorized("unauthorized", "authentication required")
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
+		for _, allowed := range roles {
+			if principal.Role == allowed {
+				return c.Next()
+			}
+		}
 
-		c.Locals("user", claims)
+		return apperror.Forbidden("insufficient_role", "your role is not allowed to access this resource")
+	}
+}
+
+/**
+ * @brief Middleware to restrict routes to users who confirmed their login with a second factor.
+ *
+ * This middleware must run after RequireAuth. It rejects the request with a 403
+ * Forbidden status if the JWT was not issued for a login confirmed with a TOTP
+ * or recovery code.
+ *
+ * @return A fiber.Handler that checks the login of the user used two factors.
+ */
+func RequireTwoFactor() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			return apperror.Unauthorized("unauthorized", "authentication required")
+		}
+
+		if !principal.TwoFactor {
+			return apperror.Forbidden("two_factor_required", "log in with two-factor authentication first, enrolling with POST /auth/totp/enroll if needed")
+		}
 		return c.Next()
 	}
 }
 
 /**
- * @brief Generates a JWT for the given email and role.
+ * @brief Middleware to require a login confirmed with a second factor from users with the given roles only.
  *
- * @param email The email address of the user.
- * @param role The role of the user (e.g., "Admin", "User").
- * @return A signed JWT string or an error if the token generation fails.
+ * This middleware must run after RequireAuth. It rejects the request with a 403
+ * Forbidden status if the user has one of the roles and the JWT was not issued
+ * for a login confirmed with a TOTP or recovery code. Users with other roles
+ * always pass.
+ *
+ * @param roles The roles that need a two-factor login, e.g. "admin".
+ * @return A fiber.Handler that checks the login of users with those roles used two factors.
  */
-func GenerateJWT(email, role string) (string, error) {
-	claims := jwt.MapClaims{
-		"email": email,
-		"role":  role,
-		"exp":   time.Now().Add(time.Hour * 72).Unix(),
+func RequireTwoFactorFor(roles ...string) fiber.Handler {
+	requireTwoFactor := RequireTwoFactor()
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			return apperror.Unauthorized("unauthorized", "authentication required")
+		}
+
+		for _, role := range roles {
+			if principal.Role == role {
+				return requireTwoFactor(c)
+			}
+		}
+		return c.Next()
 	}
+}
+
+/**
+ * @brief Middleware to restrict routes to users who have verified their email.
+ *
+ * This middleware must run after RequireAuth. It rejects the request with a 403
+ * Forbidden status if the email of the authenticated user is not verified.
+ *
+ * @return A fiber.Handler that checks the email of the user is verified.
+ */
+func RequireVerifiedEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		principal := CurrentPrincipal(c)
+		if principal == nil {
+			return apperror.Unauthorized("unauthorized", "authentication required")
+		}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(SecretKey))
+		if !principal.EmailVerified {
+			return apperror.Forbidden("email_not_verified", "verify your email address first")
+		}
+		return c.Next()
+	}
 }
 
 /**
- * @brief Checks if the user is authenticated based on the JWT.
+ * @brief Retrieves the JWT claims stored by the Protected middleware.
  *
  * @param c The Fiber context.
- * @param userRepo The user repository to query the user data.
- * @return True if the user is authenticated, false otherwise.
+ * @return The claims of the authenticated request and true, or nil and false if none are present.
  */
-func IsAuthenticated(c *fiber.Ctx, userRepo repository.UserRepository) bool {
-	tokenString := c.Get("Authorization")
-	if tokenString == "" {
-		return false
+func GetClaims(c *fiber.Ctx) (jwt.MapClaims, bool) {
+	claims, ok := c.Locals("user").(jwt.MapClaims)
+	return claims, ok
+}
+
+/**
+ * @struct IssuedToken
+ * @brief Structure representing a freshly signed JWT.
+ *
+ * This structure contains the signed token together with its JWT ID (jti) and
+ * expiration time, which are needed to revoke it later.
+ */
+type IssuedToken struct {
+	Token     string
+	ID        string
+	ExpiresAt time.Time
+}
+
+/**
+ * @brief Minimum time between two updates of the last-seen time of a session.
+ */
+const sessionTouchInterval = time.Minute
+
+/**
+ * @brief Generates a JWT for the given user.
+ *
+ * @param userID The ID of the user, stored in the subject claim.
+ * @param sessionID The ID of the session the token belongs to, stored in the sid claim.
+ * @param email The email address of the user.
+ * @param role The role of the user (e.g., "admin", "buyer").
+ * @param twoFactor Whether the login was confirmed with a second factor, stored in the mfa claim.
+ * @param ttl How long the token is valid for.
+ * @return The signed JWT with its ID and expiration time, or an error if the token generation fails.
+ */
+func GenerateJWT(userID, sessionID uint, email, role string, twoFactor bool, ttl time.Duration) (*IssuedToken, error) {
+	if keyRing == nil {
+		return nil, errors.New("no key ring configured for signing tokens")
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SecretKey), nil
-	})
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
 
-	if err != nil || !token.Valid {
-		return false
+	now := time.Now()
+	issued := &IssuedToken{
+		ID:        hex.EncodeToString(id),
+		ExpiresAt: now.Add(ttl),
 	}
 
-	user, err := userRepo.GetUserByToken(tokenString)
-	if err != nil || user == nil {
-		return false
+	claims := jwt.MapClaims{
+		"sub":   strconv.FormatUint(uint64(userID), 10),
+		"jti":   issued.ID,
+		"sid":   strconv.FormatUint(uint64(sessionID), 10),
+		"email": email,
+		"role":  role,
+		"mfa":   twoFactor,
+		"iat":   now.Unix(),
+		"exp":   issued.ExpiresAt.Unix(),
+	}
+
+	signed, err := keyRing.Sign(claims)
+	if err != nil {
+		return nil, err
 	}
 
-	return true
+	issued.Token = signed
+	return issued, nil
+}
+
+/**
+ * @brief Middleware to make sure the JWT has not been revoked and to identify its user.
+ *
+ * This middleware must run after Protected. It rejects the request with a 401
+ * Unauthorized status if the JWT was revoked by a logout, if it was issued before
+ * the user logged out of all sessions, or if the user no longer exists, and with
+ * a 403 Forbidden status if the user has been suspended. Otherwise
+ * it records the activity on the session of the token and attaches the
+ * authenticated Principal to the request context.
+ *
+ * @param userRepo The user repository to query the user data.
+ * @param tokenRepo The token repository to query the revoked tokens.
+ * @param sessionRepo The session repository to record the activity of the session.
+ * @return A fiber.Handler that authenticates the user.
+ */
+func RequireAuth(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := GetClaims(c)
+		if !ok {
+			return apperror.Unauthorized("missing_token", "missing or malformed JWT")
+		}
+
+		if err := authenticateJWT(c, claims, userRepo, tokenRepo, sessionRepo); err != nil {
+			return err
+		}
+		return c.Next()
+	}
 }
 
 /**
- * @brief Checks if the user is an admin based on the JWT.
+ * @brief Identifies the user of verified JWT claims and attaches the Principal to the request context.
  *
  * @param c The Fiber context.
+ * @param claims The claims of the verified JWT.
  * @param userRepo The user repository to query the user data.
- * @return True if the user is an admin, false otherwise.
+ * @param tokenRepo The token repository to query the revoked tokens.
+ * @param sessionRepo The session repository to record the activity of the session.
+ * @return An error if the JWT was revoked, or its user no longer exists or is suspended.
  */
-func IsAdmin(c *fiber.Ctx, userRepo repository.UserRepository) bool {
-	tokenString := c.Get("Authorization")
-	if tokenString == "" {
-		return false
+func authenticateJWT(c *fiber.Ctx, claims jwt.MapClaims, userRepo repository.UserRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository) error {
+	subject, _ := claims["sub"].(string)
+	userID, err := strconv.ParseUint(subject, 10, 64)
+	session, _ := claims["sid"].(string)
+	sessionID, sessionErr := strconv.ParseUint(session, 10, 64)
+	tokenID, _ := claims["jti"].(string)
+	issuedAt, _ := claims["iat"].(float64)
+	expiresAt, _ := claims["exp"].(float64)
+	if err != nil || sessionErr != nil || tokenID == "" {
+		return apperror.Unauthorized("invalid_token", "missing or malformed JWT")
 	}
 
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return []byte(SecretKey), nil
-	})
+	revoked, err := tokenRepo.IsAccessTokenRevoked(tokenID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return apperror.Unauthorized("token_revoked", "the JWT has been revoked")
+	}
 
-	if err != nil || !token.Valid {
-		return false
+	user, err := userRepo.GetUserByID(uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return apperror.Unauthorized("unauthorized", "the user of the JWT no longer exists")
+	}
+	if err != nil {
+		return err
+	}
+	if user.SuspendedAt != nil {
+		return apperror.Forbidden("account_suspended", "this account has been suspended")
 	}
 
-	claims := token.Claims.(jwt.MapClaims)
-	if claims["role"] != "Admin" {
-		return false
+	// Tokens carry whole seconds, so a token issued in the same second as the
+	// cut-off may predate it and is rejected too.
+	if user.TokensValidAfter != nil && int64(issuedAt) <= user.TokensValidAfter.Unix() {
+		return apperror.Unauthorized("token_revoked", "the JWT has been revoked")
 	}
 
-	user, err := userRepo.GetUserByToken(tokenString)
-	if err != nil || user == nil {
-		return false
+	if err := sessionRepo.TouchSession(uint(sessionID), user.ID, time.Now(), sessionTouchInterval); err != nil {
+		log.Printf("Failed to record activity of session %d: %v", sessionID, err)
 	}
 
-	return true
+	role, _ := claims["role"].(string)
+	twoFactor, _ := claims["mfa"].(bool)
+	c.Locals(principalKey, &Principal{
+		UserID:         user.ID,
+		SessionID:      uint(sessionID),
+		Email:          user.Email,
+		EmailVerified:  user.EmailVerifiedAt != nil,
+		Role:           role,
+		TwoFactor:      twoFactor,
+		TokenID:        tokenID,
+		TokenExpiresAt: time.Unix(int64(expiresAt), 0),
+	})
+	return nil
 }
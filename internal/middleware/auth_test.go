@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+type stubUserRepository struct {
+	repository.UserRepository
+	users map[uint]*models.User
+}
+
+func (r *stubUserRepository) GetUserByID(id uint) (*models.User, error) {
+	if user, ok := r.users[id]; ok {
+		return user, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+type stubTokenRepository struct {
+	repository.TokenRepository
+	revoked map[string]bool
+}
+
+func (r *stubTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	return r.revoked[jti], nil
+}
+
+type stubSessionRepository struct {
+	repository.SessionRepository
+}
+
+func (r *stubSessionRepository) TouchSession(id, userID uint, lastSeenAt time.Time, interval time.Duration) error {
+	return nil
+}
+
+/**
+ * @brief Installs a fresh key ring for the duration of a test.
+ *
+ * @param t The test.
+ */
+func useEphemeralKeyRing(t *testing.T) {
+	t.Helper()
+
+	ring, err := NewEphemeralKeyRing()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	previous := keyRing
+	SetKeyRing(ring)
+	t.Cleanup(func() { SetKeyRing(previous) })
+}
+
+/**
+ * @brief Builds an application with a single route behind Protected and RequireAuth, answering 200.
+ *
+ * @param users The users known to the user repository.
+ * @param revoked The IDs of the revoked access tokens.
+ * @return The application.
+ */
+func newAuthApp(users map[uint]*models.User, revoked map[string]bool) *fiber.App {
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		return c.SendStatus(errorStatus(err))
+	}})
+	app.Get("/", Protected(), RequireAuth(&stubUserRepository{users: users}, &stubTokenRepository{revoked: revoked}, &stubSessionRepository{}), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	return app
+}
+
+/**
+ * @brief Sends a request with the given headers and returns the status of the response.
+ *
+ * @param t The test.
+ * @param app The application.
+ * @param headers The headers of the request.
+ * @return The status of the response.
+ */
+func statusOf(t *testing.T, app *fiber.App, headers map[string]string) int {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+/**
+ * @brief Checks that tokens issued before or in the same second as the cut-off of a user are rejected.
+ */
+func TestRequireAuthRejectsTokensIssuedBeforeCutOff(t *testing.T) {
+	useEphemeralKeyRing(t)
+
+	user := &models.User{Model: gorm.Model{ID: 1}, Email: "frank@example.com", Role: models.RoleBuyer}
+	app := newAuthApp(map[uint]*models.User{user.ID: user}, nil)
+
+	token, err := GenerateJWT(user.ID, 1, user.Email, user.Role, false, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	issuedAt := token.ExpiresAt.Add(-time.Minute).Unix()
+
+	cases := []struct {
+		name       string
+		validAfter *time.Time
+		want       int
+	}{
+		{"no cut-off", nil, http.StatusOK},
+		{"cut-off in an earlier second", timePtr(time.Unix(issuedAt-1, 900000000)), http.StatusOK},
+		{"cut-off later in the same second", timePtr(time.Unix(issuedAt, 900000000)), http.StatusUnauthorized},
+		{"cut-off at the start of the same second", timePtr(time.Unix(issuedAt, 0)), http.StatusUnauthorized},
+		{"cut-off in a later second", timePtr(time.Unix(issuedAt+1, 0)), http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		user.TokensValidAfter = tc.validAfter
+		if got := statusOf(t, app, map[string]string{"Authorization": token.Token}); got != tc.want {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.want, got)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+/**
+ * @brief Maps an error returned by a middleware to the status the application answers with.
+ *
+ * @param err The error.
+ * @return The status of the domain error, or 500 for any other error.
+ */
+func errorStatus(err error) int {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) {
+		return appErr.Status()
+	}
+	return fiber.StatusInternalServerError
+}
@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+/**
+ * @brief Smallest RSA key size accepted for signing tokens.
+ */
+const minRSAKeyBits = 2048
+
+/**
+ * @struct signingKey
+ * @brief A key of the key ring, identified by its key ID (kid).
+ *
+ * Keys loaded from a public key file can only verify tokens, not sign them.
+ */
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer
+	public  crypto.PublicKey
+}
+
+/**
+ * @struct KeyRing
+ * @brief Set of keys used to sign and verify JWTs.
+ *
+ * Tokens are always signed with the active key, but are accepted as long as
+ * they were signed with any key of the ring. Keys can therefore be rotated by
+ * adding a new key, making it the active one, and removing the previous key
+ * once the tokens it signed have expired.
+ */
+type KeyRing struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+/**
+ * @brief Key ring used by Protected and GenerateJWT.
+ */
+var keyRing *KeyRing
+
+/**
+ * @brief Sets the key ring used to sign and verify JWTs.
+ *
+ * @param ring The key ring to use.
+ */
+func SetKeyRing(ring *KeyRing) {
+	keyRing = ring
+}
+
+/**
+ * @brief Returns the key ring used to sign and verify JWTs.
+ *
+ * @return The key ring, or nil if none has been set.
+ */
+func CurrentKeyRing() *KeyRing {
+	return keyRing
+}
+
+/**
+ * @brief Loads a key ring from a directory of PEM files.
+ *
+ * Every .pem file of the directory holds one key, whose key ID is the file name
+ * without the extension. Private keys may be PKCS #8 (RSA or Ed25519) or PKCS #1
+ * (RSA); public keys are PKIX and can only be used to verify tokens, which is
+ * enough for keys being retired.
+ *
+ * @param dir The directory holding the keys.
+ * @param activeID The ID of the key used to sign new tokens.
+ * @return The key ring and an error if a key cannot be loaded or the active key is missing.
+ */
+func LoadKeyRing(dir, activeID string) (*KeyRing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &KeyRing{keys: make(map[string]*signingKey)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		id := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		key, err := parseKey(id, data)
+		if err != nil {
+			return nil, fmt.Errorf("loading key %s: %w", path, err)
+		}
+		ring.keys[id] = key
+	}
+
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %q not found in %s", activeID, dir)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active key %q has no private key", activeID)
+	}
+	ring.active = active
+
+	return ring, nil
+}
+
+/**
+ * @brief Creates a key ring holding a single Ed25519 key generated on the fly.
+ *
+ * Tokens signed with it stop being valid when the process exits, so it is only
+ * meant for local development.
+ *
+ * @return The key ring and an error if the key cannot be generated.
+ */
+func NewEphemeralKeyRing() (*KeyRing, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{
+		id:      "ephemeral-" + hex.EncodeToString(id),
+		method:  jwt.SigningMethodEdDSA,
+		private: private,
+		public:  public,
+	}
+	return &KeyRing{active: key, keys: map[string]*signingKey{key.id: key}}, nil
+}
+
+/**
+ * @brief Parses a PEM encoded key.
+ *
+ * @param id The key ID.
+ * @param data The PEM data.
+ * @return The key and an error if the data is not a supported key.
+ */
+func parseKey(id string, data []byte) (*signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &signingKey{id: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+	}
+
+	return key, nil
+}
+
+/**
+ * @brief Signs the given claims with the active key.
+ *
+ * The ID of the key is written to the kid header so that the token can be
+ * verified after the active key changes.
+ *
+ * @param claims The claims of the token.
+ * @return The signed token and an error if the signing fails.
+ */
+func (r *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(r.active.method, claims)
+	token.Header["kid"] = r.active.id
+	return token.SignedString(r.active.private)
+}
+
+/**
+ * @brief Parses and verifies a token signed by one of the keys of the ring.
+ *
+ * The key is selected by the kid header, and the token is rejected unless it
+ * was signed with the algorithm of that key, so a token cannot pick its own
+ * algorithm (e.g. "none" or HS256 with a public key as secret).
+ *
+ * @param tokenString The token to verify.
+ * @return The parsed token and an error if it is malformed, expired or not signed by the ring.
+ */
+func (r *KeyRing) Parse(tokenString string) (*jwt.Token, error) {
+	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		id, _ := token.Header["kid"].(string)
+		key, ok := r.keys[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", id)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}))
+}
+
+/**
+ * @brief Returns the public keys of the ring as a JSON Web Key Set (RFC 7517).
+ *
+ * Other services can use it to verify the tokens issued by this API.
+ *
+ * @return The key set, sorted by key ID.
+ */
+func (r *KeyRing) JWKS() models.JSONWebKeySet {
+	set := models.JSONWebKeySet{Keys: make([]models.JSONWebKey, 0, len(r.keys))}
+	for _, key := range r.keys {
+		jwk := models.JSONWebKey{
+			KeyID:     key.id,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+		}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].KeyID < set.Keys[j].KeyID })
+	return set
+}
@@ -0,0 +1,262 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+/**
+ * @brief Writes a PEM file to a directory.
+ *
+ * @param t The test.
+ * @param dir The directory.
+ * @param name The file name, whose base is the key ID.
+ * @param blockType The type of the PEM block.
+ * @param der The DER encoded key.
+ */
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+/**
+ * @brief Generates an RSA key of the given size and writes it as a PKCS #1 private key.
+ *
+ * @param t The test.
+ * @param dir The directory.
+ * @param id The key ID.
+ * @param bits The size of the key.
+ * @return The key.
+ */
+func writeRSAKey(t *testing.T, dir, id string, bits int) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	writePEM(t, dir, id+".pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return key
+}
+
+/**
+ * @brief Generates an Ed25519 key and writes it as a PKCS #8 private key.
+ *
+ * @param t The test.
+ * @param dir The directory.
+ * @param id The key ID.
+ * @return The key.
+ */
+func writeEd25519Key(t *testing.T, dir, id string) ed25519.PrivateKey {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode Ed25519 key: %v", err)
+	}
+	writePEM(t, dir, id+".pem", "PRIVATE KEY", der)
+	return key
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "exp": time.Now().Add(time.Minute).Unix()}
+}
+
+/**
+ * @brief Checks that tokens signed with an RSA or Ed25519 active key are verified and carry its kid.
+ */
+func TestKeyRingRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "rsa", 2048)
+	writeEd25519Key(t, dir, "ed")
+
+	for _, tc := range []struct {
+		active string
+		alg    string
+	}{
+		{"rsa", "RS256"},
+		{"ed", "EdDSA"},
+	} {
+		ring, err := LoadKeyRing(dir, tc.active)
+		if err != nil {
+			t.Fatalf("failed to load key ring with active key %s: %v", tc.active, err)
+		}
+
+		signed, err := ring.Sign(testClaims())
+		if err != nil {
+			t.Fatalf("failed to sign with %s: %v", tc.active, err)
+		}
+		token, err := ring.Parse(signed)
+		if err != nil || !token.Valid {
+			t.Fatalf("expected the token signed with %s to verify, got %v", tc.active, err)
+		}
+		if token.Header["kid"] != tc.active || token.Method.Alg() != tc.alg {
+			t.Fatalf("expected kid %s and alg %s, got %v and %s", tc.active, tc.alg, token.Header["kid"], token.Method.Alg())
+		}
+	}
+}
+
+/**
+ * @brief Checks that a token signed with a retired key, of which only the public key is kept, still verifies.
+ */
+func TestKeyRingVerifiesRetiredKey(t *testing.T) {
+	dir := t.TempDir()
+	retired := writeRSAKey(t, dir, "2023", 2048)
+	writeEd25519Key(t, dir, "2024")
+
+	oldRing, err := LoadKeyRing(dir, "2023")
+	if err != nil {
+		t.Fatalf("failed to load key ring: %v", err)
+	}
+	signed, err := oldRing.Sign(testClaims())
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+
+	public, err := x509.MarshalPKIXPublicKey(&retired.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to encode public key: %v", err)
+	}
+	writePEM(t, dir, "2023.pem", "PUBLIC KEY", public)
+
+	ring, err := LoadKeyRing(dir, "2024")
+	if err != nil {
+		t.Fatalf("failed to load rotated key ring: %v", err)
+	}
+	if token, err := ring.Parse(signed); err != nil || !token.Valid {
+		t.Fatalf("expected the token of the retired key to verify, got %v", err)
+	}
+
+	if _, err := LoadKeyRing(dir, "2023"); err == nil {
+		t.Fatalf("expected a public key to be refused as active key")
+	}
+
+	found := false
+	for _, jwk := range ring.JWKS().Keys {
+		if jwk.KeyID == "2023" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected the retired key to stay published")
+	}
+}
+
+/**
+ * @brief Checks that tokens with an unknown kid, no signature, an HMAC signature or another algorithm than their key are rejected.
+ */
+func TestKeyRingRejectsForgedTokens(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := writeRSAKey(t, dir, "rsa", 2048)
+	edKey := writeEd25519Key(t, dir, "ed")
+	ring, err := LoadKeyRing(dir, "rsa")
+	if err != nil {
+		t.Fatalf("failed to load key ring: %v", err)
+	}
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, testClaims())
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign with %s: %v", method.Alg(), err)
+		}
+		return signed
+	}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	publicDER := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+
+	cases := map[string]string{
+		"unknown kid":            sign(jwt.SigningMethodRS256, "missing", other),
+		"key of another kid":     sign(jwt.SigningMethodRS256, "rsa", other),
+		"alg none":               sign(jwt.SigningMethodNone, "rsa", jwt.UnsafeAllowNoneSignatureType),
+		"HS256 with public key":  sign(jwt.SigningMethodHS256, "rsa", publicDER),
+		"EdDSA under an RSA kid": sign(jwt.SigningMethodEdDSA, "rsa", edKey),
+		"RS512 under an RSA kid": sign(jwt.SigningMethodRS512, "rsa", rsaKey),
+	}
+	for name, signed := range cases {
+		if token, err := ring.Parse(signed); err == nil && token.Valid {
+			t.Errorf("%s: expected the token to be rejected", name)
+		}
+	}
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "1", "exp": time.Now().Add(-time.Minute).Unix()})
+	expired.Header["kid"] = "rsa"
+	signed, err := expired.SignedString(rsaKey)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	if _, err := ring.Parse(signed); err == nil {
+		t.Errorf("expected an expired token to be rejected")
+	}
+}
+
+/**
+ * @brief Checks that RSA keys shorter than the minimum size are refused.
+ */
+func TestLoadKeyRingRejectsShortRSAKeys(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "short", 1024)
+
+	_, err := LoadKeyRing(dir, "short")
+	if err == nil || !strings.Contains(err.Error(), "at least 2048 bits") {
+		t.Fatalf("expected a 1024-bit key to be refused, got %v", err)
+	}
+}
+
+/**
+ * @brief Checks that the JWKS publishes the public parts of RSA and Ed25519 keys, sorted by key ID.
+ */
+func TestKeyRingJWKS(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey := writeRSAKey(t, dir, "b-rsa", 2048)
+	edKey := writeEd25519Key(t, dir, "a-ed")
+	ring, err := LoadKeyRing(dir, "b-rsa")
+	if err != nil {
+		t.Fatalf("failed to load key ring: %v", err)
+	}
+
+	keys := ring.JWKS().Keys
+	if len(keys) != 2 || keys[0].KeyID != "a-ed" || keys[1].KeyID != "b-rsa" {
+		t.Fatalf("expected the keys sorted by ID, got %+v", keys)
+	}
+
+	ed := keys[0]
+	if ed.KeyType != "OKP" || ed.Curve != "Ed25519" || ed.Algorithm != "EdDSA" || ed.Use != "sig" {
+		t.Fatalf("unexpected Ed25519 key %+v", ed)
+	}
+	if ed.X != base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)) {
+		t.Fatalf("unexpected Ed25519 public key %s", ed.X)
+	}
+
+	rsaJWK := keys[1]
+	if rsaJWK.KeyType != "RSA" || rsaJWK.Algorithm != "RS256" || rsaJWK.Exponent != "AQAB" {
+		t.Fatalf("unexpected RSA key %+v", rsaJWK)
+	}
+	if rsaJWK.Modulus != base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()) {
+		t.Fatalf("unexpected RSA modulus")
+	}
+}
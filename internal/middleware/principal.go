@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+/**
+ * @brief Key under which RequireAuth and RequireAuthOrAPIKey store the Principal in the request context.
+ */
+const principalKey = "principal"
+
+/**
+ * @struct Principal
+ * @brief Structure representing the authenticated user of a request.
+ *
+ * This structure is attached to the request context by RequireAuth, so that
+ * handlers can identify the user without touching the JWT or the database. It
+ * also carries the session, whether its login was confirmed with a second
+ * factor, and the ID and expiration of the access token used for the request
+ * or, for requests made with an API key, the ID and scopes of the key.
+ */
+type Principal struct {
+	UserID         uint
+	SessionID      uint
+	Email          string
+	EmailVerified  bool
+	Role           string
+	TwoFactor      bool
+	TokenID        string
+	TokenExpiresAt time.Time
+	APIKeyID       uint
+	Scopes         []string
+}
+
+/**
+ * @brief Checks whether the principal may act with the rights of an admin, e.g. on the orders of other users.
+ *
+ * Admins only get those rights once they confirmed their login with a second
+ * factor, and never through an API key.
+ *
+ * @return True if the principal is an admin with a two-factor login, false otherwise.
+ */
+func (p *Principal) IsAdmin() bool {
+	return p.Role == models.RoleAdmin && p.TwoFactor && p.APIKeyID == 0
+}
+
+/**
+ * @brief Checks whether the principal may perform the actions covered by a scope.
+ *
+ * Users authenticated with a JWT may do everything their role allows, while
+ * API keys only grant the scopes they were created with.
+ *
+ * @param scope The scope to check, e.g. "orders:write".
+ * @return True if the principal has the scope, false otherwise.
+ */
+func (p *Principal) HasScope(scope string) bool {
+	if p.APIKeyID == 0 {
+		return true
+	}
+	for _, granted := range p.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @brief Retrieves the authenticated user of the request.
+ *
+ * @param c The Fiber context.
+ * @return The Principal attached by RequireAuth, or nil if the request is not authenticated.
+ */
+func CurrentPrincipal(c *fiber.Ctx) *Principal {
+	principal, _ := c.Locals(principalKey).(*Principal)
+	return principal
+}
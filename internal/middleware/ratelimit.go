@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+/**
+ * @brief Headers describing the rate limit closest to being exhausted, as in the IETF RateLimit header fields draft.
+ */
+const (
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerRateLimitPolicy    = "RateLimit-Policy"
+)
+
+/**
+ * @brief Middleware to limit the rate of requests with a token bucket per key.
+ *
+ * Every request takes a token from the bucket of its key, and is rejected with a
+ * 429 Too Many Requests status and a Retry-After header when the bucket is
+ * empty. The RateLimit-* headers describe the limit with the fewest remaining
+ * requests among those applied to the request. If the store cannot be reached
+ * the request is let through, so that an outage of the store does not take the
+ * API down with it.
+ *
+ * @param store The store of the token buckets.
+ * @param name The name of the limit, which keeps its buckets apart from those of other limits.
+ * @param limit The limit to apply. A disabled limit lets every request through.
+ * @param key Returns the key of the bucket of a request, e.g. ClientIPKey.
+ * @return A fiber.Handler that limits the rate of requests.
+ */
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit, key func(c *fiber.Ctx) string) fiber.Handler {
+	if !limit.Enabled() {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	policy := limit.Policy()
+	return func(c *fiber.Ctx) error {
+		result, err := store.Take(name+":"+key(c), limit)
+		if err != nil {
+			log.Printf("Failed to apply rate limit %s: %v", name, err)
+			return c.Next()
+		}
+
+		remaining, err := strconv.Atoi(string(c.Response().Header.Peek(headerRateLimitRemaining)))
+		if err != nil || result.Remaining <= remaining {
+			c.Set(headerRateLimitLimit, strconv.Itoa(limit.Requests))
+			c.Set(headerRateLimitRemaining, strconv.Itoa(result.Remaining))
+			c.Set(headerRateLimitReset, strconv.FormatInt(ceilSeconds(result.Reset), 10))
+			c.Set(headerRateLimitPolicy, policy)
+		}
+
+		if !result.Allowed {
+			return apperror.TooManyRequests("rate_limited", "too many requests, try again later", result.RetryAfter)
+		}
+		return c.Next()
+	}
+}
+
+/**
+ * @brief Returns the key of the rate limits applied per client IP address.
+ *
+ * @param c The Fiber context.
+ * @return The IP address of the client, as resolved from the trusted proxy header if configured.
+ */
+func ClientIPKey(c *fiber.Ctx) string {
+	return "ip:" + c.IP()
+}
+
+/**
+ * @brief Returns the key of the rate limits applied per user.
+ *
+ * Requests that have not been authenticated by RequireAuth fall back to the IP address of the client.
+ *
+ * @param c The Fiber context.
+ * @return The ID of the authenticated user, or the IP address of the client.
+ */
+func UserKey(c *fiber.Ctx) string {
+	principal := CurrentPrincipal(c)
+	if principal == nil {
+		return ClientIPKey(c)
+	}
+	return "user:" + strconv.FormatUint(uint64(principal.UserID), 10)
+}
+
+/**
+ * @brief Returns the key of the rate limits applied per email given in the request body.
+ *
+ * The email is compared case-insensitively, like the emails of accounts.
+ * Requests without an email fall back to the IP address of the client.
+ *
+ * @param c The Fiber context.
+ * @return The email of the request body, or the IP address of the client.
+ */
+func BodyEmailKey(c *fiber.Ctx) string {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(c.Body(), &body); err != nil || strings.TrimSpace(body.Email) == "" {
+		return ClientIPKey(c)
+	}
+	return "email:" + strings.ToLower(strings.TrimSpace(body.Email))
+}
+
+/**
+ * @brief Rounds a duration up to whole seconds.
+ *
+ * @param d The duration.
+ * @return The number of seconds.
+ */
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
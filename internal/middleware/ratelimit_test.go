@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/ratelimit"
+	"github.com/gofiber/fiber/v2"
+)
+
+/**
+ * @struct stubRateLimitStore
+ * @brief Store answering every bucket with a fixed result, and recording the keys it was asked for.
+ */
+type stubRateLimitStore struct {
+	results map[string]ratelimit.Result
+	err     error
+	keys    []string
+}
+
+func (s *stubRateLimitStore) Take(key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	s.keys = append(s.keys, key)
+	name, _, _ := strings.Cut(key, ":")
+	return s.results[name], s.err
+}
+
+/**
+ * @brief Checks that the RateLimit-* headers describe the limit with the fewest remaining requests.
+ */
+func TestRateLimitHeaders(t *testing.T) {
+	store := &stubRateLimitStore{results: map[string]ratelimit.Result{
+		"global": {Allowed: true, Remaining: 50, Reset: 1500 * time.Millisecond},
+		"login":  {Allowed: true, Remaining: 2, Reset: 90 * time.Second},
+	}}
+	app := fiber.New()
+	app.Get("/",
+		RateLimit(store, "global", ratelimit.Limit{Requests: 100, Period: time.Minute}, ClientIPKey),
+		RateLimit(store, "login", ratelimit.Limit{Requests: 5, Period: 5 * time.Minute}, ClientIPKey),
+		RateLimit(store, "global", ratelimit.Limit{Requests: 100, Period: time.Minute}, ClientIPKey),
+		func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusOK) },
+	)
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+	want := map[string]string{
+		"RateLimit-Limit":     "5",
+		"RateLimit-Remaining": "2",
+		"RateLimit-Reset":     "90",
+		"RateLimit-Policy":    "5;w=300",
+	}
+	for name, value := range want {
+		if got := resp.Header.Get(name); got != value {
+			t.Errorf("expected %s %q, got %q", name, value, got)
+		}
+	}
+	if store.keys[0] != "global:ip:0.0.0.0" || store.keys[1] != "login:ip:0.0.0.0" {
+		t.Errorf("expected the buckets to be keyed by limit and IP address, got %v", store.keys)
+	}
+}
+
+/**
+ * @brief Checks that an empty bucket rejects the request with the delay until its next token.
+ */
+func TestRateLimitRejects(t *testing.T) {
+	store := &stubRateLimitStore{results: map[string]ratelimit.Result{
+		"login": {Remaining: 0, Reset: time.Minute, RetryAfter: 1200 * time.Millisecond},
+	}}
+
+	var rejected error
+	app := fiber.New(fiber.Config{ErrorHandler: func(c *fiber.Ctx, err error) error {
+		rejected = err
+		return c.SendStatus(errorStatus(err))
+	}})
+	app.Get("/", RateLimit(store, "login", ratelimit.Limit{Requests: 5, Period: 5 * time.Minute}, ClientIPKey), func(c *fiber.Ctx) error {
+		t.Fatalf("expected the request to be rejected")
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var appErr *apperror.Error
+	if resp.StatusCode != http.StatusTooManyRequests || !errors.As(rejected, &appErr) {
+		t.Fatalf("expected status 429, got %d and %v", resp.StatusCode, rejected)
+	}
+	if appErr.Code != "rate_limited" || appErr.RetryAfter != 1200*time.Millisecond {
+		t.Fatalf("expected a rate_limited error retrying after 1.2s, got %q after %v", appErr.Code, appErr.RetryAfter)
+	}
+	if got := resp.Header.Get("RateLimit-Remaining"); got != "0" {
+		t.Fatalf("expected no remaining requests, got %q", got)
+	}
+}
+
+/**
+ * @brief Checks that requests pass when the limit is disabled or the store cannot be reached.
+ */
+func TestRateLimitLetsThrough(t *testing.T) {
+	cases := map[string]struct {
+		store ratelimit.Store
+		limit ratelimit.Limit
+	}{
+		"disabled limit": {&stubRateLimitStore{}, ratelimit.Limit{}},
+		"store failure":  {&stubRateLimitStore{err: errors.New("connection refused")}, ratelimit.Limit{Requests: 1, Period: time.Minute}},
+	}
+	for name, tc := range cases {
+		app := fiber.New()
+		app.Get("/", RateLimit(tc.store, "global", tc.limit, ClientIPKey), func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), -1)
+		if err != nil {
+			t.Fatalf("%s: request failed: %v", name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("RateLimit-Limit") != "" {
+			t.Errorf("%s: expected the request to pass without headers, got %d", name, resp.StatusCode)
+		}
+	}
+}
+
+/**
+ * @brief Checks that the email of the request body keys the bucket case-insensitively, falling back to the IP address.
+ */
+func TestBodyEmailKey(t *testing.T) {
+	cases := []struct {
+		body string
+		want string
+	}{
+		{`{"email": " Dave@Example.com "}`, "email:dave@example.com"},
+		{`{"email": ""}`, "ip:0.0.0.0"},
+		{`{}`, "ip:0.0.0.0"},
+		{`not json`, "ip:0.0.0.0"},
+	}
+	for _, tc := range cases {
+		var got string
+		app := fiber.New()
+		app.Post("/", func(c *fiber.Ctx) error {
+			got = BodyEmailKey(c)
+			return nil
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if got != tc.want {
+			t.Errorf("%s: expected key %q, got %q", tc.body, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief Scopes that can be granted to an API key.
+ */
+const (
+	ScopeOffersRead  = "offers:read"
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+)
+
+/**
+ * @struct APIKey
+ * @brief Structure representing a key that lets scripts use the API on behalf of a user.
+ *
+ * Only the public prefix of the key, which identifies it, and the hash of the
+ * full key are stored. A key can only be used for the routes its scopes grant,
+ * stored separated by spaces, until it expires or is revoked.
+ */
+type APIKey struct {
+	gorm.Model
+	UserID     uint `gorm:"index"`
+	Name       string
+	Prefix     string `gorm:"uniqueIndex"`
+	KeyHash    string
+	Scopes     string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+/**
+ * @brief Lists the scopes granted to the key.
+ *
+ * @return The scopes of the key.
+ */
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+/**
+ * @struct CreateAPIKeyRequest
+ * @brief Structure representing the request data for creating an API key.
+ *
+ * This structure contains a name to recognize the key by, the scopes it grants
+ * and, optionally, after how many days it expires.
+ */
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,oneof=offers:read orders:read orders:write"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=365"`
+}
+
+/**
+ * @struct APIKeyResponse
+ * @brief Structure representing an API key returned by the API.
+ *
+ * This structure contains the ID, name, prefix and scopes of the key, when it was
+ * created, when it expires and when it was last used. The key itself is never
+ * part of it.
+ */
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+/**
+ * @struct CreatedAPIKeyResponse
+ * @brief Structure representing the response data after creating an API key.
+ *
+ * This structure contains the response code, the key, which is shown only this
+ * once, and its details.
+ */
+type CreatedAPIKeyResponse struct {
+	Code   string         `json:"code"`
+	Key    string         `json:"key"`
+	APIKey APIKeyResponse `json:"api_key"`
+}
+
+/**
+ * @struct APIKeysResponse
+ * @brief Structure representing the response data for listing API keys.
+ *
+ * This structure contains the response code and the keys of the user that have not been revoked.
+ */
+type APIKeysResponse struct {
+	Code    string           `json:"code"`
+	APIKeys []APIKeyResponse `json:"api_keys"`
+}
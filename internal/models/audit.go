@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+/**
+ * @brief Actions recorded in the audit log.
+ */
+const (
+	AuditAccountLocked       = "account_locked"
+	AuditAccountUnlocked     = "account_unlocked"
+	AuditIPLocked            = "ip_locked"
+	AuditAccountSuspended    = "account_suspended"
+	AuditAccountReactivated  = "account_reactivated"
+	AuditUserUpdated         = "user_updated"
+	AuditRoleChanged         = "role_changed"
+	AuditPasswordResetForced = "password_reset_forced"
+	AuditPasswordChanged     = "password_changed"
+	AuditAccountDeleted      = "account_deleted"
+	AuditAccountRestored     = "account_restored"
+	AuditAPIKeyCreated       = "api_key_created"
+	AuditAPIKeyRevoked       = "api_key_revoked"
+	AuditIdentityLinked      = "identity_linked"
+	AuditTOTPEnabled         = "totp_enabled"
+	AuditTOTPDisabled        = "totp_disabled"
+	AuditTOTPReset           = "totp_reset"
+	AuditRecoveryCodesIssued = "recovery_codes_issued"
+	AuditRecoveryCodeUsed    = "recovery_code_used"
+)
+
+/**
+ * @struct AuditEntry
+ * @brief Structure representing a security relevant event.
+ *
+ * This structure records what happened, the user it happened to and the user
+ * who caused it, if any, the IP address involved and free-form details. Entries
+ * are append-only, so they are never updated or soft deleted.
+ */
+type AuditEntry struct {
+	ID        uint      `json:"id" gorm:"primarykey"`
+	Action    string    `json:"action" gorm:"index"`
+	UserID    *uint     `json:"user_id,omitempty" gorm:"index"`
+	ActorID   *uint     `json:"actor_id,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+/**
+ * @struct AuditEntriesResponse
+ * @brief Response structure for querying the audit log.
+ *
+ * This structure contains the response code and the requested entries, newest first.
+ */
+type AuditEntriesResponse struct {
+	Code    string       `json:"code"`
+	Entries []AuditEntry `json:"entries"`
+}
@@ -1,14 +1,26 @@
 package models
 
 /**
- * @struct Response_d
- * @brief General response structure for API responses.
+ * @struct DashboardResponse
+ * @brief Response structure for the admin dashboard endpoint.
  *
- * This structure represents a generic response with a code and a message.
+ * This structure contains the response code and the dashboard data.
  */
-type Response_d struct {
-	Code    string      `json:"code"`
-	Message interface{} `json:"message"`
+type DashboardResponse struct {
+	Code    string           `json:"code"`
+	Message DashboardMessage `json:"message"`
+}
+
+/**
+ * @struct DashboardMessage
+ * @brief Structure representing the data shown in the admin dashboard.
+ *
+ * This structure contains the order and revenue metrics, every offer and every order.
+ */
+type DashboardMessage struct {
+	Dashboard AdminDashboardResponse `json:"dashboard"`
+	Offers    []OfferResponse        `json:"offers"`
+	Orders    []OrderSummary         `json:"orders"`
 }
 
 /**
@@ -26,6 +38,7 @@ type AdminDashboardResponse struct {
 	PreparingOrders  int64 `json:"preparing_orders"`
 	ProcessingOrders int64 `json:"processing_orders"`
 	ShippedOrders    int64 `json:"shipped_orders"`
+	CancelledOrders  int64 `json:"cancelled_orders"`
 }
 
 /**
@@ -35,7 +48,8 @@ type AdminDashboardResponse struct {
  * This structure represents the data required to update the status of an order.
  */
 type OrderStatusUpdateRequest struct {
-	Status string `json:"status"`
+	Status string `json:"status" validate:"required,oneof=preparing processing shipped delivered cancelled"`
+	Reason string `json:"reason,omitempty" validate:"max=500"`
 }
 
 /**
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+/**
+ * @struct ExternalIdentity
+ * @brief Structure representing an account of a user at an OpenID Connect provider.
+ *
+ * The issuer and subject identify the account at the provider for good, even if
+ * the user changes their email there. Logging in with the provider logs in the
+ * linked user. Identities are removed together with the personal data of the
+ * user, so they are never soft deleted.
+ */
+type ExternalIdentity struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	Issuer    string `gorm:"uniqueIndex:idx_external_identities_issuer_subject"`
+	Subject   string `gorm:"uniqueIndex:idx_external_identities_issuer_subject"`
+	Email     string
+	CreatedAt time.Time
+}
+
+/**
+ * @struct ProviderLogin
+ * @brief Structure representing a login with an OpenID Connect provider that has not completed yet.
+ *
+ * Only the hash of the state handed to the provider is stored. The nonce and the
+ * PKCE verifier are needed to complete the login, which can only be done once
+ * and before the state expires.
+ */
+type ProviderLogin struct {
+	ID           uint   `gorm:"primarykey"`
+	StateHash    string `gorm:"uniqueIndex"`
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time `gorm:"index"`
+	CreatedAt    time.Time
+}
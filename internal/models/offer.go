@@ -17,6 +17,20 @@ type Offer struct {
 	Category string `json:"category"`
 }
 
+/**
+ * @struct OfferResponse
+ * @brief Structure representing an offer in API responses.
+ *
+ * This structure contains the ID, name, available quantity, price and category of an offer.
+ */
+type OfferResponse struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+	Category string `json:"category"`
+}
+
 /**
  * @struct OffersRequest
  * @brief Request structure for fetching offers.
@@ -34,6 +48,6 @@ type OffersRequest struct {
  * This structure represents the data returned when fetching offers, including a code and a list of offers.
  */
 type OffersResponse struct {
-	Code    string  `json:"code"`
-	Message []Offer `json:"message"`
+	Code    string          `json:"code"`
+	Message []OfferResponse `json:"message"`
 }
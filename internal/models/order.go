@@ -1,19 +1,40 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief Possible values of the status of an order.
+ */
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusPreparing  = "preparing"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+)
 
 /**
  * @struct Order
  * @brief Structure representing an order.
  *
- * This structure represents an order in the system, including its status, total amount,
- * and associated order items.
+ * This structure represents an order in the system, including the buyer who placed it,
+ * its status, total amount, and associated order items. Cancelled orders also record
+ * who cancelled them, when and why.
  */
 type Order struct {
 	gorm.Model
-	Status     string
-	Total      int
-	OrderItems []OrderItem `gorm:"foreignKey:OrderID"`
+	UserID       uint
+	Status       string `gorm:"index"`
+	Total        int
+	OrderItems   []OrderItem `gorm:"foreignKey:OrderID"`
+	CancelledBy  *uint
+	CancelledAt  *time.Time
+	CancelReason string
 }
 
 /**
@@ -21,16 +42,108 @@ type Order struct {
  * @brief Structure representing an item in an order.
  *
  * This structure represents an item within an order, including its product ID, quantity,
- * and price.
+ * and the unit price at the time of purchase.
  */
 type OrderItem struct {
 	gorm.Model
 	OrderID   uint
 	ProductID uint
+	Offer     Offer `json:"-" gorm:"foreignKey:ProductID"`
 	Quantity  int
 	Price     int
 }
 
+/**
+ * @struct OrderStatusHistory
+ * @brief Structure representing a change in the status of an order.
+ *
+ * This structure records every status an order goes through, including the
+ * previous and new status, the user who made the change and when it happened.
+ * Entries are append-only, so they are never updated or soft deleted.
+ */
+type OrderStatusHistory struct {
+	ID         uint      `json:"id" gorm:"primarykey"`
+	OrderID    uint      `json:"order_id" gorm:"index"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  uint      `json:"changed_by"`
+	Reason     string    `json:"reason,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+/**
+ * @struct OrderStatusHistoryResponse
+ * @brief Response structure for querying the status history of an order.
+ *
+ * This structure represents the data returned when querying the status history of an order,
+ * including a response code and the list of status changes, oldest first.
+ */
+type OrderStatusHistoryResponse struct {
+	Code    string               `json:"code"`
+	History []OrderStatusHistory `json:"history"`
+}
+
+/**
+ * @struct OrderFilter
+ * @brief Structure representing the filters applied when listing orders.
+ *
+ * This structure contains the optional status and creation date range used to
+ * filter orders, together with the requested page and page size.
+ */
+type OrderFilter struct {
+	Status string
+	From   *time.Time
+	To     *time.Time
+	Page   int
+	Limit  int
+}
+
+/**
+ * @struct OrderItemResponse
+ * @brief Structure representing an item of an order in API responses.
+ *
+ * This structure contains the product bought, its name, the quantity, the unit
+ * price at the time of purchase and the resulting line total.
+ */
+type OrderItemResponse struct {
+	ProductID uint   `json:"product_id"`
+	Name      string `json:"name"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int    `json:"unit_price"`
+	LineTotal int    `json:"line_total"`
+}
+
+/**
+ * @struct OrderSummary
+ * @brief Structure representing an order in the order history of a buyer.
+ *
+ * This structure contains the ID, status, total and creation date of an order, together with its items.
+ * The buyer is only reported in admin listings, which mix the orders of every buyer.
+ */
+type OrderSummary struct {
+	ID        uint                `json:"id"`
+	BuyerID   uint                `json:"buyer_id,omitempty"`
+	Status    string              `json:"status"`
+	Total     int                 `json:"total"`
+	CreatedAt time.Time           `json:"created_at"`
+	Items     []OrderItemResponse `json:"items"`
+}
+
+/**
+ * @struct OrderListResponse
+ * @brief Response structure for listing the orders of a buyer.
+ *
+ * This structure represents the data returned when listing orders, including a response code,
+ * the requested page of orders and the pagination details.
+ */
+type OrderListResponse struct {
+	Code   string         `json:"code"`
+	Orders []OrderSummary `json:"orders"`
+	Page   int            `json:"page"`
+	Limit  int            `json:"limit"`
+	Total  int64          `json:"total"`
+}
+
 /**
  * @struct CheckoutRequest
  * @brief Request structure for checking out an order.
@@ -39,7 +152,7 @@ type OrderItem struct {
  * a list of order items.
  */
 type CheckoutRequest struct {
-	OrderItems []OrderItemRequest `json:"orderItems"`
+	OrderItems []OrderItemRequest `json:"orderItems" validate:"required,min=1,max=50,dive"`
 }
 
 /**
@@ -49,8 +162,8 @@ type CheckoutRequest struct {
  * This structure represents an item in a checkout request, including its product ID and quantity.
  */
 type OrderItemRequest struct {
-	ProductID uint `json:"productID"`
-	Quantity  int  `json:"quantity"`
+	ProductID uint `json:"productID" validate:"required"`
+	Quantity  int  `json:"quantity" validate:"gt=0"`
 }
 
 /**
@@ -79,13 +192,43 @@ type CheckoutMessage struct {
 }
 
 /**
- * @struct OrderStatusResponse
- * @brief Response structure for querying the status of an order.
+ * @struct CancelOrderRequest
+ * @brief Request structure for cancelling an order.
  *
- * This structure represents the data returned when querying the status of an order,
- * including a response code and the status.
+ * This structure represents the data required to cancel an order, including an optional reason.
  */
-type OrderStatusResponse struct {
-	Code   string `json:"code"`
-	Status string `json:"status"`
+type CancelOrderRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+/**
+ * @struct OrderDetail
+ * @brief Structure representing the full details of an order.
+ *
+ * This structure contains the items of an order with their product name, unit price
+ * at the time of purchase, quantity and line total, together with the order total,
+ * its timestamps, the cancellation details if it was cancelled, and its status history.
+ */
+type OrderDetail struct {
+	ID           uint                 `json:"id"`
+	Status       string               `json:"status"`
+	Total        int                  `json:"total"`
+	Items        []OrderItemResponse  `json:"items"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	CancelledAt  *time.Time           `json:"cancelled_at,omitempty"`
+	CancelReason string               `json:"cancel_reason,omitempty"`
+	History      []OrderStatusHistory `json:"history"`
+}
+
+/**
+ * @struct OrderDetailResponse
+ * @brief Response structure for querying a specific order.
+ *
+ * This structure represents the data returned when querying an order,
+ * including a response code and the details of the order.
+ */
+type OrderDetailResponse struct {
+	Code  string      `json:"code"`
+	Order OrderDetail `json:"order"`
 }
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * @struct Session
+ * @brief Structure representing a login of a user on a device.
+ *
+ * A session is started at every login and lives as long as its refresh tokens
+ * keep being exchanged. It records the device (user agent) and IP address the
+ * user logged in from, when the session was last used, and whether the login
+ * was confirmed with a second factor.
+ */
+type Session struct {
+	gorm.Model
+	UserID     uint `gorm:"index"`
+	UserAgent  string
+	IPAddress  string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	TwoFactor  bool `gorm:"not null;default:false"`
+}
+
+/**
+ * @struct ClientInfo
+ * @brief Structure describing the client a request comes from.
+ *
+ * This structure contains the user agent and IP address recorded on the session of the user.
+ */
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+/**
+ * @struct SessionResponse
+ * @brief Structure representing a session returned by the API.
+ *
+ * This structure contains the device and IP address of the session, when it was
+ * created and last used, when it expires, and whether it is the session of the
+ * request itself.
+ */
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+/**
+ * @struct SessionsResponse
+ * @brief Structure representing the response data for fetching the sessions of a user.
+ *
+ * This structure contains the response code and the active sessions of the user, most recently used first.
+ */
+type SessionsResponse struct {
+	Code     string            `json:"code"`
+	Sessions []SessionResponse `json:"sessions"`
+}
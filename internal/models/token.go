@@ -0,0 +1,93 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * @struct RefreshToken
+ * @brief Structure representing a refresh token issued to a user.
+ *
+ * Only the SHA-256 hash of the token is stored, together with the ID of the access
+ * token issued alongside it. Every refresh token belongs to the session started at
+ * login; using a token replaces it with a new one of the same session, and presenting
+ * an already used token revokes the whole session.
+ */
+type RefreshToken struct {
+	gorm.Model
+	UserID               uint   `gorm:"index"`
+	SessionID            uint   `gorm:"index"`
+	TokenHash            string `gorm:"uniqueIndex"`
+	AccessTokenID        string
+	AccessTokenExpiresAt time.Time
+	ExpiresAt            time.Time
+	UsedAt               *time.Time
+	RevokedAt            *time.Time
+}
+
+/**
+ * @struct RevokedToken
+ * @brief Structure representing an access token that was revoked before it expired.
+ *
+ * Access tokens are identified by their JWT ID (jti). Entries are only needed
+ * until the token would have expired anyway, after which they are purged.
+ */
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+/**
+ * @struct AuthTokens
+ * @brief Structure representing the tokens issued after a successful authentication.
+ *
+ * This structure contains the short-lived access token (JWT), the long-lived
+ * refresh token used to obtain new ones, and the lifetime of the access token.
+ * When the user still has to confirm the login with a second factor, only the
+ * challenge token is set instead, and ExpiresIn is the lifetime of the challenge.
+ */
+type AuthTokens struct {
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
+	ExpiresIn      time.Duration
+}
+
+/**
+ * @struct RefreshRequest
+ * @brief Structure representing the request data for refreshing the tokens of a user.
+ *
+ * This structure contains the refresh token obtained at login or at the previous refresh.
+ */
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+/**
+ * @struct JSONWebKey
+ * @brief Structure representing a public key used to verify the JWTs issued by the API.
+ *
+ * RSA keys carry their modulus (n) and exponent (e), Ed25519 keys their curve (crv) and public key (x).
+ */
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Modulus   string `json:"n,omitempty"`
+	Exponent  string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+}
+
+/**
+ * @struct JSONWebKeySet
+ * @brief Structure representing the set of public keys used to verify the JWTs issued by the API.
+ */
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
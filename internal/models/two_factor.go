@@ -0,0 +1,108 @@
+package models
+
+import "time"
+
+/**
+ * @struct RecoveryCode
+ * @brief Structure representing a code that can be used once instead of a TOTP code.
+ *
+ * Only the hash of the code is stored. Users get a new set of codes when they
+ * enroll in two-factor authentication or ask for new ones, which replaces the
+ * previous set.
+ */
+type RecoveryCode struct {
+	ID        uint   `gorm:"primarykey"`
+	UserID    uint   `gorm:"index"`
+	CodeHash  string `gorm:"index"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+/**
+ * @struct LoginChallenge
+ * @brief Structure representing a login waiting to be confirmed with a second factor.
+ *
+ * Only the hash of the challenge token handed to the client is stored. The
+ * challenge can be completed once, before it expires and before too many wrong
+ * codes have been entered.
+ */
+type LoginChallenge struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"index"`
+	TokenHash string    `gorm:"uniqueIndex"`
+	Attempts  int       `gorm:"not null;default:0"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+/**
+ * @struct LoginChallengeResponse
+ * @brief Structure representing the response data for a login that needs a second factor.
+ *
+ * This structure contains the response code, the challenge token to send back
+ * with the TOTP or recovery code, and the number of seconds until it expires.
+ */
+type LoginChallengeResponse struct {
+	Code           string `json:"code"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+	Message        string `json:"message"`
+}
+
+/**
+ * @struct TwoFactorLoginRequest
+ * @brief Structure representing the request data for confirming a login with a second factor.
+ *
+ * This structure contains the challenge token returned by the login and either a
+ * code of the authenticator app or one of the recovery codes.
+ */
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+/**
+ * @struct TOTPEnrollmentResponse
+ * @brief Structure representing the response data for starting the enrollment in two-factor authentication.
+ *
+ * This structure contains the response code, the secret to enter in the
+ * authenticator app and the otpauth URL to show as a QR code instead.
+ */
+type TOTPEnrollmentResponse struct {
+	Code   string `json:"code"`
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+}
+
+/**
+ * @struct TOTPCodeRequest
+ * @brief Structure representing the request data for the operations confirmed with a code of the authenticator app.
+ *
+ * This structure contains the code, or one of the recovery codes where they are accepted.
+ */
+type TOTPCodeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+/**
+ * @struct DisableTOTPRequest
+ * @brief Structure representing the request data for turning off two-factor authentication.
+ *
+ * This structure contains the password of the user, left empty by users who
+ * never set one, and a code of the authenticator app or a recovery code.
+ */
+type DisableTOTPRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" validate:"required"`
+}
+
+/**
+ * @struct RecoveryCodesResponse
+ * @brief Structure representing the response data for a new set of recovery codes.
+ *
+ * This structure contains the response code and the recovery codes, which are shown only this once.
+ */
+type RecoveryCodesResponse struct {
+	Code          string   `json:"code"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -1,20 +1,52 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief Roles that can be assigned to a user.
+ */
+const (
+	RoleBuyer = "buyer"
+	RoleAdmin = "admin"
+)
 
 /**
  * @struct User
  * @brief Structure representing a user in the system.
  *
  * This structure represents a user with attributes such as username, email,
- * password, and token.
+ * password, and role. Users are only soft deleted, so the username and email
+ * are unique among the users that are not deleted.
  */
 type User struct {
 	gorm.Model
-	Username string `json:"username" gorm:"uniqueIndex"`
-	Email    string `json:"email" gorm:"uniqueIndex"`
-	Password string `json:"password"`
-	Token    string `json:"token"`
+	Username string `json:"username" gorm:"uniqueIndex:idx_users_username_active,where:deleted_at IS NULL"`
+	Email    string `json:"email" gorm:"uniqueIndex:idx_users_email_active,where:deleted_at IS NULL"`
+	Password string `json:"-"`
+	Role     string `json:"role" gorm:"not null;default:buyer"`
+	// Access tokens issued before this time, or in the same second, are rejected, which logs the user out everywhere.
+	TokensValidAfter *time.Time `json:"-"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	// Only the hashes of pending verification and reset tokens are stored, until they are used or expire.
+	EmailVerificationTokenHash string     `json:"-" gorm:"index"`
+	EmailVerificationExpiresAt *time.Time `json:"-"`
+	PasswordResetTokenHash     string     `json:"-" gorm:"index"`
+	PasswordResetExpiresAt     *time.Time `json:"-"`
+	// Suspended users cannot log in or use their tokens until an admin reactivates them.
+	SuspendedAt   *time.Time `json:"-"`
+	SuspendReason string     `json:"-"`
+	// When the personal data of the deleted user was erased.
+	AnonymizedAt *time.Time `json:"-"`
+	// The secret of the enrolled authenticator, and the one awaiting a first code during enrollment.
+	TOTPSecret        string     `json:"-"`
+	TOTPPendingSecret string     `json:"-"`
+	TOTPEnabledAt     *time.Time `json:"-"`
+	// The time step of the last accepted code, so that no code is accepted twice.
+	TOTPLastStep int64 `json:"-"`
 }
 
 /**
@@ -25,9 +57,9 @@ type User struct {
  * including username, email, and password.
  */
 type RegisterUserRequest struct {
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Username string `json:"username" validate:"required,username"`
+	Email    string `json:"email" validate:"required,email,max=254"`
+	Password string `json:"password" validate:"required,password"`
 }
 
 /**
@@ -37,19 +69,53 @@ type RegisterUserRequest struct {
  * This structure contains the email and password required for a user to log in.
  */
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+/**
+ * @struct VerifyEmailRequest
+ * @brief Structure representing the request data for verifying the email of an account.
+ *
+ * This structure contains the verification token sent by email.
+ */
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+/**
+ * @struct ForgotPasswordRequest
+ * @brief Structure representing the request data for asking for a password reset.
+ *
+ * This structure contains the email of the account whose password was forgotten.
+ */
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+/**
+ * @struct ResetPasswordRequest
+ * @brief Structure representing the request data for resetting a password.
+ *
+ * This structure contains the reset token sent by email and the new password.
+ */
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
 }
 
 /**
  * @struct LoginResponse
  * @brief Structure representing the response data for user login.
  *
- * This structure contains the response code and authentication token returned after a successful login.
+ * This structure contains the response code, the short-lived access token, the refresh
+ * token and the number of seconds until the access token expires.
  */
 type LoginResponse struct {
-	Code string `json:"code"`
-	Auth string `json:"auth"`
+	Code         string `json:"code"`
+	Auth         string `json:"auth"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
 }
 
 /**
@@ -70,16 +136,202 @@ type Response struct {
  * This structure contains the email of the user to be deleted.
  */
 type DeleteUserRequest struct {
-	Email string `json:"email"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+/**
+ * @struct UpdateUserRoleRequest
+ * @brief Structure representing the request data for changing the role of a user.
+ *
+ * This structure contains the role to assign to the user, either "buyer" or "admin".
+ */
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=buyer admin"`
+}
+
+/**
+ * @struct UserRoleResponse
+ * @brief Structure representing the response data after changing the role of a user.
+ *
+ * This structure contains the response code, the ID of the user and their new role.
+ */
+type UserRoleResponse struct {
+	Code   string `json:"code"`
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+/**
+ * @struct UserResponse
+ * @brief Structure representing a user in API responses.
+ *
+ * This structure contains the public data of a user. Password hashes, TOTP
+ * secrets and pending tokens are never part of it.
+ */
+type UserResponse struct {
+	ID              uint       `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	// Only set for suspended accounts.
+	SuspendedAt   *time.Time `json:"suspended_at,omitempty"`
+	SuspendReason string     `json:"suspend_reason,omitempty"`
+	// Only set for deleted accounts.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Only set once the personal data of a deleted account was erased.
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty"`
+	// Whether the user logs in with two-factor authentication.
+	TwoFactor bool `json:"two_factor"`
+}
+
+/**
+ * @struct UserFilter
+ * @brief Structure representing the filters applied when searching users.
+ *
+ * This structure contains the optional text matched against the username and the
+ * email, the optional role, whether to search the deleted users instead of the
+ * active ones, and the requested page and page size.
+ */
+type UserFilter struct {
+	Query   string
+	Role    string
+	Deleted bool
+	Page    int
+	Limit   int
 }
 
 /**
  * @struct UsersResponse
  * @brief Structure representing the response data for fetching users.
  *
- * This structure contains the response code and a list of users returned by the API.
+ * This structure contains the response code, the requested page of users and the pagination details.
  */
 type UsersResponse struct {
-	Code  string `json:"code"`
-	Users []User `json:"users"`
+	Code  string         `json:"code"`
+	Users []UserResponse `json:"users"`
+	Page  int            `json:"page"`
+	Limit int            `json:"limit"`
+	Total int64          `json:"total"`
+}
+
+/**
+ * @struct UserOrderStats
+ * @brief Structure representing statistics about the orders of a user.
+ *
+ * This structure contains the number of orders of a user, how much they spent on
+ * orders that were not cancelled, their number of orders per status and when
+ * they last ordered.
+ */
+type UserOrderStats struct {
+	TotalOrders    int64            `json:"total_orders"`
+	TotalSpent     int              `json:"total_spent"`
+	OrdersByStatus map[string]int64 `json:"orders_by_status"`
+	LastOrderAt    *time.Time       `json:"last_order_at,omitempty"`
+}
+
+/**
+ * @struct UserDetailResponse
+ * @brief Structure representing the response data for fetching a single user.
+ *
+ * This structure contains the response code, the user and statistics about their orders.
+ */
+type UserDetailResponse struct {
+	Code       string         `json:"code"`
+	User       UserResponse   `json:"user"`
+	OrderStats UserOrderStats `json:"order_stats"`
+}
+
+/**
+ * @struct UpdatedUserResponse
+ * @brief Structure representing the response data after changing a user.
+ *
+ * This structure contains the response code and the user as it is after the change.
+ */
+type UpdatedUserResponse struct {
+	Code string       `json:"code"`
+	User UserResponse `json:"user"`
+}
+
+/**
+ * @struct UpdateUserRequest
+ * @brief Structure representing the request data for updating the profile of a user.
+ *
+ * This structure contains the new username and the new email, either of which may be left out.
+ */
+type UpdateUserRequest struct {
+	Username *string `json:"username" validate:"omitempty,username"`
+	Email    *string `json:"email" validate:"omitempty,email,max=254"`
+}
+
+/**
+ * @struct ProfileResponse
+ * @brief Structure representing the response data for the account of the logged-in user.
+ *
+ * This structure contains the response code and the user.
+ */
+type ProfileResponse struct {
+	Code string       `json:"code"`
+	User UserResponse `json:"user"`
+}
+
+/**
+ * @struct ChangePasswordRequest
+ * @brief Structure representing the request data for changing the password of the logged-in user.
+ *
+ * This structure contains the current password, which has to be confirmed, and the new one.
+ * Users who signed up with an identity provider have no password yet, so they leave the
+ * current password empty to set their first one.
+ */
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" validate:"required,password"`
+}
+
+/**
+ * @struct DeleteAccountRequest
+ * @brief Structure representing the request data for deleting the account of the logged-in user.
+ *
+ * This structure contains the password of the user, confirming the deletion. It is
+ * left empty by users who signed up with an identity provider and never set a password.
+ */
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+/**
+ * @struct SuspendUserRequest
+ * @brief Structure representing the request data for suspending a user.
+ *
+ * This structure contains an optional reason, shown to admins.
+ */
+type SuspendUserRequest struct {
+	Reason string `json:"reason" validate:"max=500"`
+}
+
+/**
+ * @struct FieldError
+ * @brief Structure representing a field of a request that failed validation.
+ *
+ * This structure contains the path of the field in the request body and the reason it was rejected.
+ */
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+/**
+ * @struct ErrorResponse
+ * @brief Structure representing the response data of every failed request.
+ *
+ * This structure contains the HTTP status code, a stable machine-readable error
+ * code (e.g. "order_not_found") that clients can switch on, a human readable
+ * message and, for requests that failed validation, every failing field.
+ */
+type ErrorResponse struct {
+	Code    string       `json:"code"`
+	Error   string       `json:"error"`
+	Message string       `json:"message"`
+	Errors  []FieldError `json:"errors,omitempty"`
 }
@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+/**
+ * @brief Minimum time between two sweeps of the idle buckets of a memory store.
+ */
+const sweepInterval = time.Minute
+
+/**
+ * @struct bucket
+ * @brief Token bucket of a memory store.
+ */
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+/**
+ * @brief memoryStore struct provides an implementation of Store that keeps the buckets in memory.
+ */
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+/**
+ * @brief NewMemoryStore creates a new Store that keeps the buckets in memory.
+ *
+ * Buckets are not shared between instances, so every instance of the API
+ * enforces the limits on its own.
+ *
+ * @return A new Store instance.
+ */
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket)}
+}
+
+/**
+ * @brief Takes a token from the bucket of a key, refilling it first.
+ *
+ * @param key The key of the bucket.
+ * @param limit The limit of the bucket.
+ * @return Whether the request is allowed and the state of the bucket.
+ */
+func (s *memoryStore) Take(key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	capacity := float64(limit.Requests)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.period = limit.Period
+
+	elapsed := now.Sub(b.updated)
+	b.tokens = math.Min(capacity, b.tokens+capacity*float64(elapsed)/float64(limit.Period))
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return newResult(limit, b.tokens, allowed), nil
+}
+
+/**
+ * @brief Removes the buckets that have been idle long enough to be full again.
+ *
+ * Sweeps run at most once per sweepInterval. The caller must hold the lock.
+ *
+ * @param now The current time.
+ */
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+/**
+ * @brief Checks that a bucket allows a burst of its requests, then refills one token per share of the period.
+ */
+func TestMemoryStoreBurstAndRefill(t *testing.T) {
+	store := NewMemoryStore().(*memoryStore)
+	limit := Limit{Requests: 3, Period: time.Minute}
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take("ip:10.0.0.1", limit)
+		if err != nil {
+			t.Fatalf("failed to take token: %v", err)
+		}
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("expected the burst to be allowed with %d remaining, got %+v", i, result)
+		}
+	}
+
+	result, err := store.Take("ip:10.0.0.1", limit)
+	if err != nil {
+		t.Fatalf("failed to take token: %v", err)
+	}
+	if result.Allowed || result.RetryAfter <= 0 || result.RetryAfter > 20*time.Second {
+		t.Fatalf("expected the empty bucket to reject the request within one token's delay, got %+v", result)
+	}
+
+	other, err := store.Take("ip:10.0.0.2", limit)
+	if err != nil {
+		t.Fatalf("failed to take token: %v", err)
+	}
+	if !other.Allowed || other.Remaining != 2 {
+		t.Fatalf("expected the buckets of other keys to be full, got %+v", other)
+	}
+
+	store.buckets["ip:10.0.0.1"].updated = store.buckets["ip:10.0.0.1"].updated.Add(-20 * time.Second)
+	result, err = store.Take("ip:10.0.0.1", limit)
+	if err != nil {
+		t.Fatalf("failed to take token: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 0 {
+		t.Fatalf("expected a third of the period to refill one token, got %+v", result)
+	}
+
+	store.buckets["ip:10.0.0.1"].updated = store.buckets["ip:10.0.0.1"].updated.Add(-time.Hour)
+	result, err = store.Take("ip:10.0.0.1", limit)
+	if err != nil {
+		t.Fatalf("failed to take token: %v", err)
+	}
+	if !result.Allowed || result.Remaining != 2 {
+		t.Fatalf("expected the bucket to refill no further than its capacity, got %+v", result)
+	}
+}
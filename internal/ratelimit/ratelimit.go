@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @struct Limit
+ * @brief A token bucket allowing Requests requests per Period.
+ *
+ * The bucket holds up to Requests tokens and is refilled continuously, so
+ * clients can burst up to Requests requests and then make one request every
+ * Period / Requests. A limit without requests is disabled.
+ */
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+/**
+ * @brief Tells whether the limit is enforced.
+ *
+ * @return True if the limit allows a positive number of requests per period.
+ */
+func (l Limit) Enabled() bool {
+	return l.Requests > 0 && l.Period > 0
+}
+
+/**
+ * @brief Returns the limit as a RateLimit-Policy header value, e.g. "10;w=60".
+ *
+ * @return The policy of the limit.
+ */
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Requests, int64(math.Ceil(l.Period.Seconds())))
+}
+
+/**
+ * @brief Parses a limit written as "<requests>/<period>", e.g. "10/1m", or "off" to disable it.
+ *
+ * @param value The limit to parse.
+ * @return The limit and an error if the value is malformed.
+ */
+func ParseLimit(value string) (Limit, error) {
+	if value == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limit %q is not of the form <requests>/<period>", value)
+	}
+
+	limit := Limit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must allow a positive number of requests", value)
+	}
+	if limit.Period, err = time.ParseDuration(period); err != nil || limit.Period <= 0 {
+		return Limit{}, fmt.Errorf("limit %q must have a positive period", value)
+	}
+	return limit, nil
+}
+
+/**
+ * @struct Config
+ * @brief Limits applied to the routes of the API.
+ *
+ * Global applies to every request per client IP address, User to every
+ * authenticated request per user, and the other limits further restrict their
+ * routes. ForgotPasswordEmail limits the reset links sent to a single email,
+ * whoever asks for them.
+ */
+type Config struct {
+	Global              Limit
+	User                Limit
+	Register            Limit
+	Login               Limit
+	Checkout            Limit
+	Refresh             Limit
+	EmailToken          Limit
+	ForgotPassword      Limit
+	ForgotPasswordEmail Limit
+}
+
+/**
+ * @struct Result
+ * @brief Outcome of taking a token from a bucket.
+ *
+ * Remaining is the number of requests that can still be made right away, Reset
+ * how long until the bucket is full again and RetryAfter, for rejected requests,
+ * how long until the next token is available.
+ */
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+/**
+ * @interface Store
+ * @brief Interface for keeping the token buckets of the rate limits.
+ *
+ * This interface is implemented by an in-memory store for a single instance of
+ * the API and by a Redis-backed store shared between instances.
+ */
+type Store interface {
+	Take(key string, limit Limit) (Result, error)
+}
+
+/**
+ * @brief Builds the result of taking a token from a bucket.
+ *
+ * @param limit The limit of the bucket.
+ * @param tokens The tokens left in the bucket after the request.
+ * @param allowed Whether a token was taken.
+ * @return The result.
+ */
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	perToken := limit.Period / time.Duration(limit.Requests)
+	result := Result{
+		Allowed:   allowed,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(limit.Requests) - tokens) * float64(perToken)),
+	}
+	if !allowed {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(perToken))
+	}
+	return result
+}
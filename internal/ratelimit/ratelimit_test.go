@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+/**
+ * @brief Checks the limits accepted and refused by ParseLimit.
+ */
+func TestParseLimit(t *testing.T) {
+	cases := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{"10/1m", Limit{Requests: 10, Period: time.Minute}, false},
+		{"3/1h", Limit{Requests: 3, Period: time.Hour}, false},
+		{"100/30s", Limit{Requests: 100, Period: 30 * time.Second}, false},
+		{"off", Limit{}, false},
+		{"10", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"-1/1m", Limit{}, true},
+		{"ten/1m", Limit{}, true},
+		{"10/0s", Limit{}, true},
+		{"10/minute", Limit{}, true},
+		{"", Limit{}, true},
+	}
+	for _, tc := range cases {
+		got, err := ParseLimit(tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: expected error %v, got %v", tc.value, tc.wantErr, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: expected %+v, got %+v", tc.value, tc.want, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks that only limits with requests and a period are enforced, and how their policy is written.
+ */
+func TestLimitPolicy(t *testing.T) {
+	if (Limit{}).Enabled() || (Limit{Requests: 10}).Enabled() {
+		t.Fatalf("expected limits without requests or period to be disabled")
+	}
+
+	cases := []struct {
+		limit Limit
+		want  string
+	}{
+		{Limit{Requests: 10, Period: time.Minute}, "10;w=60"},
+		{Limit{Requests: 3, Period: time.Hour}, "3;w=3600"},
+		{Limit{Requests: 5, Period: 1500 * time.Millisecond}, "5;w=2"},
+	}
+	for _, tc := range cases {
+		if !tc.limit.Enabled() {
+			t.Errorf("%+v: expected the limit to be enabled", tc.limit)
+		}
+		if got := tc.limit.Policy(); got != tc.want {
+			t.Errorf("%+v: expected policy %q, got %q", tc.limit, tc.want, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks the remaining requests, reset and retry delays derived from the tokens of a bucket.
+ */
+func TestNewResult(t *testing.T) {
+	limit := Limit{Requests: 10, Period: 10 * time.Second}
+
+	cases := []struct {
+		tokens  float64
+		allowed bool
+		want    Result
+	}{
+		{9, true, Result{Allowed: true, Remaining: 9, Reset: time.Second}},
+		{0, true, Result{Allowed: true, Remaining: 0, Reset: 10 * time.Second}},
+		{0.5, false, Result{Remaining: 0, Reset: 9500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+	}
+	for _, tc := range cases {
+		if got := newResult(limit, tc.tokens, tc.allowed); got != tc.want {
+			t.Errorf("%v tokens: expected %+v, got %+v", tc.tokens, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+/**
+ * @brief Refills the bucket of a key and takes a token from it, using the clock of the Redis server.
+ *
+ * Returns whether a token was taken and the tokens left, as a string since Lua
+ * numbers are truncated to integers when returned to the client.
+ */
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "updated")
+local tokens = tonumber(state[1])
+local updated = tonumber(state[2])
+if tokens == nil or updated == nil then
+	tokens = capacity
+	updated = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - updated) * capacity / period)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated", tostring(now))
+redis.call("PEXPIRE", KEYS[1], period)
+return {allowed, tostring(tokens)}
+`)
+
+/**
+ * @brief redisStore struct provides an implementation of Store backed by Redis.
+ */
+type redisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+/**
+ * @brief NewRedisStore creates a new Store backed by Redis.
+ *
+ * Buckets are shared by every instance of the API using the same Redis server,
+ * so the limits hold no matter which instance serves a request, and they expire
+ * on their own once full again.
+ *
+ * @param client The Redis client to use.
+ * @param prefix The prefix of the Redis keys, e.g. "ratelimit:".
+ * @return A new Store instance.
+ */
+func NewRedisStore(client *redis.Client, prefix string) Store {
+	return &redisStore{client: client, prefix: prefix}
+}
+
+/**
+ * @brief Takes a token from the bucket of a key, refilling it first.
+ *
+ * @param key The key of the bucket.
+ * @param limit The limit of the bucket.
+ * @return Whether the request is allowed and the state of the bucket, and an error if Redis cannot be reached.
+ */
+func (s *redisStore) Take(key string, limit Limit) (Result, error) {
+	reply, err := takeScript.Run(context.Background(), s.client,
+		[]string{s.prefix + key}, limit.Requests, limit.Period.Milliseconds()).Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	if len(reply) != 2 {
+		return Result{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+
+	allowed, _ := reply[0].(int64)
+	remaining, _ := reply[1].(string)
+	tokens, err := strconv.ParseFloat(remaining, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected rate limit reply %v", reply)
+	}
+	return newResult(limit, tokens, allowed == 1), nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/**
+ * @brief APIKeyRepository interface defines methods for API key-related database operations.
+ */
+type APIKeyRepository interface {
+	CreateAPIKey(key *models.APIKey, limit int64, now time.Time) (bool, error)
+	GetAPIKeyByPrefix(prefix string) (*models.APIKey, error)
+	GetUserAPIKeys(userID uint) ([]models.APIKey, error)
+	TouchAPIKey(id uint, usedAt time.Time, interval time.Duration) error
+	RevokeAPIKey(id, userID uint, revokedAt time.Time) (bool, error)
+	RevokeUserAPIKeys(userID uint, revokedAt time.Time) error
+}
+
+/**
+ * @brief apiKeyRepository struct provides the implementation of APIKeyRepository.
+ */
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+/**
+ * @brief NewAPIKeyRepository creates a new instance of apiKeyRepository.
+ *
+ * @param db The database connection.
+ * @return A new APIKeyRepository instance.
+ */
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+/**
+ * @brief Creates a new API key in the database, unless its user already has as many active keys as allowed.
+ *
+ * The row of the user is locked while their keys are counted, so that
+ * concurrent creations cannot all pass the count and exceed the limit.
+ *
+ * @param key The API key model to be created.
+ * @param limit The maximum number of keys of the user that are neither revoked nor expired.
+ * @param now The current time, used to leave out expired keys.
+ * @return True if the key was created, false if the user has reached the limit, and an error if the creation fails.
+ */
+func (r *apiKeyRepository) CreateAPIKey(key *models.APIKey, limit int64, now time.Time) (bool, error) {
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, key.UserID).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		err := tx.Model(&models.APIKey{}).
+			Where("user_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", key.UserID, now).
+			Count(&active).Error
+		if err != nil {
+			return err
+		}
+		if active >= limit {
+			return nil
+		}
+
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+/**
+ * @brief Retrieves an API key by its prefix.
+ *
+ * @param prefix The public prefix of the key.
+ * @return The API key model and an error if the retrieval fails.
+ */
+func (r *apiKeyRepository) GetAPIKeyByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+/**
+ * @brief Retrieves the API keys of a user that have not been revoked, newest first.
+ *
+ * @param userID The ID of the user.
+ * @return A slice of API key models and an error if the retrieval fails.
+ */
+func (r *apiKeyRepository) GetUserAPIKeys(userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC, id DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+/**
+ * @brief Updates the last-used time of an API key.
+ *
+ * The key is only written when it was last used more than the given interval
+ * ago, so authenticated requests do not each cause a database write.
+ *
+ * @param id The ID of the key.
+ * @param usedAt The time the key was used.
+ * @param interval The minimum time between two updates.
+ * @return An error if the update fails.
+ */
+func (r *apiKeyRepository) TouchAPIKey(id uint, usedAt time.Time, interval time.Duration) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, usedAt.Add(-interval)).
+		Update("last_used_at", usedAt).Error
+}
+
+/**
+ * @brief Revokes an API key of a user.
+ *
+ * @param id The ID of the key.
+ * @param userID The ID of the user owning the key.
+ * @param revokedAt The time of the revocation.
+ * @return True if the key was revoked, false if the user has no such key that is not already revoked, and an error if the update fails.
+ */
+func (r *apiKeyRepository) RevokeAPIKey(id, userID uint, revokedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.APIKey{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", revokedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+/**
+ * @brief Revokes every API key of a user.
+ *
+ * @param userID The ID of the user.
+ * @param revokedAt The time of the revocation.
+ * @return An error if the update fails.
+ */
+func (r *apiKeyRepository) RevokeUserAPIKeys(userID uint, revokedAt time.Time) error {
+	return r.db.Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
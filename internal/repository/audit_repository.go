@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief AuditRepository interface defines methods for audit log database operations.
+ */
+type AuditRepository interface {
+	CreateAuditEntry(entry *models.AuditEntry) error
+	GetAuditEntries(action string, limit int) ([]models.AuditEntry, error)
+}
+
+/**
+ * @brief auditRepository struct provides the implementation of AuditRepository.
+ */
+type auditRepository struct {
+	db *gorm.DB
+}
+
+/**
+ * @brief NewAuditRepository creates a new instance of auditRepository.
+ *
+ * @param db The database connection.
+ * @return A new AuditRepository instance.
+ */
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+/**
+ * @brief Appends an entry to the audit log.
+ *
+ * @param entry The audit entry to be created.
+ * @return An error if the creation fails.
+ */
+func (r *auditRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+/**
+ * @brief Retrieves the most recent entries of the audit log.
+ *
+ * @param action The action to filter by, or an empty string for every action.
+ * @param limit The maximum number of entries to return.
+ * @return The entries, newest first, and an error if the retrieval fails.
+ */
+func (r *auditRepository) GetAuditEntries(action string, limit int) ([]models.AuditEntry, error) {
+	query := r.db.Order("created_at DESC, id DESC").Limit(limit)
+	if action != "" {
+		query = query.Where("action = ?", action)
+	}
+
+	var entries []models.AuditEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
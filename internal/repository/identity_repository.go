@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/**
+ * @brief IdentityRepository interface defines methods for the database operations of logins with an OpenID Connect provider.
+ */
+type IdentityRepository interface {
+	CreateProviderLogin(login *models.ProviderLogin) error
+	ConsumeProviderLogin(stateHash string, now time.Time) (*models.ProviderLogin, error)
+	GetExternalIdentity(issuer, subject string) (*models.ExternalIdentity, error)
+	CreateExternalIdentity(identity *models.ExternalIdentity) error
+	CreateUserWithIdentity(user *models.User, identity *models.ExternalIdentity) error
+}
+
+/**
+ * @brief identityRepository struct provides the implementation of IdentityRepository.
+ */
+type identityRepository struct {
+	db *gorm.DB
+}
+
+/**
+ * @brief NewIdentityRepository creates a new instance of identityRepository.
+ *
+ * @param db The database connection.
+ * @return A new IdentityRepository instance.
+ */
+func NewIdentityRepository(db *gorm.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+/**
+ * @brief Stores a new pending login, removing the pending logins that have expired.
+ *
+ * @param login The pending login to be created.
+ * @return An error if the creation fails.
+ */
+func (r *identityRepository) CreateProviderLogin(login *models.ProviderLogin) error {
+	if err := r.db.Where("expires_at < ?", login.CreatedAt).Delete(&models.ProviderLogin{}).Error; err != nil {
+		return err
+	}
+	return r.db.Create(login).Error
+}
+
+/**
+ * @brief Removes a pending login that has not expired and returns it.
+ *
+ * Removing it in the same statement makes sure it is only completed once.
+ *
+ * @param stateHash The hash of the state handed to the provider.
+ * @param now The current time, used to leave out expired logins.
+ * @return The pending login and an error if no such login is pending or the removal fails.
+ */
+func (r *identityRepository) ConsumeProviderLogin(stateHash string, now time.Time) (*models.ProviderLogin, error) {
+	var login models.ProviderLogin
+	result := r.db.Clauses(clause.Returning{}).
+		Where("state_hash = ? AND expires_at > ?", stateHash, now).
+		Delete(&login)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &login, nil
+}
+
+/**
+ * @brief Retrieves the identity a user has at a provider.
+ *
+ * @param issuer The issuer of the provider.
+ * @param subject The identifier of the user at the provider.
+ * @return The external identity model and an error if the retrieval fails.
+ */
+func (r *identityRepository) GetExternalIdentity(issuer, subject string) (*models.ExternalIdentity, error) {
+	var identity models.ExternalIdentity
+	err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+/**
+ * @brief Links an identity at a provider to an existing user.
+ *
+ * @param identity The external identity model to be created.
+ * @return An error if the creation fails.
+ */
+func (r *identityRepository) CreateExternalIdentity(identity *models.ExternalIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+/**
+ * @brief Creates a new user linked to an identity at a provider.
+ *
+ * @param user The user model to be created.
+ * @param identity The external identity to link to the user, whose UserID is filled in.
+ * @return An error if the creation fails, in which case neither is created.
+ */
+func (r *identityRepository) CreateUserWithIdentity(user *models.User, identity *models.ExternalIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
@@ -1,26 +1,36 @@
 package repository
 
 import (
+	"time"
+
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 /**
  * @brief OrderRepository interface defines methods for order-related database operations.
  */
 type OrderRepository interface {
+	Transaction(fn func(repo OrderRepository) error) error
 	CreateOrder(order *models.Order) error
 	GetOrderById(id uint) (*models.Order, error)
+	GetOrderWithItems(id uint) (*models.Order, error)
+	GetOrderForUpdate(id uint) (*models.Order, error)
 	UpdateOrderStatus(id uint, status string) error
+	CancelOrder(id uint, cancelledBy uint, reason string, cancelledAt time.Time) error
+	CreateStatusHistory(entry *models.OrderStatusHistory) error
+	GetStatusHistory(orderID uint) ([]models.OrderStatusHistory, error)
 	CountOrders() (int64, error)
 	CalculateTotalRevenue() (int, error)
 	CountOrdersByStatus(status string) (int64, error)
-	ExistsOffer(id uint) (bool, error)
-	GetOfferQuantity(id uint) (int, error)
-	UpdateOfferQuantity(id uint, quantity int) error
-	GetOfferPrice(id uint) (int, error)
+	GetOfferForUpdate(id uint) (*models.Offer, error)
+	DecrementOfferQuantity(id uint, quantity int) (bool, error)
+	IncrementOfferQuantity(id uint, quantity int) error
 	GetAllOffers() ([]models.Offer, error)
 	GetAllOrders() ([]models.Order, error)
+	GetOrdersByUser(userID uint, filter models.OrderFilter) ([]models.Order, int64, error)
+	GetUserOrderStats(userID uint) (*models.UserOrderStats, error)
 }
 
 /**
@@ -40,6 +50,22 @@ func NewOrderRepository(db *gorm.DB) OrderRepository {
 	return &orderRepository{db: db}
 }
 
+/**
+ * @brief Runs the given function inside a database transaction.
+ *
+ * The repository passed to the function is bound to the transaction, so every
+ * operation performed through it is committed or rolled back as a whole. The
+ * transaction is rolled back if the function returns an error.
+ *
+ * @param fn The function to run inside the transaction.
+ * @return The error returned by the function, or an error if the transaction fails.
+ */
+func (r *orderRepository) Transaction(fn func(repo OrderRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&orderRepository{db: tx})
+	})
+}
+
 /**
  * @brief Creates a new order in the database.
  *
@@ -64,6 +90,41 @@ func (r *orderRepository) GetOrderById(id uint) (*models.Order, error) {
 	return &order, nil
 }
 
+/**
+ * @brief Retrieves an order by its ID, including its items and their offers.
+ *
+ * Offers are loaded even if they were deleted after the order was placed.
+ *
+ * @param id The ID of the order.
+ * @return The order model and an error if the retrieval fails.
+ */
+func (r *orderRepository) GetOrderWithItems(id uint) (*models.Order, error) {
+	var order models.Order
+	err := r.db.
+		Preload("OrderItems").
+		Preload("OrderItems.Offer", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+/**
+ * @brief Retrieves an order with its items and locks its row until the end of the transaction.
+ *
+ * @param id The ID of the order.
+ * @return The locked order model and an error if the retrieval fails.
+ */
+func (r *orderRepository) GetOrderForUpdate(id uint) (*models.Order, error) {
+	var order models.Order
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("OrderItems").First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
 /**
  * @brief Updates the status of an order by its ID.
  *
@@ -75,6 +136,49 @@ func (r *orderRepository) UpdateOrderStatus(id uint, status string) error {
 	return r.db.Model(&models.Order{}).Where("id = ?", id).Update("status", status).Error
 }
 
+/**
+ * @brief Marks an order as cancelled, recording who cancelled it, when and why.
+ *
+ * @param id The ID of the order.
+ * @param cancelledBy The ID of the user who cancelled the order.
+ * @param reason The reason given for the cancellation.
+ * @param cancelledAt The time of the cancellation.
+ * @return An error if the update fails.
+ */
+func (r *orderRepository) CancelOrder(id uint, cancelledBy uint, reason string, cancelledAt time.Time) error {
+	return r.db.Model(&models.Order{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        models.OrderStatusCancelled,
+		"cancelled_by":  cancelledBy,
+		"cancelled_at":  cancelledAt,
+		"cancel_reason": reason,
+	}).Error
+}
+
+/**
+ * @brief Records a change in the status of an order.
+ *
+ * @param entry The status history entry to be created.
+ * @return An error if the creation fails.
+ */
+func (r *orderRepository) CreateStatusHistory(entry *models.OrderStatusHistory) error {
+	return r.db.Create(entry).Error
+}
+
+/**
+ * @brief Retrieves the status history of an order, oldest change first.
+ *
+ * @param orderID The ID of the order.
+ * @return A slice of status history entries and an error if the retrieval fails.
+ */
+func (r *orderRepository) GetStatusHistory(orderID uint) ([]models.OrderStatusHistory, error) {
+	var history []models.OrderStatusHistory
+	err := r.db.Where("order_id = ?", orderID).Order("created_at, id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
 /**
  * @brief Counts the total number of orders.
  *
@@ -89,13 +193,17 @@ func (r *orderRepository) CountOrders() (int64, error) {
 }
 
 /**
- * @brief Calculates the total revenue from all orders.
+ * @brief Calculates the total revenue from all orders that were not cancelled.
  *
  * @return The total revenue and an error if the calculation fails.
  */
 func (r *orderRepository) CalculateTotalRevenue() (int, error) {
 	var total int
-	if err := r.db.Model(&models.Order{}).Select("SUM(total)").Row().Scan(&total); err != nil {
+	err := r.db.Model(&models.Order{}).
+		Where("status <> ?", models.OrderStatusCancelled).
+		Select("COALESCE(SUM(total), 0)").
+		Row().Scan(&total)
+	if err != nil {
 		return 0, err
 	}
 	return total, nil
@@ -116,59 +224,52 @@ func (r *orderRepository) CountOrdersByStatus(status string) (int64, error) {
 }
 
 /**
- * @brief Checks if an offer exists by its ID.
+ * @brief Retrieves an offer by its ID and locks its row until the end of the transaction.
  *
- * @param id The ID of the offer.
- * @return A boolean indicating if the offer exists and an error if the check fails.
- */
-func (r *orderRepository) ExistsOffer(id uint) (bool, error) {
-	var count int64
-	err := r.db.Model(&models.Offer{}).Where("id = ?", id).Count(&count).Error
-	if err != nil {
-		return false, err
-	}
-	return count > 0, nil
-}
-
-/**
- * @brief Retrieves the quantity of an offer by its ID.
+ * Concurrent transactions trying to lock the same offer block until this one
+ * commits or rolls back, so the returned quantity cannot change underneath the caller.
  *
  * @param id The ID of the offer.
- * @return The quantity of the offer and an error if the retrieval fails.
+ * @return The locked offer and an error if the retrieval fails.
  */
-func (r *orderRepository) GetOfferQuantity(id uint) (int, error) {
+func (r *orderRepository) GetOfferForUpdate(id uint) (*models.Offer, error) {
 	var offer models.Offer
-	err := r.db.Select("quantity").Where("id = ?", id).First(&offer).Error
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&offer).Error
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return offer.Quantity, nil
+	return &offer, nil
 }
 
 /**
- * @brief Updates the quantity of an offer by its ID.
+ * @brief Decrements the quantity of an offer only if enough stock is available.
+ *
+ * The check and the decrement are performed in a single conditional UPDATE,
+ * so the stock of an offer can never become negative.
  *
  * @param id The ID of the offer.
- * @param quantity The new quantity to be updated.
- * @return An error if the update fails.
+ * @param quantity The quantity to subtract from the stock.
+ * @return True if the stock was decremented, false if there was not enough stock, and an error if the update fails.
  */
-func (r *orderRepository) UpdateOfferQuantity(id uint, quantity int) error {
-	return r.db.Model(&models.Offer{}).Where("id = ?", id).Update("quantity", quantity).Error
+func (r *orderRepository) DecrementOfferQuantity(id uint, quantity int) (bool, error) {
+	result := r.db.Model(&models.Offer{}).
+		Where("id = ? AND quantity >= ?", id, quantity).
+		Update("quantity", gorm.Expr("quantity - ?", quantity))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
 
 /**
- * @brief Retrieves the price of an offer by its ID.
+ * @brief Returns the given quantity to the stock of an offer.
  *
  * @param id The ID of the offer.
- * @return The price of the offer and an error if the retrieval fails.
+ * @param quantity The quantity to add back to the stock.
+ * @return An error if the update fails.
  */
-func (r *orderRepository) GetOfferPrice(id uint) (int, error) {
-	var offer models.Offer
-	err := r.db.Select("price").Where("id = ?", id).First(&offer).Error
-	if err != nil {
-		return 0, err
-	}
-	return offer.Price, nil
+func (r *orderRepository) IncrementOfferQuantity(id uint, quantity int) error {
+	return r.db.Model(&models.Offer{}).Where("id = ?", id).Update("quantity", gorm.Expr("quantity + ?", quantity)).Error
 }
 
 /**
@@ -200,8 +301,93 @@ func (r *orderRepository) GetAllOffers() ([]models.Offer, error) {
  */
 func (r *orderRepository) GetAllOrders() ([]models.Order, error) {
 	var orders []models.Order
-	if err := r.db.Preload("OrderItems").Find(&orders).Error; err != nil && err != gorm.ErrRecordNotFound {
+	err := r.db.
+		Preload("OrderItems").
+		Preload("OrderItems.Offer", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Find(&orders).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
 		return nil, err
 	}
 	return orders, nil
 }
+
+/**
+ * @brief Retrieves a page of the orders placed by a user, newest first, including their items.
+ *
+ * The orders can be filtered by status and by a creation date range. Each item
+ * is loaded together with its offer, even if the offer was deleted afterwards.
+ *
+ * @param userID The ID of the buyer.
+ * @param filter The status, date range and pagination to apply.
+ * @return The requested page of orders, the total number of matching orders and an error if the query fails.
+ */
+func (r *orderRepository) GetOrdersByUser(userID uint, filter models.OrderFilter) ([]models.Order, int64, error) {
+	matching := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("user_id = ?", userID)
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		if filter.From != nil {
+			db = db.Where("created_at >= ?", *filter.From)
+		}
+		if filter.To != nil {
+			db = db.Where("created_at < ?", *filter.To)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.Model(&models.Order{}).Scopes(matching).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var orders []models.Order
+	err := r.db.Scopes(matching).
+		Preload("OrderItems").
+		Preload("OrderItems.Offer", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&orders).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+/**
+ * @brief Computes statistics about the orders of a user.
+ *
+ * @param userID The ID of the user.
+ * @return The number of orders per status, the amount spent on orders that were not cancelled,
+ *         when the user last ordered, and an error if the computation fails.
+ */
+func (r *orderRepository) GetUserOrderStats(userID uint) (*models.UserOrderStats, error) {
+	var rows []struct {
+		Status      string
+		Orders      int64
+		Spent       int
+		LastOrderAt *time.Time
+	}
+	err := r.db.Model(&models.Order{}).
+		Select("status, COUNT(*) AS orders, COALESCE(SUM(total), 0) AS spent, MAX(created_at) AS last_order_at").
+		Where("user_id = ?", userID).
+		Group("status").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.UserOrderStats{OrdersByStatus: make(map[string]int64, len(rows))}
+	for _, row := range rows {
+		stats.TotalOrders += row.Orders
+		stats.OrdersByStatus[row.Status] = row.Orders
+		if row.Status != models.OrderStatusCancelled {
+			stats.TotalSpent += row.Spent
+		}
+		if row.LastOrderAt != nil && (stats.LastOrderAt == nil || row.LastOrderAt.After(*stats.LastOrderAt)) {
+			stats.LastOrderAt = row.LastOrderAt
+		}
+	}
+	return stats, nil
+}
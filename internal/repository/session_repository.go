@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief SessionRepository interface defines methods for session-related database operations.
+ */
+type SessionRepository interface {
+	CreateSession(session *models.Session) error
+	GetSessionByID(id uint) (*models.Session, error)
+	GetActiveSessions(userID uint, now time.Time) ([]models.Session, error)
+	UpdateSessionActivity(id uint, client models.ClientInfo, lastSeenAt, expiresAt time.Time) error
+	TouchSession(id, userID uint, lastSeenAt time.Time, interval time.Duration) error
+	RevokeSession(id uint, revokedAt time.Time) error
+	MarkSessionTwoFactor(id, userID uint) error
+	RevokeUserSessions(userID uint, revokedAt time.Time) error
+}
+
+/**
+ * @brief sessionRepository struct provides the implementation of SessionRepository.
+ */
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+/**
+ * @brief NewSessionRepository creates a new instance of sessionRepository.
+ *
+ * @param db The database connection.
+ * @return A new SessionRepository instance.
+ */
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+/**
+ * @brief Creates a new session in the database.
+ *
+ * @param session The session model to be created.
+ * @return An error if the creation fails.
+ */
+func (r *sessionRepository) CreateSession(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+/**
+ * @brief Retrieves a session by its ID.
+ *
+ * @param id The ID of the session.
+ * @return The session model and an error if the retrieval fails.
+ */
+func (r *sessionRepository) GetSessionByID(id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+/**
+ * @brief Retrieves the sessions of a user that are neither revoked nor expired, most recently used first.
+ *
+ * @param userID The ID of the user.
+ * @param now The current time, used to leave out expired sessions.
+ * @return A slice of session models and an error if the retrieval fails.
+ */
+func (r *sessionRepository) GetActiveSessions(userID uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+/**
+ * @brief Records a new use of a session's refresh token.
+ *
+ * @param id The ID of the session.
+ * @param client The client the refresh token was used from.
+ * @param lastSeenAt The time the session was used.
+ * @param expiresAt The new expiration time of the session.
+ * @return An error if the update fails.
+ */
+func (r *sessionRepository) UpdateSessionActivity(id uint, client models.ClientInfo, lastSeenAt, expiresAt time.Time) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"user_agent":   client.UserAgent,
+		"ip_address":   client.IPAddress,
+		"last_seen_at": lastSeenAt,
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+/**
+ * @brief Updates the last-seen time of a session.
+ *
+ * The session is only written when it was last seen more than the given interval
+ * ago, so authenticated requests do not each cause a database write.
+ *
+ * @param id The ID of the session.
+ * @param userID The ID of the user owning the session.
+ * @param lastSeenAt The time the session was used.
+ * @param interval The minimum time between two updates.
+ * @return An error if the update fails.
+ */
+func (r *sessionRepository) TouchSession(id, userID uint, lastSeenAt time.Time, interval time.Duration) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND last_seen_at < ?", id, userID, lastSeenAt.Add(-interval)).
+		Update("last_seen_at", lastSeenAt).Error
+}
+
+/**
+ * @brief Records that the login of a session has been confirmed with a second factor.
+ *
+ * @param id The ID of the session.
+ * @param userID The ID of the user, which must own the session.
+ * @return An error if the update fails.
+ */
+func (r *sessionRepository) MarkSessionTwoFactor(id, userID uint) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("two_factor", true).Error
+}
+
+/**
+ * @brief Revokes a session.
+ *
+ * @param id The ID of the session.
+ * @param revokedAt The time of the revocation.
+ * @return An error if the update fails.
+ */
+func (r *sessionRepository) RevokeSession(id uint, revokedAt time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", revokedAt).Error
+}
+
+/**
+ * @brief Revokes every session of a user.
+ *
+ * @param userID The ID of the user.
+ * @param revokedAt The time of the revocation.
+ * @return An error if the update fails.
+ */
+func (r *sessionRepository) RevokeUserSessions(userID uint, revokedAt time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+/**
+ * @brief TokenRepository interface defines methods for refresh token and access token revocation database operations.
+ */
+type TokenRepository interface {
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshTokenByHash(hash string) (*models.RefreshToken, error)
+	GetSessionRefreshTokens(sessionID uint) ([]models.RefreshToken, error)
+	MarkRefreshTokenUsed(id uint, usedAt time.Time) (bool, error)
+	RevokeSessionRefreshTokens(sessionID uint, revokedAt time.Time) error
+	RevokeUserRefreshTokens(userID uint, revokedAt time.Time) error
+	RevokeAccessToken(token *models.RevokedToken) error
+	IsAccessTokenRevoked(jti string) (bool, error)
+	DeleteExpiredRevocations(before time.Time) (int64, error)
+}
+
+/**
+ * @brief tokenRepository struct provides the implementation of TokenRepository.
+ */
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+/**
+ * @brief NewTokenRepository creates a new instance of tokenRepository.
+ *
+ * @param db The database connection.
+ * @return A new TokenRepository instance.
+ */
+func NewTokenRepository(db *gorm.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
+
+/**
+ * @brief Stores a new refresh token in the database.
+ *
+ * @param token The refresh token model to be created.
+ * @return An error if the creation fails.
+ */
+func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+/**
+ * @brief Retrieves a refresh token by the hash of its value.
+ *
+ * @param hash The SHA-256 hash of the refresh token.
+ * @return The refresh token model and an error if the retrieval fails.
+ */
+func (r *tokenRepository) GetRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+/**
+ * @brief Retrieves every refresh token of a session.
+ *
+ * @param sessionID The ID of the session.
+ * @return A slice of refresh token models and an error if the retrieval fails.
+ */
+func (r *tokenRepository) GetSessionRefreshTokens(sessionID uint) ([]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	err := r.db.Where("session_id = ?", sessionID).Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+/**
+ * @brief Marks a refresh token as used, unless it was already used.
+ *
+ * The check and the update are performed in a single conditional UPDATE, so a
+ * refresh token can only be exchanged once even under concurrent requests.
+ *
+ * @param id The ID of the refresh token.
+ * @param usedAt The time the token was used.
+ * @return True if the token was marked as used, false if it had already been used, and an error if the update fails.
+ */
+func (r *tokenRepository) MarkRefreshTokenUsed(id uint, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", usedAt)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+/**
+ * @brief Revokes every refresh token of a session.
+ *
+ * @param sessionID The ID of the session.
+ * @param revokedAt The time of the revocation.
+ * @return An error if the update fails.
+ */
+func (r *tokenRepository) RevokeSessionRefreshTokens(sessionID uint, revokedAt time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", revokedAt).Error
+}
+
+/**
+ * @brief Revokes every refresh token of a user.
+ *
+ * @param userID The ID of the user.
+ * @param revokedAt The time of the revocation.
+ * @return An error if the update fails.
+ */
+func (r *tokenRepository) RevokeUserRefreshTokens(userID uint, revokedAt time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", revokedAt).Error
+}
+
+/**
+ * @brief Adds an access token to the revocation list.
+ *
+ * Revoking a token that is already in the list is not an error.
+ *
+ * @param token The revoked token entry to be created.
+ * @return An error if the creation fails.
+ */
+func (r *tokenRepository) RevokeAccessToken(token *models.RevokedToken) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error
+}
+
+/**
+ * @brief Checks whether an access token is in the revocation list.
+ *
+ * @param jti The JWT ID of the access token.
+ * @return True if the token was revoked, and an error if the check fails.
+ */
+func (r *tokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+/**
+ * @brief Removes from the revocation list the tokens that have expired anyway.
+ *
+ * @param before Entries of tokens expiring before this time are deleted.
+ * @return The number of deleted entries and an error if the deletion fails.
+ */
+func (r *tokenRepository) DeleteExpiredRevocations(before time.Time) (int64, error) {
+	result := r.db.Where("expires_at < ?", before).Delete(&models.RevokedToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,225 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief TwoFactorRepository interface defines methods for the database operations of two-factor authentication.
+ */
+type TwoFactorRepository interface {
+	SetPendingTOTPSecret(userID uint, secret string) error
+	EnableTOTP(userID uint, secret string, step int64, enabledAt time.Time, codeHashes []string) (bool, error)
+	DisableTOTP(userID uint) error
+	UseTOTPStep(userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) (bool, error)
+	CreateLoginChallenge(challenge *models.LoginChallenge) error
+	GetLoginChallenge(tokenHash string, now time.Time) (*models.LoginChallenge, error)
+	RecordChallengeFailure(id uint) error
+	DeleteLoginChallenge(id uint) (bool, error)
+}
+
+/**
+ * @brief twoFactorRepository struct provides the implementation of TwoFactorRepository.
+ */
+type twoFactorRepository struct {
+	db *gorm.DB
+}
+
+/**
+ * @brief NewTwoFactorRepository creates a new instance of twoFactorRepository.
+ *
+ * @param db The database connection.
+ * @return A new TwoFactorRepository instance.
+ */
+func NewTwoFactorRepository(db *gorm.DB) TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+/**
+ * @brief Stores the secret of an enrollment that has not been confirmed yet, replacing any previous one.
+ *
+ * @param userID The ID of the user.
+ * @param secret The new secret.
+ * @return An error if the update fails.
+ */
+func (r *twoFactorRepository) SetPendingTOTPSecret(userID uint, secret string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("totp_pending_secret", secret).Error
+}
+
+/**
+ * @brief Turns on two-factor authentication with a confirmed secret and issues the first recovery codes.
+ *
+ * The secret is only enabled if it is still the pending secret of the user, so
+ * that a confirmation racing with a new enrollment cannot enable a stale one.
+ *
+ * @param userID The ID of the user.
+ * @param secret The confirmed secret.
+ * @param step The step of the code that confirmed it, which cannot be used again.
+ * @param enabledAt When two-factor authentication was turned on.
+ * @param codeHashes The hashes of the recovery codes, replacing any previous ones.
+ * @return True if two-factor authentication was turned on, and an error if the update fails.
+ */
+func (r *twoFactorRepository) EnableTOTP(userID uint, secret string, step int64, enabledAt time.Time, codeHashes []string) (bool, error) {
+	enabled := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND totp_pending_secret = ? AND totp_enabled_at IS NULL", userID, secret).
+			Updates(map[string]interface{}{
+				"totp_secret":         secret,
+				"totp_pending_secret": "",
+				"totp_enabled_at":     enabledAt,
+				"totp_last_step":      step,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		enabled = true
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+	return enabled, err
+}
+
+/**
+ * @brief Turns off two-factor authentication, removing the secret and the recovery codes.
+ *
+ * @param userID The ID of the user.
+ * @return An error if the update fails.
+ */
+func (r *twoFactorRepository) DisableTOTP(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":         "",
+			"totp_pending_secret": "",
+			"totp_enabled_at":     nil,
+			"totp_last_step":      0,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.LoginChallenge{}).Error
+	})
+}
+
+/**
+ * @brief Records the step of an accepted code, unless a code of the same or a later step was already accepted.
+ *
+ * @param userID The ID of the user.
+ * @param step The step of the accepted code.
+ * @return True if the code had not been used yet, and an error if the update fails.
+ */
+func (r *twoFactorRepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+/**
+ * @brief Replaces the recovery codes of a user with a new set.
+ *
+ * @param userID The ID of the user.
+ * @param codeHashes The hashes of the new recovery codes.
+ * @return An error if the replacement fails, in which case the previous codes are kept.
+ */
+func (r *twoFactorRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+/**
+ * @brief Removes the recovery codes of a user and stores a new set, within a transaction.
+ *
+ * @param tx The transaction.
+ * @param userID The ID of the user.
+ * @param codeHashes The hashes of the new recovery codes.
+ * @return An error if the replacement fails.
+ */
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+
+	codes := make([]models.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+/**
+ * @brief Marks a recovery code of a user as used, unless it was already used.
+ *
+ * @param userID The ID of the user.
+ * @param codeHash The hash of the recovery code.
+ * @param usedAt When the code was used.
+ * @return True if the code existed and had not been used yet, and an error if the update fails.
+ */
+func (r *twoFactorRepository) UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", usedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+/**
+ * @brief Stores a new login challenge, removing the challenges that have expired.
+ *
+ * @param challenge The login challenge to be created.
+ * @return An error if the creation fails.
+ */
+func (r *twoFactorRepository) CreateLoginChallenge(challenge *models.LoginChallenge) error {
+	if err := r.db.Where("expires_at < ?", challenge.CreatedAt).Delete(&models.LoginChallenge{}).Error; err != nil {
+		return err
+	}
+	return r.db.Create(challenge).Error
+}
+
+/**
+ * @brief Retrieves a login challenge that has not expired by the hash of its token.
+ *
+ * @param tokenHash The hash of the challenge token.
+ * @param now The current time, used to leave out expired challenges.
+ * @return The login challenge and an error if the retrieval fails.
+ */
+func (r *twoFactorRepository) GetLoginChallenge(tokenHash string, now time.Time) (*models.LoginChallenge, error) {
+	var challenge models.LoginChallenge
+	err := r.db.Where("token_hash = ? AND expires_at > ?", tokenHash, now).First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+/**
+ * @brief Counts a wrong code entered for a login challenge.
+ *
+ * @param id The ID of the login challenge.
+ * @return An error if the update fails.
+ */
+func (r *twoFactorRepository) RecordChallengeFailure(id uint) error {
+	return r.db.Model(&models.LoginChallenge{}).Where("id = ?", id).
+		Update("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+/**
+ * @brief Removes a completed login challenge.
+ *
+ * @param id The ID of the login challenge.
+ * @return True if the challenge was still there, so that it is only completed once, and an error if the removal fails.
+ */
+func (r *twoFactorRepository) DeleteLoginChallenge(id uint) (bool, error) {
+	result := r.db.Delete(&models.LoginChallenge{}, id)
+	return result.RowsAffected > 0, result.Error
+}
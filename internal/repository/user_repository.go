@@ -1,23 +1,49 @@
 package repository
 
 import (
+	"strings"
+	"time"
+
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 /**
  * @brief UserRepository interface defines methods for user-related database operations.
  */
 type UserRepository interface {
+	Transaction(fn func(repo UserRepository) error) error
 	CreateUser(user *models.User) error
 	GetUserByEmail(email string) (*models.User, error)
-	UpdateUserToken(user *models.User) error
-	GetUserByToken(token string) (*models.User, error)
-	GetAllUsers() ([]models.User, error)
+	GetUserByID(id uint) (*models.User, error)
+	UpdateUserRole(id uint, role string) error
+	CountUsersByRole(role string) (int64, error)
+	CountUsersByRoleForUpdate(role string) (int64, error)
+	UpdateTokensValidAfter(id uint, validAfter time.Time) error
+	SetEmailVerificationToken(id uint, tokenHash string, expiresAt time.Time) error
+	GetUserByEmailVerificationToken(tokenHash string) (*models.User, error)
+	MarkEmailVerified(id uint, tokenHash string, verifiedAt time.Time) (bool, error)
+	SetPasswordResetToken(id uint, tokenHash string, expiresAt time.Time) error
+	GetUserByPasswordResetToken(tokenHash string) (*models.User, error)
+	ResetPassword(id uint, tokenHash, password string) (bool, error)
+	SearchUsers(filter models.UserFilter) ([]models.User, int64, error)
+	UpdateUserProfile(user *models.User) error
+	SetUserSuspension(id uint, suspendedAt *time.Time, reason string) error
+	UpdatePassword(id uint, password string) error
+	AnonymizeUser(id uint, deletedAt time.Time) error
+	GetUserByIDIncludingDeleted(id uint) (*models.User, error)
+	RestoreUser(id uint) error
+	PurgeDeletedUsers(before time.Time) (purged, anonymized int64, err error)
 	DeleteUserByEmail(email string) error
 	GetDB() *gorm.DB
 }
 
+/**
+ * @brief Escapes the wildcards of LIKE patterns, so that searched text is matched literally.
+ */
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 /**
  * @brief userRepository struct provides the implementation of UserRepository.
  */
@@ -35,6 +61,22 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+/**
+ * @brief Runs the given function inside a database transaction.
+ *
+ * The repository passed to the function is bound to the transaction, so every
+ * operation performed through it is committed or rolled back as a whole. The
+ * transaction is rolled back if the function returns an error.
+ *
+ * @param fn The function to run inside the transaction.
+ * @return The error returned by the function, or an error if the transaction fails.
+ */
+func (r *userRepository) Transaction(fn func(repo UserRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&userRepository{db: tx})
+	})
+}
+
 /**
  * @brief Creates a new user in the database.
  *
@@ -70,24 +112,157 @@ func (r *userRepository) GetUserByEmail(email string) (*models.User, error) {
 }
 
 /**
- * @brief Updates a user's token in the database.
+ * @brief Retrieves a user by their ID.
+ *
+ * @param id The ID of the user.
+ * @return The user model and an error if the retrieval fails.
+ */
+func (r *userRepository) GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+/**
+ * @brief Changes the role of a user.
+ *
+ * @param id The ID of the user.
+ * @param role The new role of the user.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) UpdateUserRole(id uint, role string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+/**
+ * @brief Counts the users that have the given role.
+ *
+ * @param role The role to count.
+ * @return The number of users with the role and an error if the count fails.
+ */
+func (r *userRepository) CountUsersByRole(role string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+/**
+ * @brief Counts the users that have the given role and locks their rows until the end of the transaction.
+ *
+ * Concurrent transactions counting the same role block until this one commits
+ * or rolls back, and then no longer see the users whose role it changed, so
+ * that two of them cannot both act on a count that is about to change.
+ *
+ * @param role The role to count.
+ * @return The number of users with the role and an error if the count fails.
+ */
+func (r *userRepository) CountUsersByRoleForUpdate(role string) (int64, error) {
+	var ids []uint
+	err := r.db.Model(&models.User{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ?", role).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
+
+/**
+ * @brief Sets the time before which the access tokens of a user are no longer accepted.
+ *
+ * @param id The ID of the user.
+ * @param validAfter Access tokens issued before this time, or in the same second, are rejected.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) UpdateTokensValidAfter(id uint, validAfter time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("tokens_valid_after", validAfter).Error
+}
+
+/**
+ * @brief Stores the hash of an email verification token for a user, replacing any previous one.
+ *
+ * @param id The ID of the user.
+ * @param tokenHash The SHA-256 hash of the verification token.
+ * @param expiresAt The time the verification token expires.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) SetEmailVerificationToken(id uint, tokenHash string, expiresAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"email_verification_token_hash": tokenHash,
+		"email_verification_expires_at": expiresAt,
+	}).Error
+}
+
+/**
+ * @brief Retrieves a user by the hash of their pending email verification token.
+ *
+ * @param tokenHash The SHA-256 hash of the verification token.
+ * @return The user model and an error if the retrieval fails.
+ */
+func (r *userRepository) GetUserByEmailVerificationToken(tokenHash string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("email_verification_token_hash = ?", tokenHash).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+/**
+ * @brief Marks the email of a user as verified and consumes their verification token.
+ *
+ * The update only happens if the given verification token is still pending, so
+ * a token can only be used once.
  *
- * @param user The user model with the updated token.
+ * @param id The ID of the user.
+ * @param tokenHash The SHA-256 hash of the verification token being used.
+ * @param verifiedAt The time the email was verified.
+ * @return True if the email was marked as verified, false if the token had already been used, and an error if the update fails.
+ */
+func (r *userRepository) MarkEmailVerified(id uint, tokenHash string, verifiedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND email_verification_token_hash = ?", id, tokenHash).
+		Updates(map[string]interface{}{
+			"email_verified_at":             verifiedAt,
+			"email_verification_token_hash": "",
+			"email_verification_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+/**
+ * @brief Stores the hash of a password reset token for a user, replacing any previous one.
+ *
+ * @param id The ID of the user.
+ * @param tokenHash The SHA-256 hash of the reset token.
+ * @param expiresAt The time the reset token expires.
  * @return An error if the update fails.
  */
-func (r *userRepository) UpdateUserToken(user *models.User) error {
-	return r.db.Save(user).Error
+func (r *userRepository) SetPasswordResetToken(id uint, tokenHash string, expiresAt time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password_reset_token_hash": tokenHash,
+		"password_reset_expires_at": expiresAt,
+	}).Error
 }
 
 /**
- * @brief Retrieves a user by their token.
+ * @brief Retrieves a user by the hash of their pending password reset token.
  *
- * @param token The token of the user.
+ * @param tokenHash The SHA-256 hash of the reset token.
  * @return The user model and an error if the retrieval fails.
  */
-func (r *userRepository) GetUserByToken(token string) (*models.User, error) {
+func (r *userRepository) GetUserByPasswordResetToken(tokenHash string) (*models.User, error) {
 	var user models.User
-	err := r.db.Where("token = ?", token).First(&user).Error
+	err := r.db.Where("password_reset_token_hash = ?", tokenHash).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -95,17 +270,253 @@ func (r *userRepository) GetUserByToken(token string) (*models.User, error) {
 }
 
 /**
- * @brief Retrieves all users from the database.
+ * @brief Replaces the password of a user and consumes their password reset token.
+ *
+ * The update only happens if the given reset token is still pending, so a token
+ * can only be used once even under concurrent requests.
+ *
+ * @param id The ID of the user.
+ * @param tokenHash The SHA-256 hash of the reset token being used.
+ * @param password The bcrypt hash of the new password.
+ * @return True if the password was replaced, false if the token had already been used, and an error if the update fails.
+ */
+func (r *userRepository) ResetPassword(id uint, tokenHash, password string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND password_reset_token_hash = ?", id, tokenHash).
+		Updates(map[string]interface{}{
+			"password":                  password,
+			"password_reset_token_hash": "",
+			"password_reset_expires_at": nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+/**
+ * @brief Retrieves a page of the users matching a filter, oldest first.
+ *
+ * The query is matched case-insensitively against any part of the username or the email.
  *
- * @return A slice of User models and an error if the retrieval fails.
+ * @param filter The text, role and pagination to apply.
+ * @return The requested page of users, the total number of matching users and an error if the retrieval fails.
  */
-func (r *userRepository) GetAllUsers() ([]models.User, error) {
+func (r *userRepository) SearchUsers(filter models.UserFilter) ([]models.User, int64, error) {
+	order := "id"
+	if filter.Deleted {
+		order = "deleted_at DESC, id DESC"
+	}
+
+	matching := func(db *gorm.DB) *gorm.DB {
+		if filter.Deleted {
+			db = db.Unscoped().Where("deleted_at IS NOT NULL")
+		}
+		if filter.Query != "" {
+			pattern := "%" + likeEscaper.Replace(filter.Query) + "%"
+			db = db.Where("username ILIKE ? OR email ILIKE ?", pattern, pattern)
+		}
+		if filter.Role != "" {
+			db = db.Where("role = ?", filter.Role)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.Model(&models.User{}).Scopes(matching).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
 	var users []models.User
-	err := r.db.Find(&users).Error
+	err := r.db.Scopes(matching).
+		Order(order).
+		Offset((filter.Page - 1) * filter.Limit).
+		Limit(filter.Limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+/**
+ * @brief Saves the profile of a user: username, email and the state of the email verification.
+ *
+ * @param user The user with the new profile.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) UpdateUserProfile(user *models.User) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", user.ID).
+		Select("username", "email", "email_verified_at", "email_verification_token_hash", "email_verification_expires_at").
+		Updates(user).Error
+}
+
+/**
+ * @brief Suspends or reactivates a user.
+ *
+ * @param id The ID of the user.
+ * @param suspendedAt When the user was suspended, or nil to reactivate them.
+ * @param reason Why the user was suspended.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) SetUserSuspension(id uint, suspendedAt *time.Time, reason string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"suspended_at":   suspendedAt,
+		"suspend_reason": reason,
+	}).Error
+}
+
+/**
+ * @brief Replaces the password hash of a user.
+ *
+ * @param id The ID of the user.
+ * @param password The new bcrypt password hash.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) UpdatePassword(id uint, password string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("password", password).Error
+}
+
+/**
+ * @brief Returns the columns that erase the personal data of the users they are applied to.
+ *
+ * The username and email are replaced with placeholders derived from the ID, so
+ * that they stay unique, and the password, TOTP secrets and pending tokens are
+ * cleared.
+ *
+ * @param anonymizedAt When the personal data was erased.
+ * @return The columns to update.
+ */
+func anonymizedUserColumns(anonymizedAt time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"username":                      gorm.Expr("'deleted-user-' || id"),
+		"email":                         gorm.Expr("'deleted-user-' || id || '@deleted.invalid'"),
+		"password":                      "",
+		"email_verified_at":             nil,
+		"email_verification_token_hash": "",
+		"email_verification_expires_at": nil,
+		"password_reset_token_hash":     "",
+		"password_reset_expires_at":     nil,
+		"suspend_reason":                "",
+		"totp_secret":                   "",
+		"totp_pending_secret":           "",
+		"totp_enabled_at":               nil,
+		"anonymized_at":                 anonymizedAt,
+	}
+}
+
+/**
+ * @brief Erases the personal data of a user and deletes their account.
+ *
+ * The devices and IP addresses recorded on the sessions of the user are erased
+ * as well, and the identities, recovery codes and login challenges of the user
+ * are removed. The user row itself is only soft deleted, so that their orders keep
+ * pointing at it.
+ *
+ * @param id The ID of the user.
+ * @param deletedAt When the account was deleted.
+ * @return An error if the update fails.
+ */
+func (r *userRepository) AnonymizeUser(id uint, deletedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		columns := anonymizedUserColumns(deletedAt)
+		columns["tokens_valid_after"] = deletedAt
+		columns["deleted_at"] = deletedAt
+
+		if err := tx.Model(&models.User{}).Where("id = ?", id).Updates(columns).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.ExternalIdentity{}, &models.RecoveryCode{}, &models.LoginChallenge{}} {
+			if err := tx.Where("user_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.Session{}).Where("user_id = ?", id).Updates(map[string]interface{}{
+			"user_agent": "",
+			"ip_address": "",
+		}).Error
+	})
+}
+
+/**
+ * @brief Retrieves a user by their ID, whether or not they are deleted.
+ *
+ * @param id The ID of the user.
+ * @return The user model and an error if the retrieval fails.
+ */
+func (r *userRepository) GetUserByIDIncludingDeleted(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.Unscoped().First(&user, id).Error
 	if err != nil {
 		return nil, err
 	}
-	return users, nil
+	return &user, nil
+}
+
+/**
+ * @brief Restores a deleted user.
+ *
+ * @param id The ID of the user.
+ * @return An error if the username or email is in use again or the update fails.
+ */
+func (r *userRepository) RestoreUser(id uint) error {
+	return r.db.Unscoped().Model(&models.User{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+/**
+ * @brief Permanently removes the users deleted before the given time.
+ *
+ * Users without orders are removed together with their sessions and refresh
+ * tokens. Users with orders are kept, so that the orders still point at them for
+ * accounting, but their personal data is erased if it was not already. Either
+ * way, their identities, recovery codes and login challenges are removed.
+ *
+ * @param before Users deleted before this time are purged.
+ * @return The number of removed users, the number of anonymized users and an error if the purge fails.
+ */
+func (r *userRepository) PurgeDeletedUsers(before time.Time) (purged, anonymized int64, err error) {
+	err = r.db.Transaction(func(tx *gorm.DB) error {
+		withoutOrders := tx.Unscoped().Model(&models.User{}).Select("id").
+			Where("deleted_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id)")
+		deleted := tx.Unscoped().Model(&models.User{}).Select("id").Where("deleted_at < ?", before)
+
+		for _, model := range []interface{}{&models.ExternalIdentity{}, &models.RecoveryCode{}, &models.LoginChallenge{}} {
+			if err := tx.Where("user_id IN (?)", deleted).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Unscoped().Where("user_id IN (?)", withoutOrders).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN (?)", withoutOrders).Delete(&models.Session{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Unscoped().
+			Where("deleted_at < ?", before).
+			Where("NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id)").
+			Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+
+		result = tx.Unscoped().Model(&models.User{}).
+			Where("deleted_at < ? AND anonymized_at IS NULL", before).
+			Updates(anonymizedUserColumns(time.Now()))
+		if result.Error != nil {
+			return result.Error
+		}
+		anonymized = result.RowsAffected
+
+		return tx.Model(&models.Session{}).
+			Where("user_id IN (?)", deleted).
+			Updates(map[string]interface{}{"user_agent": "", "ip_address": ""}).Error
+	})
+	return purged, anonymized, err
 }
 
 /**
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apikey"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+)
+
+/**
+ * @brief Returned when an API key does not exist, belongs to another user or has already been revoked.
+ */
+var ErrAPIKeyNotFound = apperror.NotFound("api_key_not_found", "API key not found")
+
+/**
+ * @brief Returned when a user already has as many active API keys as allowed.
+ */
+var ErrTooManyAPIKeys = apperror.Conflict("too_many_api_keys", "revoke an API key before creating a new one")
+
+/**
+ * @brief Maximum number of API keys a user can have that are neither revoked nor expired.
+ */
+const maxAPIKeysPerUser = 10
+
+/**
+ * @interface APIKeyService
+ * @brief Interface for API key-related services.
+ *
+ * This interface defines methods for managing the API keys scripts use to call
+ * the API on behalf of a user.
+ */
+type APIKeyService interface {
+	CreateAPIKey(userID uint, request *models.CreateAPIKeyRequest) (*models.APIKey, string, error)
+	GetAPIKeys(userID uint) ([]models.APIKey, error)
+	RevokeAPIKey(userID uint, id string) error
+}
+
+/**
+ * @struct apiKeyService
+ * @brief Implementation of the APIKeyService interface.
+ *
+ * This struct implements the `APIKeyService` interface, storing the keys with the
+ * API key repository and recording their creation and revocation in the audit log.
+ */
+type apiKeyService struct {
+	apiKeyRepository repository.APIKeyRepository
+	auditRepository  repository.AuditRepository
+}
+
+/**
+ * @brief Creates a new APIKeyService instance.
+ *
+ * @param apiKeyRepo The API key repository to use for database operations.
+ * @param auditRepo The audit repository recording the creation and revocation of keys.
+ * @return A new APIKeyService instance.
+ */
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, auditRepo repository.AuditRepository) APIKeyService {
+	return &apiKeyService{apiKeyRepository: apiKeyRepo, auditRepository: auditRepo}
+}
+
+/**
+ * @brief Creates a new API key for a user.
+ *
+ * Only the prefix and the hash of the key are stored, so the key is returned
+ * once and cannot be retrieved again. Duplicated scopes are granted once.
+ *
+ * @param userID The ID of the user owning the key.
+ * @param request The name, scopes and lifetime of the key.
+ * @return The stored key, the key itself and an error if the user has too many keys or the creation fails.
+ */
+func (s *apiKeyService) CreateAPIKey(userID uint, request *models.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	generated, err := apikey.Generate()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var scopes []string
+	granted := make(map[string]bool)
+	for _, scope := range request.Scopes {
+		if !granted[scope] {
+			granted[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	now := time.Now()
+	key := &models.APIKey{
+		UserID:  userID,
+		Name:    request.Name,
+		Prefix:  generated.Prefix,
+		KeyHash: generated.Hash,
+		Scopes:  strings.Join(scopes, " "),
+	}
+	if request.ExpiresInDays > 0 {
+		expiresAt := now.AddDate(0, 0, request.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	created, err := s.apiKeyRepository.CreateAPIKey(key, maxAPIKeysPerUser, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if !created {
+		return nil, "", ErrTooManyAPIKeys
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAPIKeyCreated,
+		UserID:  &userID,
+		ActorID: &userID,
+		Detail:  key.Prefix + " (" + key.Scopes + ")",
+	})
+	return key, generated.Key, nil
+}
+
+/**
+ * @brief Retrieves the API keys of a user that have not been revoked, newest first.
+ *
+ * @param userID The ID of the user.
+ * @return A slice of API key models and an error if the retrieval fails.
+ */
+func (s *apiKeyService) GetAPIKeys(userID uint) ([]models.APIKey, error) {
+	return s.apiKeyRepository.GetUserAPIKeys(userID)
+}
+
+/**
+ * @brief Revokes an API key of a user, which can no longer be used.
+ *
+ * @param userID The ID of the user owning the key.
+ * @param id The ID of the key.
+ * @return An error if the key does not exist, belongs to another user, is already revoked or the revocation fails.
+ */
+func (s *apiKeyService) RevokeAPIKey(userID uint, id string) error {
+	keyID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ErrAPIKeyNotFound
+	}
+
+	revoked, err := s.apiKeyRepository.RevokeAPIKey(uint(keyID), userID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAPIKeyRevoked,
+		UserID:  &userID,
+		ActorID: &userID,
+		Detail:  "API key " + id,
+	})
+	return nil
+}
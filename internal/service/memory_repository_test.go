@@ -0,0 +1,493 @@
+package service
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/lockout"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/mailer"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/sso"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+/**
+ * @struct memoryStore
+ * @brief The rows shared by the in-memory repositories the user service is tested against.
+ *
+ * Only the methods the tests reach are implemented; any other one panics on
+ * the nil repository interface embedded in each repository.
+ */
+type memoryStore struct {
+	mu            sync.Mutex
+	nextID        uint
+	users         []*models.User
+	identities    []*models.ExternalIdentity
+	logins        map[string]*models.ProviderLogin
+	sessions      []*models.Session
+	refreshTokens []*models.RefreshToken
+	revokedTokens map[string]models.RevokedToken
+	apiKeys       []*models.APIKey
+	recoveryCodes []*models.RecoveryCode
+	audit         []models.AuditEntry
+}
+
+/**
+ * @brief Creates an empty store.
+ *
+ * @return The store.
+ */
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		logins:        make(map[string]*models.ProviderLogin),
+		revokedTokens: make(map[string]models.RevokedToken),
+	}
+}
+
+func (s *memoryStore) id() uint {
+	s.nextID++
+	return s.nextID
+}
+
+func (s *memoryStore) addUser(user *models.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user.ID = s.id()
+	s.users = append(s.users, user)
+}
+
+func (s *memoryStore) addAPIKey(key *models.APIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key.ID = s.id()
+	s.apiKeys = append(s.apiKeys, key)
+}
+
+/**
+ * @brief Builds a user service on top of in-memory repositories sharing a store.
+ *
+ * A fresh signing key ring is installed for the access tokens it issues.
+ *
+ * @param t The test.
+ * @param store The store of the repositories.
+ * @param provider The identity provider, or nil if logins with a provider are disabled.
+ * @return The service.
+ */
+func newMemoryUserService(t *testing.T, store *memoryStore, provider sso.Provider) UserService {
+	t.Helper()
+
+	keyRing, err := middleware.NewEphemeralKeyRing()
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	middleware.SetKeyRing(keyRing)
+
+	return NewUserService(
+		&memoryUserRepository{store: store},
+		&memoryTokenRepository{store: store},
+		&memorySessionRepository{store: store},
+		&memoryAuditRepository{store: store},
+		&memoryIdentityRepository{store: store},
+		&memoryTwoFactorRepository{store: store},
+		&memoryAPIKeyRepository{store: store},
+		lockout.NewGuard(lockout.NewMemoryStore(), lockout.Config{
+			AccountThreshold: 5,
+			IPThreshold:      20,
+			Window:           15 * time.Minute,
+			BaseDelay:        time.Second,
+			MaxDelay:         time.Minute,
+			LockoutDuration:  15 * time.Minute,
+		}),
+		provider,
+		mailer.NewLogMailer(os.DevNull),
+		TokenConfig{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, EmailVerificationTTL: time.Hour},
+	)
+}
+
+type memoryUserRepository struct {
+	repository.UserRepository
+	store *memoryStore
+}
+
+func (r *memoryUserRepository) GetUserByEmail(email string) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, user := range r.store.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) GetUserByID(id uint) (*models.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, user := range r.store.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) UpdatePassword(id uint, password string) error {
+	user, err := r.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	user.Password = password
+	return nil
+}
+
+func (r *memoryUserRepository) UpdateTokensValidAfter(id uint, validAfter time.Time) error {
+	user, err := r.GetUserByID(id)
+	if err != nil {
+		return err
+	}
+	user.TokensValidAfter = &validAfter
+	return nil
+}
+
+type memoryIdentityRepository struct {
+	store *memoryStore
+}
+
+func (r *memoryIdentityRepository) CreateProviderLogin(login *models.ProviderLogin) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.logins[login.StateHash] = login
+	return nil
+}
+
+func (r *memoryIdentityRepository) ConsumeProviderLogin(stateHash string, now time.Time) (*models.ProviderLogin, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	login, ok := r.store.logins[stateHash]
+	if !ok || !login.ExpiresAt.After(now) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	delete(r.store.logins, stateHash)
+	return login, nil
+}
+
+func (r *memoryIdentityRepository) GetExternalIdentity(issuer, subject string) (*models.ExternalIdentity, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, identity := range r.store.identities {
+		if identity.Issuer == issuer && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryIdentityRepository) CreateExternalIdentity(identity *models.ExternalIdentity) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	identity.ID = r.store.id()
+	r.store.identities = append(r.store.identities, identity)
+	return nil
+}
+
+/**
+ * @brief Creates a user and links the identity to it, failing like the unique indexes of the database do.
+ */
+func (r *memoryIdentityRepository) CreateUserWithIdentity(user *models.User, identity *models.ExternalIdentity) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return &pgconn.PgError{Code: uniqueViolation, ConstraintName: "idx_users_email_active"}
+		}
+		if existing.Username == user.Username {
+			return &pgconn.PgError{Code: uniqueViolation, ConstraintName: "idx_users_username_active"}
+		}
+	}
+	user.ID = r.store.id()
+	r.store.users = append(r.store.users, user)
+	identity.ID = r.store.id()
+	identity.UserID = user.ID
+	r.store.identities = append(r.store.identities, identity)
+	return nil
+}
+
+type memorySessionRepository struct {
+	repository.SessionRepository
+	store *memoryStore
+}
+
+func (r *memorySessionRepository) CreateSession(session *models.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	session.ID = r.store.id()
+	r.store.sessions = append(r.store.sessions, session)
+	return nil
+}
+
+func (r *memorySessionRepository) GetSessionByID(id uint) (*models.Session, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, session := range r.store.sessions {
+		if session.ID == id {
+			return session, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memorySessionRepository) UpdateSessionActivity(id uint, client models.ClientInfo, lastSeenAt, expiresAt time.Time) error {
+	session, err := r.GetSessionByID(id)
+	if err != nil {
+		return err
+	}
+	session.UserAgent = client.UserAgent
+	session.IPAddress = client.IPAddress
+	session.LastSeenAt = lastSeenAt
+	session.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeSession(id uint, revokedAt time.Time) error {
+	session, err := r.GetSessionByID(id)
+	if err != nil {
+		return err
+	}
+	session.RevokedAt = &revokedAt
+	return nil
+}
+
+func (r *memorySessionRepository) RevokeUserSessions(userID uint, revokedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+type memoryTokenRepository struct {
+	repository.TokenRepository
+	store *memoryStore
+}
+
+func (r *memoryTokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	token.ID = r.store.id()
+	r.store.refreshTokens = append(r.store.refreshTokens, token)
+	return nil
+}
+
+func (r *memoryTokenRepository) GetRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, token := range r.store.refreshTokens {
+		if token.TokenHash == hash {
+			return token, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryTokenRepository) GetSessionRefreshTokens(sessionID uint) ([]models.RefreshToken, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	var tokens []models.RefreshToken
+	for _, token := range r.store.refreshTokens {
+		if token.SessionID == sessionID {
+			tokens = append(tokens, *token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *memoryTokenRepository) MarkRefreshTokenUsed(id uint, usedAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, token := range r.store.refreshTokens {
+		if token.ID == id && token.UsedAt == nil {
+			token.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryTokenRepository) RevokeSessionRefreshTokens(sessionID uint, revokedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, token := range r.store.refreshTokens {
+		if token.SessionID == sessionID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeUserRefreshTokens(userID uint, revokedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, token := range r.store.refreshTokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepository) RevokeAccessToken(token *models.RevokedToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.revokedTokens[token.JTI] = *token
+	return nil
+}
+
+func (r *memoryTokenRepository) IsAccessTokenRevoked(jti string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	_, revoked := r.store.revokedTokens[jti]
+	return revoked, nil
+}
+
+type memoryAPIKeyRepository struct {
+	repository.APIKeyRepository
+	store *memoryStore
+}
+
+func (r *memoryAPIKeyRepository) RevokeUserAPIKeys(userID uint, revokedAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, key := range r.store.apiKeys {
+		if key.UserID == userID && key.RevokedAt == nil {
+			key.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+type memoryTwoFactorRepository struct {
+	repository.TwoFactorRepository
+	store *memoryStore
+}
+
+func (r *memoryTwoFactorRepository) UseTOTPStep(userID uint, step int64) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, user := range r.store.users {
+		if user.ID == userID && user.TOTPLastStep < step {
+			user.TOTPLastStep = step
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryTwoFactorRepository) UseRecoveryCode(userID uint, codeHash string, usedAt time.Time) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	for _, code := range r.store.recoveryCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &usedAt
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryAuditRepository struct {
+	repository.AuditRepository
+	store *memoryStore
+}
+
+func (r *memoryAuditRepository) CreateAuditEntry(entry *models.AuditEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.audit = append(r.store.audit, *entry)
+	return nil
+}
+
+/**
+ * @struct memoryOrderRepository
+ * @brief In-memory order repository the order service is tested against.
+ *
+ * Transactions run directly against the repository, without rolling back.
+ */
+type memoryOrderRepository struct {
+	repository.OrderRepository
+	orders  map[uint]*models.Order
+	stock   map[uint]int
+	history []models.OrderStatusHistory
+	filter  models.OrderFilter
+}
+
+/**
+ * @brief Creates a repository holding the given orders and the stock of their offers.
+ *
+ * @param stock The quantity of each offer, by ID.
+ * @param orders The orders.
+ * @return The repository.
+ */
+func newMemoryOrderRepository(stock map[uint]int, orders ...*models.Order) *memoryOrderRepository {
+	r := &memoryOrderRepository{orders: make(map[uint]*models.Order), stock: stock}
+	for _, order := range orders {
+		r.orders[order.ID] = order
+	}
+	return r
+}
+
+func (r *memoryOrderRepository) Transaction(fn func(repo repository.OrderRepository) error) error {
+	return fn(r)
+}
+
+func (r *memoryOrderRepository) GetOrderForUpdate(id uint) (*models.Order, error) {
+	order, ok := r.orders[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	// Like a row read from the database, the copy is not affected by later updates.
+	read := *order
+	return &read, nil
+}
+
+func (r *memoryOrderRepository) UpdateOrderStatus(id uint, status string) error {
+	r.orders[id].Status = status
+	return nil
+}
+
+func (r *memoryOrderRepository) CancelOrder(id uint, cancelledBy uint, reason string, cancelledAt time.Time) error {
+	order := r.orders[id]
+	order.Status = models.OrderStatusCancelled
+	order.CancelledBy = &cancelledBy
+	order.CancelledAt = &cancelledAt
+	order.CancelReason = reason
+	return nil
+}
+
+func (r *memoryOrderRepository) IncrementOfferQuantity(id uint, quantity int) error {
+	r.stock[id] += quantity
+	return nil
+}
+
+func (r *memoryOrderRepository) CreateStatusHistory(entry *models.OrderStatusHistory) error {
+	r.history = append(r.history, *entry)
+	return nil
+}
+
+func (r *memoryOrderRepository) GetOrdersByUser(userID uint, filter models.OrderFilter) ([]models.Order, int64, error) {
+	r.filter = filter
+	var orders []models.Order
+	for _, order := range r.orders {
+		if order.UserID == userID {
+			orders = append(orders, *order)
+		}
+	}
+	return orders, int64(len(orders)), nil
+}
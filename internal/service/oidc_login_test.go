@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/sso"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const testClientID = "newworld-test"
+
+/**
+ * @struct stubGrant
+ * @brief An authorization code issued by the stub issuer, with what the token request has to match.
+ */
+type stubGrant struct {
+	challenge string
+	nonce     string
+	claims    jwt.MapClaims
+}
+
+/**
+ * @struct stubIssuer
+ * @brief An OpenID Connect provider serving discovery, JWKS and token endpoints over httptest.
+ *
+ * The tests play the part of the browser: they read the challenge and nonce
+ * off the authorization URL and grant a code for them, or for other values to
+ * simulate a tampered login.
+ */
+type stubIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu     sync.Mutex
+	grants map[string]stubGrant
+}
+
+/**
+ * @brief Starts a stub issuer that is shut down at the end of the test.
+ *
+ * @param t The test.
+ * @return The stub issuer.
+ */
+func newStubIssuer(t *testing.T) *stubIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate issuer key: %v", err)
+	}
+	issuer := &stubIssuer{key: key, grants: make(map[string]stubGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/jwks", issuer.jwks)
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *stubIssuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                i.server.URL,
+		"authorization_endpoint":                i.server.URL + "/authorize",
+		"token_endpoint":                        i.server.URL + "/token",
+		"jwks_uri":                              i.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *stubIssuer) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": "test",
+			"n":   encode(i.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(i.key.E)).Bytes()),
+		}},
+	})
+}
+
+/**
+ * @brief Exchanges a code for an ID token, like a provider enforcing PKCE does.
+ */
+func (i *stubIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	i.mu.Lock()
+	grant, ok := i.grants[r.PostForm.Get("code")]
+	delete(i.grants, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	if !ok || sso.Challenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":   i.server.URL,
+		"aud":   testClientID,
+		"nonce": grant.nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Minute).Unix(),
+	}
+	for name, value := range grant.claims {
+		claims[name] = value
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "test"
+	signed, err := idToken.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "stub-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+/**
+ * @brief Grants a code for a login, as the provider does once the user has logged in there.
+ *
+ * @param challenge The PKCE challenge the code is bound to.
+ * @param nonce The nonce put in the ID token.
+ * @param claims The claims of the user put in the ID token.
+ * @return The authorization code.
+ */
+func (i *stubIssuer) grant(challenge, nonce string, claims jwt.MapClaims) string {
+	code, _ := sso.RandomValue()
+	i.mu.Lock()
+	i.grants[code] = stubGrant{challenge: challenge, nonce: nonce, claims: claims}
+	i.mu.Unlock()
+	return code
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+/**
+ * @brief Builds a user service logging in with the stub issuer on top of in-memory repositories.
+ *
+ * @param t The test.
+ * @param issuer The stub issuer.
+ * @return The service and the store of its repositories.
+ */
+func newOIDCTestService(t *testing.T, issuer *stubIssuer) (UserService, *memoryStore) {
+	t.Helper()
+
+	provider, err := sso.NewProvider(context.Background(), sso.Config{
+		IssuerURL:   issuer.server.URL,
+		ClientID:    testClientID,
+		RedirectURL: "http://localhost/auth/oidc/callback",
+	})
+	if err != nil {
+		t.Fatalf("failed to discover the stub issuer: %v", err)
+	}
+
+	store := newMemoryStore()
+	return newMemoryUserService(t, store, provider), store
+}
+
+/**
+ * @brief Starts a login and reads the state, nonce and PKCE challenge off the authorization URL.
+ *
+ * @param t The test.
+ * @param userService The service.
+ * @return The query of the authorization URL.
+ */
+func startLogin(t *testing.T, userService UserService) url.Values {
+	t.Helper()
+
+	authURL, err := userService.StartOIDCLogin()
+	if err != nil {
+		t.Fatalf("failed to start login: %v", err)
+	}
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("invalid authorization URL %q: %v", authURL, err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("client_id") != testClientID {
+		t.Fatalf("unexpected authorization URL %q", authURL)
+	}
+	return query
+}
+
+/**
+ * @brief Checks that a callback with an unknown or already used state is refused.
+ */
+func TestCompleteOIDCLoginRejectsMismatchedState(t *testing.T) {
+	issuer := newStubIssuer(t)
+	userService, _ := newOIDCTestService(t, issuer)
+
+	query := startLogin(t, userService)
+	code := issuer.grant(query.Get("code_challenge"), query.Get("nonce"), jwt.MapClaims{"sub": "state", "email": "state@example.com", "email_verified": true})
+
+	if _, err := userService.CompleteOIDCLogin("forged", code, models.ClientInfo{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("expected ErrInvalidOIDCState for an unknown state, got %v", err)
+	}
+
+	if _, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{}); err != nil {
+		t.Fatalf("expected the login to complete, got %v", err)
+	}
+	if _, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{}); !errors.Is(err, ErrInvalidOIDCState) {
+		t.Fatalf("expected ErrInvalidOIDCState for a state used twice, got %v", err)
+	}
+}
+
+/**
+ * @brief Checks that an ID token carrying another nonce than the login is refused.
+ */
+func TestCompleteOIDCLoginRejectsMismatchedNonce(t *testing.T) {
+	issuer := newStubIssuer(t)
+	userService, store := newOIDCTestService(t, issuer)
+
+	query := startLogin(t, userService)
+	code := issuer.grant(query.Get("code_challenge"), "replayed", jwt.MapClaims{"sub": "nonce", "email": "nonce@example.com", "email_verified": true})
+
+	if _, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{}); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Fatalf("expected ErrOIDCLoginFailed, got %v", err)
+	}
+	if len(store.users) != 0 {
+		t.Fatalf("expected no user to be created, got %d", len(store.users))
+	}
+}
+
+/**
+ * @brief Checks that a code bound to another PKCE challenge cannot be exchanged.
+ */
+func TestCompleteOIDCLoginRejectsMismatchedVerifier(t *testing.T) {
+	issuer := newStubIssuer(t)
+	userService, store := newOIDCTestService(t, issuer)
+
+	query := startLogin(t, userService)
+	code := issuer.grant(sso.Challenge("another verifier"), query.Get("nonce"), jwt.MapClaims{"sub": "pkce", "email": "pkce@example.com", "email_verified": true})
+
+	if _, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{}); !errors.Is(err, ErrOIDCLoginFailed) {
+		t.Fatalf("expected ErrOIDCLoginFailed, got %v", err)
+	}
+	if len(store.users) != 0 {
+		t.Fatalf("expected no user to be created, got %d", len(store.users))
+	}
+}
+
+/**
+ * @brief Checks that an identity with a verified email is linked to the user registered with it.
+ */
+func TestCompleteOIDCLoginLinksUserWithVerifiedEmail(t *testing.T) {
+	issuer := newStubIssuer(t)
+	userService, store := newOIDCTestService(t, issuer)
+
+	now := time.Now()
+	existing := &models.User{Username: "bob", Email: "bob@example.com", Role: models.RoleBuyer, EmailVerifiedAt: &now}
+	store.addUser(existing)
+
+	query := startLogin(t, userService)
+	code := issuer.grant(query.Get("code_challenge"), query.Get("nonce"), jwt.MapClaims{"sub": "bob-at-provider", "email": "bob@example.com", "email_verified": true})
+
+	tokens, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{IPAddress: "192.0.2.1"})
+	if err != nil {
+		t.Fatalf("expected the login to complete, got %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected a session to be started, got %+v", tokens)
+	}
+
+	if len(store.users) != 1 {
+		t.Fatalf("expected no new user, got %d users", len(store.users))
+	}
+	if len(store.identities) != 1 || store.identities[0].UserID != existing.ID || store.identities[0].Subject != "bob-at-provider" || store.identities[0].Issuer != issuer.server.URL {
+		t.Fatalf("expected the identity to be linked to user %d, got %+v", existing.ID, store.identities)
+	}
+	if len(store.audit) != 1 || store.audit[0].Action != models.AuditIdentityLinked || *store.audit[0].UserID != existing.ID {
+		t.Fatalf("expected the link to be audited, got %+v", store.audit)
+	}
+}
+
+/**
+ * @brief Checks that an identity with an unverified email is not linked to the user registered with it.
+ */
+func TestCompleteOIDCLoginRefusesToLinkUnverifiedEmail(t *testing.T) {
+	issuer := newStubIssuer(t)
+	userService, store := newOIDCTestService(t, issuer)
+
+	store.addUser(&models.User{Username: "carol", Email: "carol@example.com", Role: models.RoleBuyer})
+
+	query := startLogin(t, userService)
+	code := issuer.grant(query.Get("code_challenge"), query.Get("nonce"), jwt.MapClaims{"sub": "carol-at-provider", "email": "carol@example.com", "email_verified": false})
+
+	if _, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{}); !errors.Is(err, ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+	if len(store.identities) != 0 || len(store.users) != 1 {
+		t.Fatalf("expected nothing to be linked or created, got %d identities and %d users", len(store.identities), len(store.users))
+	}
+}
+
+/**
+ * @brief Checks that a new user gets a suffixed username when the one of the profile is taken.
+ */
+func TestCompleteOIDCLoginCreatesUserWhenUsernameIsTaken(t *testing.T) {
+	issuer := newStubIssuer(t)
+	userService, store := newOIDCTestService(t, issuer)
+
+	store.addUser(&models.User{Username: "dave", Email: "dave@example.com", Role: models.RoleBuyer})
+
+	query := startLogin(t, userService)
+	code := issuer.grant(query.Get("code_challenge"), query.Get("nonce"), jwt.MapClaims{"sub": "dave-at-provider", "email": "dave@example.org", "email_verified": true, "preferred_username": "dave"})
+
+	if _, err := userService.CompleteOIDCLogin(query.Get("state"), code, models.ClientInfo{}); err != nil {
+		t.Fatalf("expected the login to complete, got %v", err)
+	}
+
+	if len(store.users) != 2 {
+		t.Fatalf("expected a new user, got %d users", len(store.users))
+	}
+	created := store.users[1]
+	if created.Email != "dave@example.org" || created.Role != models.RoleBuyer || created.EmailVerifiedAt == nil {
+		t.Fatalf("unexpected new user %+v", created)
+	}
+	if !strings.HasPrefix(created.Username, "dave-") || created.Username == "dave" {
+		t.Fatalf("expected a suffixed username, got %q", created.Username)
+	}
+	if len(store.identities) != 1 || store.identities[0].UserID != created.ID {
+		t.Fatalf("expected the identity to be linked to the new user, got %+v", store.identities)
+	}
+}
@@ -1,24 +1,76 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"time"
 
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"gorm.io/gorm"
 )
 
+/**
+ * @brief Returned when an order does not exist or does not belong to the requesting buyer.
+ */
+var ErrOrderNotFound = apperror.NotFound("order_not_found", "order not found")
+
+/**
+ * @brief Returned when an order can no longer be cancelled by the requesting user.
+ */
+var ErrOrderNotCancellable = apperror.Conflict("order_not_cancellable", "order cannot be cancelled")
+
+/**
+ * @brief Returned when the requested status is not part of the order lifecycle.
+ */
+var ErrInvalidOrderStatus = apperror.Invalid("invalid_order_status", "invalid order status")
+
+/**
+ * @brief Returned when an order cannot move from its current status to the requested one.
+ */
+var ErrInvalidStatusTransition = apperror.Conflict("invalid_status_transition", "invalid order status transition")
+
+/**
+ * @brief Number of orders returned per page when listing orders, and the maximum that can be requested.
+ */
+const (
+	defaultOrderPageSize = 20
+	maxOrderPageSize     = 100
+)
+
+/**
+ * @brief Order lifecycle, mapping each status to the statuses it can move to.
+ *
+ * Orders move forward from pending to delivered and can be cancelled until
+ * they are shipped. Delivered and cancelled orders are final.
+ */
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:    {models.OrderStatusPreparing, models.OrderStatusCancelled},
+	models.OrderStatusPreparing:  {models.OrderStatusProcessing, models.OrderStatusCancelled},
+	models.OrderStatusProcessing: {models.OrderStatusShipped, models.OrderStatusCancelled},
+	models.OrderStatusShipped:    {models.OrderStatusDelivered},
+	models.OrderStatusDelivered:  {},
+	models.OrderStatusCancelled:  {},
+}
+
 /**
  * @interface OrderService
  * @brief Interface for order-related services.
  *
- * This interface defines methods for managing orders, including checking out, retrieving order status, updating order status, and getting admin dashboard data.
+ * This interface defines methods for managing orders, including checking out, retrieving order details, listing the orders of a buyer, updating order status, cancelling orders, retrieving the status history, and getting admin dashboard data.
  */
 type OrderService interface {
-	Checkout(order *models.CheckoutRequest) (uint, int, error)
-	GetOrderStatus(id string) (string, error)
-	UpdateOrderStatus(id string, status *models.OrderStatusUpdateRequest) (string, error)
-	GetAdminDashboard() (models.AdminDashboardResponse, []models.Offer, []models.Order, error)
+	Checkout(userID uint, order *models.CheckoutRequest) (uint, int, error)
+	GetOrder(id string, userID uint, isAdmin bool) (*models.OrderDetail, error)
+	GetUserOrders(userID uint, filter *models.OrderFilter) ([]models.OrderSummary, int64, error)
+	UpdateOrderStatus(id string, userID uint, status *models.OrderStatusUpdateRequest) (string, error)
+	CancelOrder(id string, userID uint, isAdmin bool, reason string) error
+	GetOrderStatusHistory(id string) ([]models.OrderStatusHistory, error)
+	GetAdminDashboard() (models.AdminDashboardResponse, []models.Offer, []models.OrderSummary, error)
+	GetUserOrderStats(userID uint) (*models.UserOrderStats, error)
 }
 
 /**
@@ -44,81 +96,255 @@ func NewOrderService(orderRepo repository.OrderRepository) OrderService {
 /**
  * @brief Processes the checkout of an order.
  *
+ * Stock checks, stock decrements and the order insertion run in a single
+ * transaction. Offer rows are locked in ascending product ID order so that
+ * concurrent checkouts cannot oversell an offer or deadlock each other, and
+ * any failure leaves the stock untouched.
+ *
+ * @param userID The ID of the buyer placing the order.
  * @param checkout The checkout request containing the order items.
  * @return The order ID, total amount, and an error if the checkout fails.
  */
-func (s *orderService) Checkout(checkout *models.CheckoutRequest) (uint, int, error) {
-	var total int
-	neworder := models.Order{Status: "pending", Total: 0, OrderItems: []models.OrderItem{}}
+func (s *orderService) Checkout(userID uint, checkout *models.CheckoutRequest) (uint, int, error) {
+	items := make([]models.OrderItemRequest, len(checkout.OrderItems))
+	copy(items, checkout.OrderItems)
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].ProductID < items[j].ProductID
+	})
 
-	for _, item := range checkout.OrderItems {
+	for _, item := range items {
 		if item.Quantity <= 0 {
-			return 0, 0, fmt.Errorf("invalid quantity for product %d: must be greater than zero", item.ProductID)
-		}
-		exists, err := s.orderRepository.ExistsOffer(item.ProductID)
-		if err != nil || !exists {
-			return 0, 0, fmt.Errorf("product %d does not exist", item.ProductID)
+			return 0, 0, apperror.Invalid("invalid_quantity", fmt.Sprintf("invalid quantity for product %d: must be greater than zero", item.ProductID))
 		}
+	}
 
-		quantity, err := s.orderRepository.GetOfferQuantity(item.ProductID)
-		if err != nil || quantity < item.Quantity {
-			return 0, 0, fmt.Errorf("product %d not available in the requested quantity", item.ProductID)
-		}
+	neworder := models.Order{UserID: userID, Status: models.OrderStatusPending, Total: 0, OrderItems: []models.OrderItem{}}
 
-		price, err := s.orderRepository.GetOfferPrice(item.ProductID)
-		if err != nil {
-			return 0, 0, fmt.Errorf("failed to get price for product %d", item.ProductID)
-		}
+	err := s.orderRepository.Transaction(func(repo repository.OrderRepository) error {
+		var total int
 
-		newQuantity := quantity - item.Quantity
-		if err := s.orderRepository.UpdateOfferQuantity(item.ProductID, newQuantity); err != nil {
-			return 0, 0, fmt.Errorf("failed to update quantity for product %d", item.ProductID)
-		}
+		for _, item := range items {
+			offer, err := repo.GetOfferForUpdate(item.ProductID)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apperror.NotFound("offer_not_found", fmt.Sprintf("product %d does not exist", item.ProductID))
+			}
+			if err != nil {
+				return err
+			}
 
-		total += item.Quantity * price
+			decremented, err := repo.DecrementOfferQuantity(item.ProductID, item.Quantity)
+			if err != nil {
+				return fmt.Errorf("failed to update quantity for product %d: %w", item.ProductID, err)
+			}
+			if !decremented {
+				return apperror.InsufficientStock("insufficient_stock", fmt.Sprintf("product %d not available in the requested quantity", item.ProductID))
+			}
 
-		newItem := models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     price,
+			total += item.Quantity * offer.Price
+
+			newItem := models.OrderItem{
+				ProductID: item.ProductID,
+				Quantity:  item.Quantity,
+				Price:     offer.Price,
+			}
+			neworder.OrderItems = append(neworder.OrderItems, newItem)
+		}
+
+		neworder.Total = total
+		if err := repo.CreateOrder(&neworder); err != nil {
+			return err
 		}
-		neworder.OrderItems = append(neworder.OrderItems, newItem)
-	}
 
-	neworder.Total = total
-	err := s.orderRepository.CreateOrder(&neworder)
+		return repo.CreateStatusHistory(&models.OrderStatusHistory{
+			OrderID:   neworder.ID,
+			ToStatus:  models.OrderStatusPending,
+			ChangedBy: userID,
+		})
+	})
 	if err != nil {
 		return 0, 0, err
 	}
-	return neworder.ID, total, nil
+	return neworder.ID, neworder.Total, nil
 }
 
 /**
- * @brief Retrieves the status of an order by its ID.
+ * @brief Retrieves the details of an order by its ID, including its items and status history.
+ *
+ * Buyers can only query their own orders; orders placed by someone else are
+ * reported as not found. Admins can query any order.
  *
  * @param id The order ID.
- * @return The status of the order and an error if the retrieval fails.
+ * @param userID The ID of the user requesting the order.
+ * @param isAdmin Whether the requesting user is an admin.
+ * @return The details of the order and an error if the retrieval fails.
  */
-func (s *orderService) GetOrderStatus(id string) (string, error) {
-	orderID, _ := strconv.ParseUint(id, 10, 64)
-	order, err := s.orderRepository.GetOrderById(uint(orderID))
+func (s *orderService) GetOrder(id string, userID uint, isAdmin bool) (*models.OrderDetail, error) {
+	orderID, err := strconv.ParseUint(id, 10, 64)
 	if err != nil {
-		return "", err
+		return nil, ErrOrderNotFound
+	}
+
+	order, err := s.orderRepository.GetOrderWithItems(uint(orderID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin && order.UserID != userID {
+		return nil, ErrOrderNotFound
+	}
+
+	history, err := s.orderRepository.GetStatusHistory(order.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OrderDetail{
+		ID:           order.ID,
+		Status:       order.Status,
+		Total:        order.Total,
+		Items:        toOrderItemResponses(order.OrderItems),
+		CreatedAt:    order.CreatedAt,
+		UpdatedAt:    order.UpdatedAt,
+		CancelledAt:  order.CancelledAt,
+		CancelReason: order.CancelReason,
+		History:      history,
+	}, nil
+}
+
+/**
+ * @brief Retrieves a page of the orders placed by a buyer, newest first.
+ *
+ * Missing or out of range pagination values are replaced by the defaults.
+ *
+ * @param userID The ID of the buyer.
+ * @param filter The status, date range and pagination to apply. Page and Limit are normalized in place.
+ * @return The requested page of orders, the total number of matching orders and an error if the retrieval fails.
+ */
+func (s *orderService) GetUserOrders(userID uint, filter *models.OrderFilter) ([]models.OrderSummary, int64, error) {
+	if filter.Status != "" {
+		if _, ok := orderTransitions[filter.Status]; !ok {
+			return nil, 0, ErrInvalidOrderStatus
+		}
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultOrderPageSize
+	}
+	if filter.Limit > maxOrderPageSize {
+		filter.Limit = maxOrderPageSize
 	}
 
-	return order.Status, nil
+	orders, total, err := s.orderRepository.GetOrdersByUser(userID, *filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return toOrderSummaries(orders), total, nil
+}
+
+/**
+ * @brief Converts orders into their API representation.
+ *
+ * @param orders The orders, with their items and offers loaded.
+ * @return The order summaries, in the same order.
+ */
+func toOrderSummaries(orders []models.Order) []models.OrderSummary {
+	summaries := make([]models.OrderSummary, 0, len(orders))
+	for _, order := range orders {
+		summaries = append(summaries, models.OrderSummary{
+			ID:        order.ID,
+			Status:    order.Status,
+			Total:     order.Total,
+			CreatedAt: order.CreatedAt,
+			Items:     toOrderItemResponses(order.OrderItems),
+		})
+	}
+	return summaries
+}
+
+/**
+ * @brief Converts the items of an order into their API representation.
+ *
+ * @param items The order items, with their offer loaded.
+ * @return The items with their product name and line total.
+ */
+func toOrderItemResponses(items []models.OrderItem) []models.OrderItemResponse {
+	responses := make([]models.OrderItemResponse, 0, len(items))
+	for _, item := range items {
+		responses = append(responses, models.OrderItemResponse{
+			ProductID: item.ProductID,
+			Name:      item.Offer.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.Price,
+			LineTotal: item.Quantity * item.Price,
+		})
+	}
+	return responses
 }
 
 /**
  * @brief Updates the status of an order by its ID.
  *
+ * The change must follow the order lifecycle and is recorded in the status
+ * history of the order. Moving an order to cancelled goes through CancelOrder
+ * so that its stock is restored.
+ *
  * @param id The order ID.
+ * @param userID The ID of the admin updating the order.
  * @param status The new status to update.
  * @return The updated status and an error if the update fails.
  */
-func (s *orderService) UpdateOrderStatus(id string, status *models.OrderStatusUpdateRequest) (string, error) {
-	orderID, _ := strconv.ParseUint(id, 10, 64)
-	err := s.orderRepository.UpdateOrderStatus(uint(orderID), status.Status)
+func (s *orderService) UpdateOrderStatus(id string, userID uint, status *models.OrderStatusUpdateRequest) (string, error) {
+	if _, ok := orderTransitions[status.Status]; !ok {
+		return "", ErrInvalidOrderStatus
+	}
+
+	if status.Status == models.OrderStatusCancelled {
+		err := s.CancelOrder(id, userID, true, status.Reason)
+		if errors.Is(err, ErrOrderNotCancellable) {
+			return "", ErrInvalidStatusTransition
+		}
+		if err != nil {
+			return "", err
+		}
+		return status.Status, nil
+	}
+
+	orderID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return "", ErrOrderNotFound
+	}
+
+	err = s.orderRepository.Transaction(func(repo repository.OrderRepository) error {
+		order, err := repo.GetOrderForUpdate(uint(orderID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if !canTransition(order.Status, status.Status) {
+			return ErrInvalidStatusTransition
+		}
+
+		if err := repo.UpdateOrderStatus(order.ID, status.Status); err != nil {
+			return err
+		}
+
+		return repo.CreateStatusHistory(&models.OrderStatusHistory{
+			OrderID:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   status.Status,
+			ChangedBy:  userID,
+			Reason:     status.Reason,
+		})
+	})
 	if err != nil {
 		return "", err
 	}
@@ -126,12 +352,123 @@ func (s *orderService) UpdateOrderStatus(id string, status *models.OrderStatusUp
 	return status.Status, nil
 }
 
+/**
+ * @brief Checks whether the order lifecycle allows moving from one status to another.
+ *
+ * @param from The current status of the order.
+ * @param to The requested status.
+ * @return True if the transition is allowed, false otherwise.
+ */
+func canTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * @brief Cancels an order and returns the quantity of each of its items to the stock.
+ *
+ * Buyers can only cancel their own orders while they are still pending. Admins
+ * can cancel any order that has not been shipped yet. The stock restoration and
+ * the status change run in a single transaction.
+ *
+ * @param id The order ID.
+ * @param userID The ID of the user cancelling the order.
+ * @param isAdmin Whether the user cancelling the order is an admin.
+ * @param reason The reason given for the cancellation.
+ * @return An error if the cancellation fails.
+ */
+func (s *orderService) CancelOrder(id string, userID uint, isAdmin bool, reason string) error {
+	orderID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ErrOrderNotFound
+	}
+
+	return s.orderRepository.Transaction(func(repo repository.OrderRepository) error {
+		order, err := repo.GetOrderForUpdate(uint(orderID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if !isAdmin && order.UserID != userID {
+			return ErrOrderNotFound
+		}
+
+		if !canCancel(order.Status, isAdmin) {
+			return ErrOrderNotCancellable
+		}
+
+		for _, item := range order.OrderItems {
+			if err := repo.IncrementOfferQuantity(item.ProductID, item.Quantity); err != nil {
+				return fmt.Errorf("failed to restore quantity for product %d", item.ProductID)
+			}
+		}
+
+		if err := repo.CancelOrder(order.ID, userID, reason, time.Now()); err != nil {
+			return err
+		}
+
+		return repo.CreateStatusHistory(&models.OrderStatusHistory{
+			OrderID:    order.ID,
+			FromStatus: order.Status,
+			ToStatus:   models.OrderStatusCancelled,
+			ChangedBy:  userID,
+			Reason:     reason,
+		})
+	})
+}
+
+/**
+ * @brief Checks whether an order in the given status can be cancelled.
+ *
+ * Admins can cancel an order whenever the lifecycle allows it, buyers only
+ * while it is still pending.
+ *
+ * @param status The current status of the order.
+ * @param isAdmin Whether the user cancelling the order is an admin.
+ * @return True if the order can be cancelled, false otherwise.
+ */
+func canCancel(status string, isAdmin bool) bool {
+	if !isAdmin && status != models.OrderStatusPending {
+		return false
+	}
+	return canTransition(status, models.OrderStatusCancelled)
+}
+
+/**
+ * @brief Retrieves the status history of an order by its ID.
+ *
+ * @param id The order ID.
+ * @return The status changes of the order, oldest first, and an error if the retrieval fails.
+ */
+func (s *orderService) GetOrderStatusHistory(id string) ([]models.OrderStatusHistory, error) {
+	orderID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if _, err := s.orderRepository.GetOrderById(uint(orderID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOrderNotFound
+		}
+		return nil, err
+	}
+
+	return s.orderRepository.GetStatusHistory(uint(orderID))
+}
+
 /**
  * @brief Retrieves the admin dashboard data.
  *
- * @return The admin dashboard response, offers, orders, and an error if the retrieval fails.
+ * @return The admin dashboard response, offers, the summaries of every order, and an error if the retrieval fails.
  */
-func (s *orderService) GetAdminDashboard() (models.AdminDashboardResponse, []models.Offer, []models.Order, error) {
+func (s *orderService) GetAdminDashboard() (models.AdminDashboardResponse, []models.Offer, []models.OrderSummary, error) {
 	totalOrders, err := s.orderRepository.CountOrders()
 	if err != nil {
 		return models.AdminDashboardResponse{}, nil, nil, err
@@ -142,27 +479,32 @@ func (s *orderService) GetAdminDashboard() (models.AdminDashboardResponse, []mod
 		return models.AdminDashboardResponse{}, nil, nil, err
 	}
 
-	pendingOrders, err := s.orderRepository.CountOrdersByStatus("pending")
+	pendingOrders, err := s.orderRepository.CountOrdersByStatus(models.OrderStatusPending)
 	if err != nil {
 		return models.AdminDashboardResponse{}, nil, nil, err
 	}
 
-	deliveredOrders, err := s.orderRepository.CountOrdersByStatus("delivered")
+	deliveredOrders, err := s.orderRepository.CountOrdersByStatus(models.OrderStatusDelivered)
 	if err != nil {
 		return models.AdminDashboardResponse{}, nil, nil, err
 	}
 
-	preparingOrders, err := s.orderRepository.CountOrdersByStatus("preparing")
+	preparingOrders, err := s.orderRepository.CountOrdersByStatus(models.OrderStatusPreparing)
 	if err != nil {
 		return models.AdminDashboardResponse{}, nil, nil, err
 	}
 
-	processingOrders, err := s.orderRepository.CountOrdersByStatus("processing")
+	processingOrders, err := s.orderRepository.CountOrdersByStatus(models.OrderStatusProcessing)
 	if err != nil {
 		return models.AdminDashboardResponse{}, nil, nil, err
 	}
 
-	shippedOrders, err := s.orderRepository.CountOrdersByStatus("shipped")
+	shippedOrders, err := s.orderRepository.CountOrdersByStatus(models.OrderStatusShipped)
+	if err != nil {
+		return models.AdminDashboardResponse{}, nil, nil, err
+	}
+
+	cancelledOrders, err := s.orderRepository.CountOrdersByStatus(models.OrderStatusCancelled)
 	if err != nil {
 		return models.AdminDashboardResponse{}, nil, nil, err
 	}
@@ -185,7 +527,23 @@ func (s *orderService) GetAdminDashboard() (models.AdminDashboardResponse, []mod
 		PreparingOrders:  preparingOrders,
 		ProcessingOrders: processingOrders,
 		ShippedOrders:    shippedOrders,
+		CancelledOrders:  cancelledOrders,
 	}
 
-	return dashboard, offers, orders, nil
+	summaries := toOrderSummaries(orders)
+	for i := range summaries {
+		summaries[i].BuyerID = orders[i].UserID
+	}
+
+	return dashboard, offers, summaries, nil
+}
+
+/**
+ * @brief Retrieves statistics about the orders of a user.
+ *
+ * @param userID The ID of the user.
+ * @return The order statistics of the user and an error if the retrieval fails.
+ */
+func (s *orderService) GetUserOrderStats(userID uint) (*models.UserOrderStats, error) {
+	return s.orderRepository.GetUserOrderStats(userID)
 }
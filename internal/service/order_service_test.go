@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"gorm.io/gorm"
+)
+
+/**
+ * @brief Checks every transition between two statuses against the order lifecycle.
+ */
+func TestCanTransition(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{models.OrderStatusPending, models.OrderStatusPreparing}:    true,
+		{models.OrderStatusPending, models.OrderStatusCancelled}:    true,
+		{models.OrderStatusPreparing, models.OrderStatusProcessing}: true,
+		{models.OrderStatusPreparing, models.OrderStatusCancelled}:  true,
+		{models.OrderStatusProcessing, models.OrderStatusShipped}:   true,
+		{models.OrderStatusProcessing, models.OrderStatusCancelled}: true,
+		{models.OrderStatusShipped, models.OrderStatusDelivered}:    true,
+	}
+	statuses := []string{
+		models.OrderStatusPending,
+		models.OrderStatusPreparing,
+		models.OrderStatusProcessing,
+		models.OrderStatusShipped,
+		models.OrderStatusDelivered,
+		models.OrderStatusCancelled,
+		"unknown",
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]string{from, to}]
+			if got := canTransition(from, to); got != want {
+				t.Errorf("%s -> %s: expected %v, got %v", from, to, want, got)
+			}
+		}
+	}
+}
+
+/**
+ * @brief Checks that status updates follow the lifecycle and are recorded in the history of the order.
+ */
+func TestUpdateOrderStatus(t *testing.T) {
+	cases := []struct {
+		name    string
+		from    string
+		to      string
+		wantErr error
+	}{
+		{"pending to preparing", models.OrderStatusPending, models.OrderStatusPreparing, nil},
+		{"processing to shipped", models.OrderStatusProcessing, models.OrderStatusShipped, nil},
+		{"shipped to delivered", models.OrderStatusShipped, models.OrderStatusDelivered, nil},
+		{"processing to cancelled", models.OrderStatusProcessing, models.OrderStatusCancelled, nil},
+		{"pending to shipped", models.OrderStatusPending, models.OrderStatusShipped, ErrInvalidStatusTransition},
+		{"shipped to preparing", models.OrderStatusShipped, models.OrderStatusPreparing, ErrInvalidStatusTransition},
+		{"shipped to cancelled", models.OrderStatusShipped, models.OrderStatusCancelled, ErrInvalidStatusTransition},
+		{"delivered to cancelled", models.OrderStatusDelivered, models.OrderStatusCancelled, ErrInvalidStatusTransition},
+		{"cancelled to pending", models.OrderStatusCancelled, models.OrderStatusPending, ErrInvalidStatusTransition},
+		{"unknown status", models.OrderStatusPending, "lost", ErrInvalidOrderStatus},
+	}
+	for _, tc := range cases {
+		order := &models.Order{Model: gorm.Model{ID: 1}, UserID: 2, Status: tc.from}
+		repo := newMemoryOrderRepository(map[uint]int{}, order)
+
+		status, err := NewOrderService(repo).UpdateOrderStatus("1", 3, &models.OrderStatusUpdateRequest{Status: tc.to, Reason: "test"})
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+			continue
+		}
+		if tc.wantErr != nil {
+			if order.Status != tc.from || len(repo.history) != 0 {
+				t.Errorf("%s: expected the order to be left %s without history, got %s", tc.name, tc.from, order.Status)
+			}
+			continue
+		}
+
+		want := models.OrderStatusHistory{OrderID: 1, FromStatus: tc.from, ToStatus: tc.to, ChangedBy: 3, Reason: "test"}
+		if status != tc.to || order.Status != tc.to || len(repo.history) != 1 || repo.history[0] != want {
+			t.Errorf("%s: expected the order to move to %s with history %+v, got %s and %+v", tc.name, tc.to, want, order.Status, repo.history)
+		}
+	}
+
+	if _, err := NewOrderService(newMemoryOrderRepository(map[uint]int{})).UpdateOrderStatus("1", 3, &models.OrderStatusUpdateRequest{Status: models.OrderStatusPreparing}); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("expected a missing order to be reported, got %v", err)
+	}
+}
+
+/**
+ * @brief Checks who can cancel an order in each status, and that a cancellation returns its items to the stock.
+ */
+func TestCancelOrder(t *testing.T) {
+	cases := []struct {
+		name    string
+		status  string
+		userID  uint
+		isAdmin bool
+		wantErr error
+	}{
+		{"buyer cancels pending order", models.OrderStatusPending, 2, false, nil},
+		{"buyer cancels preparing order", models.OrderStatusPreparing, 2, false, ErrOrderNotCancellable},
+		{"buyer cancels processing order", models.OrderStatusProcessing, 2, false, ErrOrderNotCancellable},
+		{"buyer cancels cancelled order", models.OrderStatusCancelled, 2, false, ErrOrderNotCancellable},
+		{"buyer cancels order of another buyer", models.OrderStatusPending, 4, false, ErrOrderNotFound},
+		{"admin cancels processing order", models.OrderStatusProcessing, 3, true, nil},
+		{"admin cancels shipped order", models.OrderStatusShipped, 3, true, ErrOrderNotCancellable},
+		{"admin cancels delivered order", models.OrderStatusDelivered, 3, true, ErrOrderNotCancellable},
+	}
+	for _, tc := range cases {
+		order := &models.Order{
+			Model:  gorm.Model{ID: 1},
+			UserID: 2,
+			Status: tc.status,
+			OrderItems: []models.OrderItem{
+				{ProductID: 7, Quantity: 2},
+				{ProductID: 8, Quantity: 1},
+				{ProductID: 7, Quantity: 3},
+			},
+		}
+		repo := newMemoryOrderRepository(map[uint]int{7: 10, 8: 0}, order)
+
+		err := NewOrderService(repo).CancelOrder("1", tc.userID, tc.isAdmin, "changed my mind")
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+			continue
+		}
+		if tc.wantErr != nil {
+			if order.Status != tc.status || repo.stock[7] != 10 || repo.stock[8] != 0 || len(repo.history) != 0 {
+				t.Errorf("%s: expected the order and the stock to be left alone", tc.name)
+			}
+			continue
+		}
+
+		if order.Status != models.OrderStatusCancelled || order.CancelledBy == nil || *order.CancelledBy != tc.userID || order.CancelReason != "changed my mind" {
+			t.Errorf("%s: expected the order to be cancelled by %d, got %+v", tc.name, tc.userID, order)
+		}
+		if repo.stock[7] != 15 || repo.stock[8] != 1 {
+			t.Errorf("%s: expected the quantities to be restored, got %v", tc.name, repo.stock)
+		}
+		want := models.OrderStatusHistory{OrderID: 1, FromStatus: tc.status, ToStatus: models.OrderStatusCancelled, ChangedBy: tc.userID, Reason: "changed my mind"}
+		if len(repo.history) != 1 || repo.history[0] != want {
+			t.Errorf("%s: expected history %+v, got %+v", tc.name, want, repo.history)
+		}
+	}
+
+	for _, id := range []string{"2", "abc", "-1"} {
+		if err := NewOrderService(newMemoryOrderRepository(map[uint]int{})).CancelOrder(id, 2, false, ""); !errors.Is(err, ErrOrderNotFound) {
+			t.Errorf("%s: expected a missing order to be reported, got %v", id, err)
+		}
+	}
+}
+
+/**
+ * @brief Checks that the pagination of the order history is normalized and that unknown statuses are refused.
+ */
+func TestGetUserOrdersFilter(t *testing.T) {
+	cases := []struct {
+		name      string
+		filter    models.OrderFilter
+		wantPage  int
+		wantLimit int
+		wantErr   error
+	}{
+		{"defaults", models.OrderFilter{}, 1, 20, nil},
+		{"explicit page and limit", models.OrderFilter{Page: 3, Limit: 50}, 3, 50, nil},
+		{"negative page", models.OrderFilter{Page: -2, Limit: 10}, 1, 10, nil},
+		{"negative limit", models.OrderFilter{Page: 2, Limit: -5}, 2, 20, nil},
+		{"maximum limit", models.OrderFilter{Limit: 100}, 1, 100, nil},
+		{"limit above the maximum", models.OrderFilter{Limit: 1000}, 1, 100, nil},
+		{"known status", models.OrderFilter{Status: models.OrderStatusShipped}, 1, 20, nil},
+		{"unknown status", models.OrderFilter{Status: "lost"}, 0, 0, ErrInvalidOrderStatus},
+	}
+	for _, tc := range cases {
+		repo := newMemoryOrderRepository(map[uint]int{},
+			&models.Order{Model: gorm.Model{ID: 1}, UserID: 2, Status: models.OrderStatusPending},
+			&models.Order{Model: gorm.Model{ID: 2}, UserID: 3, Status: models.OrderStatusPending},
+		)
+		filter := tc.filter
+
+		orders, total, err := NewOrderService(repo).GetUserOrders(2, &filter)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", tc.name, tc.wantErr, err)
+			continue
+		}
+		if tc.wantErr != nil {
+			continue
+		}
+
+		if filter.Page != tc.wantPage || filter.Limit != tc.wantLimit {
+			t.Errorf("%s: expected page %d and limit %d, got %d and %d", tc.name, tc.wantPage, tc.wantLimit, filter.Page, filter.Limit)
+		}
+		if repo.filter != filter {
+			t.Errorf("%s: expected the repository to be queried with %+v, got %+v", tc.name, filter, repo.filter)
+		}
+		if len(orders) != 1 || total != 1 || orders[0].ID != 1 {
+			t.Errorf("%s: expected only the orders of the buyer, got %d of %d", tc.name, len(orders), total)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+)
+
+/**
+ * @brief Generates a random opaque token, such as a refresh token.
+ *
+ * @return A URL-safe token carrying 256 bits of randomness, and an error if the system random source fails.
+ */
+func generateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+/**
+ * @brief Hashes an opaque token so that it can be stored and looked up without keeping its value.
+ *
+ * @param token The token to hash.
+ * @return The hex-encoded SHA-256 hash of the token.
+ */
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+/**
+ * @brief Builds the link sent to a user to use a token, such as a password reset token.
+ *
+ * @param baseURL The frontend page handling the token, or an empty string.
+ * @param token The token to send.
+ * @return The page with the token appended as the token query parameter, or the bare token if no page is configured.
+ */
+func tokenLink(baseURL, token string) string {
+	if baseURL == "" {
+		return token
+	}
+
+	separator := "?"
+	if strings.Contains(baseURL, "?") {
+		separator = "&"
+	}
+	return baseURL + separator + "token=" + url.QueryEscape(token)
+}
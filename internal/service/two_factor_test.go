@@ -0,0 +1,121 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/totp"
+)
+
+/**
+ * @brief Adds a user with two-factor authentication and a set of recovery codes to a store.
+ *
+ * @param t The test.
+ * @param store The store.
+ * @return The user and their recovery codes.
+ */
+func addTwoFactorUser(t *testing.T, store *memoryStore) (*models.User, []string) {
+	t.Helper()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	now := time.Now()
+	user := &models.User{Username: "heidi", Email: "heidi@example.com", Role: models.RoleBuyer, TOTPSecret: secret, TOTPEnabledAt: &now}
+	store.addUser(user)
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("failed to generate recovery codes: %v", err)
+	}
+	for _, hash := range hashes {
+		store.recoveryCodes = append(store.recoveryCodes, &models.RecoveryCode{ID: store.id(), UserID: user.ID, CodeHash: hash})
+	}
+	return user, codes
+}
+
+/**
+ * @brief Checks that a TOTP code is accepted once, and that a code of an earlier step is refused after it.
+ */
+func TestCheckSecondFactorRejectsReplayedCode(t *testing.T) {
+	store := newMemoryStore()
+	userService := newMemoryUserService(t, store, nil).(*userService)
+	user, _ := addTwoFactorUser(t, store)
+
+	step := time.Now().Unix() / 30
+	code, err := totp.Code(user.TOTPSecret, step)
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+	previous, err := totp.Code(user.TOTPSecret, step-1)
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"current code", code, true},
+		{"replayed code", code, false},
+		{"code of the previous step", previous, false},
+	}
+	for _, tc := range cases {
+		valid, err := userService.checkSecondFactor(user, tc.code)
+		if err != nil {
+			t.Fatalf("%s: failed to check code: %v", tc.name, err)
+		}
+		if valid != tc.want {
+			t.Errorf("%s: expected valid %v, got %v", tc.name, tc.want, valid)
+		}
+	}
+	if user.TOTPLastStep != step {
+		t.Fatalf("expected the last step to be %d, got %d", step, user.TOTPLastStep)
+	}
+}
+
+/**
+ * @brief Checks that each recovery code is accepted once, however it is typed, and that its use is audited.
+ */
+func TestCheckSecondFactorRecoveryCodesAreSingleUse(t *testing.T) {
+	store := newMemoryStore()
+	userService := newMemoryUserService(t, store, nil).(*userService)
+	user, codes := addTwoFactorUser(t, store)
+	if len(codes) != recoveryCodeCount {
+		t.Fatalf("expected %d recovery codes, got %d", recoveryCodeCount, len(codes))
+	}
+
+	cases := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"recovery code typed in uppercase without dashes", strings.ToUpper(strings.ReplaceAll(codes[0], "-", " ")), true},
+		{"used recovery code", codes[0], false},
+		{"another recovery code", codes[1], true},
+		{"unknown recovery code", "aaaa-bbbb-cccc-dddd", false},
+	}
+	for _, tc := range cases {
+		valid, err := userService.checkSecondFactor(user, tc.code)
+		if err != nil {
+			t.Fatalf("%s: failed to check code: %v", tc.name, err)
+		}
+		if valid != tc.want {
+			t.Errorf("%s: expected valid %v, got %v", tc.name, tc.want, valid)
+		}
+	}
+
+	used := 0
+	for _, entry := range store.audit {
+		if entry.Action == models.AuditRecoveryCodeUsed {
+			used++
+		}
+	}
+	if used != 2 {
+		t.Fatalf("expected 2 recovery code uses to be audited, got %d", used)
+	}
+}
@@ -1,24 +1,290 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/apperror"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/lockout"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/mailer"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/middleware"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
 	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/repository"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/sso"
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/totp"
+	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+/**
+ * @brief Returned when a user does not exist.
+ */
+var ErrUserNotFound = apperror.NotFound("user_not_found", "user not found")
+
+/**
+ * @brief Returned when the requested role is not one of the known roles.
+ */
+var ErrInvalidRole = apperror.Invalid("invalid_role", "invalid role")
+
+/**
+ * @brief Returned when a role change would leave the system without any admin.
+ */
+var ErrLastAdmin = apperror.Conflict("last_admin", "cannot remove the last admin")
+
+/**
+ * @brief Returned when a refresh token is unknown, expired, revoked or has already been used.
+ */
+var ErrInvalidRefreshToken = apperror.Unauthorized("invalid_refresh_token", "invalid refresh token")
+
+/**
+ * @brief Returned when a session does not exist, belongs to another user or has already been revoked.
+ */
+var ErrSessionNotFound = apperror.NotFound("session_not_found", "session not found")
+
+/**
+ * @brief Returned when a password reset token is unknown, expired or has already been used.
+ */
+var ErrInvalidResetToken = apperror.Invalid("invalid_reset_token", "invalid password reset token")
+
+/**
+ * @brief Returned when an email verification token is unknown, expired or has already been used.
+ */
+var ErrInvalidVerificationToken = apperror.Invalid("invalid_verification_token", "invalid email verification token")
+
+/**
+ * @brief Returned when asking to verify an email that is already verified.
+ */
+var ErrEmailAlreadyVerified = apperror.Conflict("email_already_verified", "email already verified")
+
+/**
+ * @brief Returned when logging in with an unknown email or a wrong password.
+ */
+var ErrInvalidCredentials = apperror.InvalidCredentials("invalid_credentials", "invalid email or password")
+
+/**
+ * @brief Returned when registering with an email that is already in use.
+ */
+var ErrEmailTaken = apperror.Conflict("email_taken", "email already in use")
+
+/**
+ * @brief Returned when registering with a username that is already in use.
+ */
+var ErrUsernameTaken = apperror.Conflict("username_taken", "username already in use")
+
+/**
+ * @brief Returned when a suspended user tries to log in or to use their tokens.
+ */
+var ErrAccountSuspended = apperror.Forbidden("account_suspended", "this account has been suspended")
+
+/**
+ * @brief Returned when an admin tries to suspend their own account.
+ */
+var ErrCannotSuspendSelf = apperror.Conflict("cannot_suspend_self", "admins cannot suspend their own account")
+
+/**
+ * @brief Returned when suspending a user who is already suspended.
+ */
+var ErrAlreadySuspended = apperror.Conflict("account_already_suspended", "the account is already suspended")
+
+/**
+ * @brief Returned when reactivating a user who is not suspended.
+ */
+var ErrNotSuspended = apperror.Conflict("account_not_suspended", "the account is not suspended")
+
+/**
+ * @brief Returned when the password confirming a change to the account is wrong.
+ */
+var ErrIncorrectPassword = apperror.Invalid("incorrect_password", "the current password is incorrect")
+
+/**
+ * @brief Returned when restoring a user who is not deleted.
+ */
+var ErrNotDeleted = apperror.Conflict("account_not_deleted", "the account is not deleted")
+
+/**
+ * @brief Returned when restoring a user whose personal data has already been erased.
+ */
+var ErrAccountAnonymized = apperror.Conflict("account_anonymized", "the personal data of the account has been erased, it cannot be restored")
+
+/**
+ * @brief Returned when logging in with an identity provider while none is configured.
+ */
+var ErrOIDCNotConfigured = apperror.NotFound("oidc_not_configured", "login with an identity provider is not configured")
+
+/**
+ * @brief Returned when the state of a login with an identity provider is unknown, expired or has already been used.
+ */
+var ErrInvalidOIDCState = apperror.Invalid("invalid_oidc_state", "the login is unknown, expired or has already been completed")
+
+/**
+ * @brief Returned when the identity provider rejects the login or does not confirm who the user is.
+ */
+var ErrOIDCLoginFailed = apperror.Unauthorized("oidc_login_failed", "identity provider login failed")
+
+/**
+ * @brief Returned when the identity provider does not share the email of the user.
+ */
+var ErrOIDCEmailMissing = apperror.Invalid("oidc_email_missing", "the identity provider did not share an email address")
+
+/**
+ * @brief Returned when logging in with an identity linked to a deleted account.
+ */
+var ErrAccountDeleted = apperror.Forbidden("account_deleted", "this account has been deleted")
+
+/**
+ * @brief Returned when a login challenge is unknown, expired, has already been completed or has seen too many wrong codes.
+ */
+var ErrInvalidLoginChallenge = apperror.Unauthorized("invalid_login_challenge", "the login challenge is unknown, expired or has already been completed")
+
+/**
+ * @brief Returned when completing a login challenge with a wrong TOTP or recovery code.
+ */
+var ErrInvalidTwoFactorCode = apperror.InvalidCredentials("invalid_two_factor_code", "invalid authentication or recovery code")
+
+/**
+ * @brief Returned when the TOTP or recovery code confirming a change to the account is wrong.
+ */
+var ErrIncorrectTwoFactorCode = apperror.Invalid("incorrect_two_factor_code", "the authentication code is incorrect")
+
+/**
+ * @brief Returned when enrolling in two-factor authentication while it is already enabled.
+ */
+var ErrTOTPAlreadyEnabled = apperror.Conflict("totp_already_enabled", "two-factor authentication is already enabled")
+
+/**
+ * @brief Returned when turning off or managing two-factor authentication while it is not enabled.
+ */
+var ErrTOTPNotEnabled = apperror.Conflict("totp_not_enabled", "two-factor authentication is not enabled")
+
+/**
+ * @brief Returned when confirming an enrollment in two-factor authentication that was not started.
+ */
+var ErrTOTPEnrollmentNotStarted = apperror.Conflict("totp_enrollment_not_started", "start the enrollment in two-factor authentication first")
+
+/**
+ * @brief Returned when an admin tries to turn off two-factor authentication, which is mandatory for them.
+ */
+var ErrTOTPRequired = apperror.Conflict("totp_required", "admins cannot turn off two-factor authentication")
+
+/**
+ * @brief Returned when an admin tries to reset their own two-factor authentication.
+ */
+var ErrCannotResetOwnTOTP = apperror.Conflict("cannot_reset_own_totp", "admins cannot reset their own two-factor authentication")
+
+/**
+ * @brief Time a user has to confirm a login with a second factor, and the number of wrong codes the challenge tolerates.
+ */
+const (
+	loginChallengeTTL    = 5 * time.Minute
+	maxChallengeAttempts = 5
+)
+
+/**
+ * @brief Number of recovery codes issued at once, and the name authenticator apps show for the accounts.
+ */
+const (
+	recoveryCodeCount = 10
+	totpIssuer        = "New World"
+)
+
+/**
+ * @brief Time a user has to complete a login with an identity provider, and the timeout of the requests made to it.
+ */
+const (
+	oidcLoginTTL        = 10 * time.Minute
+	oidcRequestTimeout  = 10 * time.Second
+	oidcUsernameRetries = 5
+)
+
+/**
+ * @brief Number of users returned per page when searching users, and the maximum that can be requested.
+ */
+const (
+	defaultUserPageSize = 20
+	maxUserPageSize     = 100
+)
+
+/**
+ * @brief Number of audit entries returned when listing the audit log, and the maximum that can be requested.
+ */
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+/**
+ * @brief SQLSTATE reported by PostgreSQL when a unique constraint is violated.
+ */
+const uniqueViolation = "23505"
+
+/**
+ * @struct TokenConfig
+ * @brief Lifetimes of the tokens issued to users.
+ *
+ * EmailVerificationURL and PasswordResetURL are the pages of the frontend where
+ * users verify their email and choose their new password; the token sent by email
+ * is appended to them as the token query parameter.
+ */
+type TokenConfig struct {
+	AccessTokenTTL       time.Duration
+	RefreshTokenTTL      time.Duration
+	EmailVerificationTTL time.Duration
+	EmailVerificationURL string
+	PasswordResetTTL     time.Duration
+	PasswordResetURL     string
+}
+
 /**
  * @interface UserService
  * @brief Interface for user-related services.
  *
- * This interface defines methods for managing users, including user creation, login, retrieval, deletion, and database access.
+ * This interface defines methods for managing users, including user creation, email verification, login, token refresh, sessions, logout, password reset, account unlocking, login with an identity provider, two-factor authentication, the audit log, search, profile updates, suspension, deletion, role management, and database access.
  */
 type UserService interface {
 	CreateUser(user *models.User) error
-	LoginUser(login *models.LoginRequest) (string, error)
-	GetAllUsers() ([]models.User, error)
-	DeleteUserByEmail(email string) error
+	VerifyEmail(token string) error
+	ResendVerificationEmail(userID uint) error
+	LoginUser(login *models.LoginRequest, client models.ClientInfo) (*models.AuthTokens, error)
+	StartOIDCLogin() (string, error)
+	CompleteOIDCLogin(state, code string, client models.ClientInfo) (*models.AuthTokens, error)
+	CompleteTwoFactorLogin(challengeToken, code string, client models.ClientInfo) (*models.AuthTokens, error)
+	EnrollTOTP(userID uint) (secret, url string, err error)
+	ConfirmTOTP(userID, sessionID uint, code string) ([]string, error)
+	DisableTOTP(userID uint, password, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	ResetTOTP(actorID uint, id string) error
+	RefreshTokens(refreshToken string, client models.ClientInfo) (*models.AuthTokens, error)
+	GetSessions(userID uint) ([]models.Session, error)
+	GetUserSessions(id string) ([]models.Session, error)
+	RevokeSession(userID uint, id string) error
+	Logout(userID, sessionID uint, tokenID string, tokenExpiresAt time.Time) error
+	LogoutAll(userID uint) error
+	RequestPasswordReset(email string) error
+	ResetPassword(token, password string) error
+	PurgeExpiredRevocations() (int64, error)
+	UnlockUser(actorID uint, id string) error
+	GetAuditEntries(action string, limit int) ([]models.AuditEntry, error)
+	SearchUsers(filter *models.UserFilter) ([]models.User, int64, error)
+	GetUser(id string) (*models.User, error)
+	UpdateUser(actorID uint, id string, update *models.UpdateUserRequest) (*models.User, error)
+	SuspendUser(actorID uint, id, reason string) (*models.User, error)
+	ReactivateUser(actorID uint, id string) (*models.User, error)
+	ForcePasswordReset(actorID uint, id string) error
+	GetProfile(userID uint) (*models.User, error)
+	UpdateProfile(userID uint, update *models.UpdateUserRequest) (*models.User, error)
+	ChangePassword(userID uint, currentPassword, newPassword string) error
+	DeleteAccount(userID uint, password string) error
+	DeleteUserByEmail(actorID uint, email string) error
+	RestoreUser(actorID uint, id string) (*models.User, error)
+	PurgeDeletedUsers(before time.Time) (purged, anonymized int64, err error)
+	UpdateUserRole(actorID uint, id string, role string) (*models.User, error)
+	BootstrapAdmin(email, username, password string) error
 	GetDB() *gorm.DB
 }
 
@@ -29,17 +295,49 @@ type UserService interface {
  * This structure provides the implementation of the methods defined in the `UserService` interface.
  */
 type userService struct {
-	userRepository repository.UserRepository
+	userRepository      repository.UserRepository
+	tokenRepository     repository.TokenRepository
+	sessionRepository   repository.SessionRepository
+	auditRepository     repository.AuditRepository
+	identityRepository  repository.IdentityRepository
+	twoFactorRepository repository.TwoFactorRepository
+	apiKeyRepository    repository.APIKeyRepository
+	loginGuard          lockout.Guard
+	identityProvider    sso.Provider
+	mailer              mailer.Mailer
+	tokenConfig         TokenConfig
 }
 
 /**
  * @brief Creates a new UserService instance.
  *
  * @param userRepo The user repository to use for database operations.
+ * @param tokenRepo The token repository to use for refresh token operations.
+ * @param sessionRepo The session repository to use for session operations.
+ * @param auditRepo The audit repository to record security events.
+ * @param identityRepo The identity repository to store logins with the identity provider and linked identities.
+ * @param twoFactorRepo The two-factor repository to store TOTP secrets, recovery codes and login challenges.
+ * @param apiKeyRepo The API key repository to revoke the keys of compromised and removed accounts.
+ * @param loginGuard The guard throttling failed login attempts.
+ * @param identityProvider The OpenID Connect provider users can log in with, or nil if there is none.
+ * @param mail The mailer used to send emails to users.
+ * @param tokenConfig The lifetimes of the issued tokens.
  * @return A new UserService instance.
  */
-func NewUserService(userRepo repository.UserRepository) UserService {
-	return &userService{userRepository: userRepo}
+func NewUserService(userRepo repository.UserRepository, tokenRepo repository.TokenRepository, sessionRepo repository.SessionRepository, auditRepo repository.AuditRepository, identityRepo repository.IdentityRepository, twoFactorRepo repository.TwoFactorRepository, apiKeyRepo repository.APIKeyRepository, loginGuard lockout.Guard, identityProvider sso.Provider, mail mailer.Mailer, tokenConfig TokenConfig) UserService {
+	return &userService{
+		userRepository:      userRepo,
+		tokenRepository:     tokenRepo,
+		sessionRepository:   sessionRepo,
+		auditRepository:     auditRepo,
+		identityRepository:  identityRepo,
+		twoFactorRepository: twoFactorRepo,
+		apiKeyRepository:    apiKeyRepo,
+		loginGuard:          loginGuard,
+		identityProvider:    identityProvider,
+		mailer:              mail,
+		tokenConfig:         tokenConfig,
+	}
 }
 
 /**
@@ -54,68 +352,1874 @@ func (s *userService) GetDB() *gorm.DB {
 /**
  * @brief Creates a new user with the given data.
  *
+ * Unless the user is created already verified, the account starts with an
+ * unverified email and a verification link is sent to it.
+ *
  * @param user The user data to create the new user.
  * @return An error if the user creation fails.
  */
 func (s *userService) CreateUser(user *models.User) error {
+	if user.Role == "" {
+		user.Role = models.RoleBuyer
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 	if err != nil {
 		return err
 	}
 	user.Password = string(hashedPassword)
-	return s.userRepository.CreateUser(user)
+
+	if user.EmailVerifiedAt != nil {
+		return duplicateUserError(s.userRepository.CreateUser(user))
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(s.tokenConfig.EmailVerificationTTL)
+	user.EmailVerificationTokenHash = hashToken(token)
+	user.EmailVerificationExpiresAt = &expiresAt
+
+	if err := s.userRepository.CreateUser(user); err != nil {
+		return duplicateUserError(err)
+	}
+
+	s.sendVerificationEmail(user, token)
+	return nil
+}
+
+/**
+ * @brief Translates the violation of a unique index on users into the matching domain error.
+ *
+ * @param err The error returned when inserting the user.
+ * @return ErrEmailTaken or ErrUsernameTaken if the email or the username is already in use, otherwise err.
+ */
+func duplicateUserError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+
+	switch {
+	case strings.Contains(pgErr.ConstraintName, "email"):
+		return ErrEmailTaken
+	case strings.Contains(pgErr.ConstraintName, "username"):
+		return ErrUsernameTaken
+	default:
+		return err
+	}
+}
+
+/**
+ * @brief Sends an email verification link to a user.
+ *
+ * Delivery failures are only logged, since the user can ask for a new link.
+ *
+ * @param user The user to send the link to.
+ * @param token The verification token.
+ */
+func (s *userService) sendVerificationEmail(user *models.User, token string) {
+	err := s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hello %s,\n\nUse the following link to verify your email:\n\n%s\n\n"+
+			"The link expires in %s. You need a verified email to place orders.",
+			user.Username, tokenLink(s.tokenConfig.EmailVerificationURL, token), s.tokenConfig.EmailVerificationTTL),
+	})
+	if err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+}
+
+/**
+ * @brief Verifies the email of a user with the token of a verification link.
+ *
+ * @param token The verification token sent by email.
+ * @return An error if the token is not valid or the verification fails.
+ */
+func (s *userService) VerifyEmail(token string) error {
+	tokenHash := hashToken(token)
+	user, err := s.userRepository.GetUserByEmailVerificationToken(tokenHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerificationExpiresAt == nil || time.Now().After(*user.EmailVerificationExpiresAt) {
+		return ErrInvalidVerificationToken
+	}
+
+	verified, err := s.userRepository.MarkEmailVerified(user.ID, tokenHash, time.Now())
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return ErrInvalidVerificationToken
+	}
+	return nil
+}
+
+/**
+ * @brief Sends a new email verification link to a user, invalidating the previous one.
+ *
+ * @param userID The ID of the user.
+ * @return An error if the email is already verified or the link cannot be issued.
+ */
+func (s *userService) ResendVerificationEmail(userID uint) error {
+	user, err := s.userRepository.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.tokenConfig.EmailVerificationTTL)
+	if err := s.userRepository.SetEmailVerificationToken(user.ID, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	s.sendVerificationEmail(user, token)
+	return nil
 }
 
 /**
  * @brief Logs in a user with the given login data.
  *
+ * Every login starts a new session, so a user can be logged in on several
+ * devices at once. Users who enrolled in two-factor authentication only get a
+ * login challenge, to be completed with CompleteTwoFactorLogin.
+ *
  * @param login The login request containing the user's email and password.
- * @return A JWT token string if the login is successful, or an error if it fails.
+ * @param client The client the user logs in from.
+ * @return A short-lived access token and a refresh token of the new session, or a login challenge, if the login is successful, or an error if it fails.
  */
-func (s *userService) LoginUser(login *models.LoginRequest) (string, error) {
+func (s *userService) LoginUser(login *models.LoginRequest, client models.ClientInfo) (*models.AuthTokens, error) {
+	blockedUntil, err := s.loginGuard.BlockedUntil(login.Email, client.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+	if wait := time.Until(blockedUntil); wait > 0 {
+		return nil, apperror.TooManyRequests("too_many_login_attempts", "too many failed login attempts, try again later", wait)
+	}
+
 	user, err := s.userRepository.GetUserByEmail(login.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		s.recordLoginFailure(login.Email, nil, client)
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	// Users who signed up with an identity provider have no password until they set one.
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password))
+	if user.Password == "" || errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		s.recordLoginFailure(login.Email, &user.ID, client)
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// With two-factor authentication, failed attempts are only forgotten once the
+	// second factor is confirmed too, so that guessing codes counts towards the lockout.
+	if user.TOTPEnabledAt == nil {
+		if err := s.loginGuard.RecordSuccess(login.Email); err != nil {
+			log.Printf("Failed to reset the failed login attempts of user %d: %v", user.ID, err)
+		}
+	}
+
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return s.startLoginChallenge(user)
+	}
+	return s.startSession(user, client, false)
+}
+
+/**
+ * @brief Starts a new session of a user who has just logged in.
+ *
+ * @param user The user who logged in.
+ * @param client The client the user logged in from.
+ * @param twoFactor Whether the login was confirmed with a second factor.
+ * @return The access token and the refresh token of the new session, or an error if the session cannot be created.
+ */
+func (s *userService) startSession(user *models.User, client models.ClientInfo, twoFactor bool) (*models.AuthTokens, error) {
+	now := time.Now()
+	session := &models.Session{
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IPAddress:  client.IPAddress,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.tokenConfig.RefreshTokenTTL),
+		TwoFactor:  twoFactor,
+	}
+	if err := s.sessionRepository.CreateSession(session); err != nil {
+		return nil, err
 	}
 
-	role := "normal"
-	if user.Username == "UBUNTU" && login.Password == "UBUNTU" {
-		role = "Admin"
+	return s.issueTokens(user, session.ID, twoFactor)
+}
+
+/**
+ * @brief Starts a login challenge for a user who still has to confirm their login with a second factor.
+ *
+ * @param user The user who logged in.
+ * @return The challenge token and its lifetime, or an error if the challenge cannot be created.
+ */
+func (s *userService) startLoginChallenge(user *models.User) (*models.AuthTokens, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
 	}
 
-	token, err := middleware.GenerateJWT(user.Email, role)
+	now := time.Now()
+	err = s.twoFactorRepository.CreateLoginChallenge(&models.LoginChallenge{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(loginChallengeTTL),
+		CreatedAt: now,
+	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	user.Token = token
-	if err := s.userRepository.UpdateUserToken(user); err != nil {
-		return "", err
+	return &models.AuthTokens{ChallengeToken: token, ExpiresIn: loginChallengeTTL}, nil
+}
+
+/**
+ * @brief Completes a login challenge with a TOTP or recovery code and starts a session.
+ *
+ * Wrong codes count towards the lockout of the account, like wrong passwords,
+ * and a challenge is given up after a few of them.
+ *
+ * @param challengeToken The challenge token returned by the login.
+ * @param code A code of the authenticator app, or one of the recovery codes.
+ * @param client The client the user logs in from.
+ * @return The access token and the refresh token of the new session, or an error if the challenge or the code is not valid.
+ */
+func (s *userService) CompleteTwoFactorLogin(challengeToken, code string, client models.ClientInfo) (*models.AuthTokens, error) {
+	challenge, err := s.twoFactorRepository.GetLoginChallenge(hashToken(challengeToken), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Attempts >= maxChallengeAttempts {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	user, err := s.userRepository.GetUserByID(challenge.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidLoginChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	blockedUntil, err := s.loginGuard.BlockedUntil(user.Email, client.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+	if wait := time.Until(blockedUntil); wait > 0 {
+		return nil, apperror.TooManyRequests("too_many_login_attempts", "too many failed login attempts, try again later", wait)
+	}
+
+	valid, err := s.checkSecondFactor(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		if err := s.twoFactorRepository.RecordChallengeFailure(challenge.ID); err != nil {
+			return nil, err
+		}
+		s.recordLoginFailure(user.Email, &user.ID, client)
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	completed, err := s.twoFactorRepository.DeleteLoginChallenge(challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, ErrInvalidLoginChallenge
+	}
+
+	if err := s.loginGuard.RecordSuccess(user.Email); err != nil {
+		log.Printf("Failed to reset the failed login attempts of user %d: %v", user.ID, err)
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
 	}
 
-	return token, nil
+	return s.startSession(user, client, true)
 }
 
 /**
- * @brief Retrieves all users from the database.
+ * @brief Checks a TOTP or recovery code of a user, using it up if it is valid.
+ *
+ * Codes of 6 digits are checked against the TOTP secret of the user, anything
+ * else against their unused recovery codes.
  *
- * @return A slice of User models and an error if the retrieval fails.
+ * @param user The user, who must have two-factor authentication enabled.
+ * @param code The code entered by the user.
+ * @return True if the code is valid and had not been used yet, and an error if it cannot be checked.
  */
-func (s *userService) GetAllUsers() ([]models.User, error) {
-	return s.userRepository.GetAllUsers()
+func (s *userService) checkSecondFactor(user *models.User, code string) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	now := time.Now()
+
+	if totp.IsCode(code) {
+		step, valid := totp.Validate(user.TOTPSecret, code, now, user.TOTPLastStep)
+		if !valid {
+			return false, nil
+		}
+		return s.twoFactorRepository.UseTOTPStep(user.ID, step)
+	}
+
+	used, err := s.twoFactorRepository.UseRecoveryCode(user.ID, hashToken(totp.NormalizeRecoveryCode(code)), now)
+	if err != nil || !used {
+		return false, err
+	}
+	audit(s.auditRepository, &models.AuditEntry{
+		Action: models.AuditRecoveryCodeUsed,
+		UserID: &user.ID,
+	})
+	return true, nil
 }
 
 /**
- * @brief Deletes a user by their email.
+ * @brief Generates a new set of recovery codes.
  *
- * @param email The email of the user to delete.
- * @return An error if the deletion fails.
+ * @return The recovery codes, their hashes and an error if no random bytes are available.
  */
-func (s *userService) DeleteUserByEmail(email string) error {
-	return s.userRepository.DeleteUserByEmail(email)
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := totp.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+/**
+ * @brief Starts a login with the identity provider.
+ *
+ * The state, nonce and PKCE verifier of the login are kept for a few minutes,
+ * during which the user has to log in at the provider and be sent back to the
+ * callback.
+ *
+ * @return The URL of the provider the user has to be sent to, or an error if no provider is configured.
+ */
+func (s *userService) StartOIDCLogin() (string, error) {
+	if s.identityProvider == nil {
+		return "", ErrOIDCNotConfigured
+	}
+
+	state, err := sso.RandomValue()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := sso.RandomValue()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := sso.RandomValue()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.identityRepository.CreateProviderLogin(&models.ProviderLogin{
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    now.Add(oidcLoginTTL),
+		CreatedAt:    now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.identityProvider.AuthCodeURL(state, nonce, verifier), nil
+}
+
+/**
+ * @brief Completes a login with the identity provider and starts a session.
+ *
+ * The user linked to the identity confirmed by the provider is logged in. An
+ * identity that is not linked yet is linked to the user with the same email if
+ * the provider verified it, and otherwise a new user without a password is
+ * created for it. Users who enrolled in two-factor authentication only get a
+ * login challenge, as with a password.
+ *
+ * @param state The state handed back by the provider.
+ * @param code The authorization code handed back by the provider.
+ * @param client The client the user logs in from.
+ * @return The access token and the refresh token of the new session, or an error if the login fails.
+ */
+func (s *userService) CompleteOIDCLogin(state, code string, client models.ClientInfo) (*models.AuthTokens, error) {
+	if s.identityProvider == nil {
+		return nil, ErrOIDCNotConfigured
+	}
+
+	login, err := s.identityRepository.ConsumeProviderLogin(hashToken(state), time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), oidcRequestTimeout)
+	defer cancel()
+	identity, err := s.identityProvider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("Failed to complete a login with the identity provider: %v", err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.userForIdentity(identity, client)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+
+	if user.TOTPEnabledAt != nil {
+		return s.startLoginChallenge(user)
+	}
+	return s.startSession(user, client, false)
+}
+
+/**
+ * @brief Finds the user an identity confirmed by the identity provider belongs to, linking or creating one if needed.
+ *
+ * @param identity The identity confirmed by the provider.
+ * @param client The client the user logs in from.
+ * @return The user, or an error if the identity belongs to a deleted user or no user can be linked to it.
+ */
+func (s *userService) userForIdentity(identity *sso.Identity, client models.ClientInfo) (*models.User, error) {
+	linked, err := s.identityRepository.GetExternalIdentity(identity.Issuer, identity.Subject)
+	if err == nil {
+		user, err := s.userRepository.GetUserByID(linked.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountDeleted
+		}
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" {
+		return nil, ErrOIDCEmailMissing
+	}
+
+	external := &models.ExternalIdentity{
+		Issuer:  identity.Issuer,
+		Subject: identity.Subject,
+		Email:   identity.Email,
+	}
+
+	user, err := s.userRepository.GetUserByEmail(identity.Email)
+	if err == nil {
+		// Only a provider that verified the email may take over the account
+		// registered with it, otherwise anyone could claim any email there.
+		if !identity.EmailVerified {
+			return nil, ErrEmailTaken
+		}
+		external.UserID = user.ID
+		if err := s.identityRepository.CreateExternalIdentity(external); err != nil {
+			return nil, err
+		}
+		audit(s.auditRepository, &models.AuditEntry{
+			Action:    models.AuditIdentityLinked,
+			UserID:    &user.ID,
+			IPAddress: client.IPAddress,
+			Detail:    fmt.Sprintf("linked identity %s at %s", identity.Subject, identity.Issuer),
+		})
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return s.createUserForIdentity(identity, external)
+}
+
+/**
+ * @brief Creates a user without a password for an identity confirmed by the identity provider.
+ *
+ * The username is derived from the profile shared by the provider, with a
+ * random suffix if it is already in use. Unless the provider verified the
+ * email, a verification link is sent to it, as for users who register.
+ *
+ * @param identity The identity confirmed by the provider.
+ * @param external The identity to link to the new user.
+ * @return The new user, or an error if the creation fails.
+ */
+func (s *userService) createUserForIdentity(identity *sso.Identity, external *models.ExternalIdentity) (*models.User, error) {
+	now := time.Now()
+	base := usernameForIdentity(identity)
+
+	var token string
+	for attempt := 0; ; attempt++ {
+		user := &models.User{
+			Username: base,
+			Email:    identity.Email,
+			Role:     models.RoleBuyer,
+		}
+		if attempt > 0 {
+			suffix, err := generateToken()
+			if err != nil {
+				return nil, err
+			}
+			user.Username = fmt.Sprintf("%.27s-%s", base, suffix[:4])
+		}
+
+		if identity.EmailVerified {
+			user.EmailVerifiedAt = &now
+		} else {
+			var err error
+			if token, err = generateToken(); err != nil {
+				return nil, err
+			}
+			expiresAt := now.Add(s.tokenConfig.EmailVerificationTTL)
+			user.EmailVerificationTokenHash = hashToken(token)
+			user.EmailVerificationExpiresAt = &expiresAt
+		}
+
+		err := duplicateUserError(s.identityRepository.CreateUserWithIdentity(user, external))
+		if errors.Is(err, ErrUsernameTaken) && attempt < oidcUsernameRetries {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if token != "" {
+			s.sendVerificationEmail(user, token)
+		}
+		return user, nil
+	}
+}
+
+/**
+ * @brief Derives a username from the profile shared by the identity provider.
+ *
+ * The preferred username is used if there is one, then the name and finally
+ * the part of the email before the @, keeping only the characters allowed in
+ * usernames.
+ *
+ * @param identity The identity confirmed by the provider.
+ * @return A username of 3 to 32 characters.
+ */
+func usernameForIdentity(identity *sso.Identity) string {
+	local, _, _ := strings.Cut(identity.Email, "@")
+	for _, candidate := range []string{identity.PreferredUsername, identity.Name, local} {
+		username := strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+				return r
+			case r == ' ':
+				return '_'
+			default:
+				return -1
+			}
+		}, candidate)
+		if len(username) > 32 {
+			username = username[:32]
+		}
+		if len(username) >= 3 {
+			return username
+		}
+	}
+	return "user"
+}
+
+/**
+ * @brief Records a failed login attempt, and audits the lockouts it triggers.
+ *
+ * Failures to record the attempt are only logged, so the login is still
+ * answered as a wrong password.
+ *
+ * @param email The email the login was attempted with.
+ * @param userID The ID of the user with that email, or nil if there is none.
+ * @param client The client the login was attempted from.
+ */
+func (s *userService) recordLoginFailure(email string, userID *uint, client models.ClientInfo) {
+	result, err := s.loginGuard.RecordFailure(email, client.IPAddress)
+	if err != nil {
+		log.Printf("Failed to record a failed login attempt: %v", err)
+		return
+	}
+
+	until := result.LockedUntil.UTC().Format(time.RFC3339)
+	if result.AccountLocked {
+		audit(s.auditRepository, &models.AuditEntry{
+			Action:    models.AuditAccountLocked,
+			UserID:    userID,
+			IPAddress: client.IPAddress,
+			Detail:    fmt.Sprintf("login for %s locked until %s", email, until),
+		})
+	}
+	if result.IPLocked {
+		audit(s.auditRepository, &models.AuditEntry{
+			Action:    models.AuditIPLocked,
+			IPAddress: client.IPAddress,
+			Detail:    fmt.Sprintf("logins from %s locked until %s", client.IPAddress, until),
+		})
+	}
+}
+
+/**
+ * @brief Appends an entry to the audit log.
+ *
+ * Failures are only logged, so that auditing never fails the audited operation.
+ *
+ * @param auditRepo The audit repository storing the entry.
+ * @param entry The entry to append.
+ */
+func audit(auditRepo repository.AuditRepository, entry *models.AuditEntry) {
+	if err := auditRepo.CreateAuditEntry(entry); err != nil {
+		log.Printf("Failed to record audit entry %s: %v", entry.Action, err)
+	}
+}
+
+/**
+ * @brief Exchanges a refresh token for a new access token and a new refresh token.
+ *
+ * Refresh tokens are single use. Presenting a token that was already exchanged
+ * means it was stolen or replayed, so the whole session is revoked, together
+ * with the access tokens issued in it.
+ *
+ * @param refreshToken The refresh token obtained at login or at the previous refresh.
+ * @param client The client the refresh token is used from.
+ * @return The new tokens and an error if the refresh token is not valid.
+ */
+func (s *userService) RefreshTokens(refreshToken string, client models.ClientInfo) (*models.AuthTokens, error) {
+	stored, err := s.tokenRepository.GetRefreshTokenByHash(hashToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil || now.After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepository.GetUserByID(stored.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrAccountSuspended
+	}
+
+	fresh, err := s.tokenRepository.MarkRefreshTokenUsed(stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		log.Printf("Refresh token reuse detected for user %d, revoking session %d", user.ID, stored.SessionID)
+		if err := s.revokeSession(stored.SessionID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	err = s.sessionRepository.UpdateSessionActivity(stored.SessionID, client, now, now.Add(s.tokenConfig.RefreshTokenTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessionRepository.GetSessionByID(stored.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(user, session.ID, session.TwoFactor)
+}
+
+/**
+ * @brief Issues a new access token and a new refresh token of the given session to a user.
+ *
+ * @param user The user the tokens are issued to.
+ * @param sessionID The session the tokens belong to.
+ * @param twoFactor Whether the login of the session was confirmed with a second factor.
+ * @return The new tokens and an error if they cannot be issued.
+ */
+func (s *userService) issueTokens(user *models.User, sessionID uint, twoFactor bool) (*models.AuthTokens, error) {
+	accessToken, err := middleware.GenerateJWT(user.ID, sessionID, user.Email, user.Role, twoFactor, s.tokenConfig.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tokenRepository.CreateRefreshToken(&models.RefreshToken{
+		UserID:               user.ID,
+		SessionID:            sessionID,
+		TokenHash:            hashToken(refreshToken),
+		AccessTokenID:        accessToken.ID,
+		AccessTokenExpiresAt: accessToken.ExpiresAt,
+		ExpiresAt:            time.Now().Add(s.tokenConfig.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthTokens{
+		AccessToken:  accessToken.Token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    s.tokenConfig.AccessTokenTTL,
+	}, nil
+}
+
+/**
+ * @brief Revokes a session, its refresh tokens and the access tokens issued in it.
+ *
+ * @param sessionID The ID of the session.
+ * @param now The time of the revocation.
+ * @return An error if the revocation fails.
+ */
+func (s *userService) revokeSession(sessionID uint, now time.Time) error {
+	tokens, err := s.tokenRepository.GetSessionRefreshTokens(sessionID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.AccessTokenID == "" || !token.AccessTokenExpiresAt.After(now) {
+			continue
+		}
+		err := s.tokenRepository.RevokeAccessToken(&models.RevokedToken{
+			JTI:       token.AccessTokenID,
+			UserID:    token.UserID,
+			ExpiresAt: token.AccessTokenExpiresAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := s.tokenRepository.RevokeSessionRefreshTokens(sessionID, now); err != nil {
+		return err
+	}
+	return s.sessionRepository.RevokeSession(sessionID, now)
+}
+
+/**
+ * @brief Retrieves the active sessions of a user.
+ *
+ * @param userID The ID of the user.
+ * @return The sessions that are neither revoked nor expired, most recently used first, and an error if the retrieval fails.
+ */
+func (s *userService) GetSessions(userID uint) ([]models.Session, error) {
+	return s.sessionRepository.GetActiveSessions(userID, time.Now())
+}
+
+/**
+ * @brief Retrieves the active sessions of any user.
+ *
+ * @param id The ID of the user.
+ * @return The sessions that are neither revoked nor expired, most recently used first, and an error if the user does not exist or the retrieval fails.
+ */
+func (s *userService) GetUserSessions(id string) ([]models.Session, error) {
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	_, err = s.userRepository.GetUserByID(uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetSessions(uint(userID))
+}
+
+/**
+ * @brief Revokes one of the sessions of a user, logging that device out.
+ *
+ * @param userID The ID of the user owning the session.
+ * @param id The ID of the session.
+ * @return An error if the session is not an active session of the user or the revocation fails.
+ */
+func (s *userService) RevokeSession(userID uint, id string) error {
+	sessionID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	session, err := s.sessionRepository.GetSessionByID(uint(sessionID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if session.UserID != userID || session.RevokedAt != nil {
+		return ErrSessionNotFound
+	}
+
+	return s.revokeSession(session.ID, time.Now())
+}
+
+/**
+ * @brief Logs a user out of the current session.
+ *
+ * The access token used for the request is added to the revocation list until
+ * it expires, and the session is revoked so that its refresh token cannot be
+ * used to obtain new tokens.
+ *
+ * @param userID The ID of the user logging out.
+ * @param sessionID The ID of the session of the request.
+ * @param tokenID The JWT ID of the access token used for the request.
+ * @param tokenExpiresAt The expiration time of the access token.
+ * @return An error if the logout fails.
+ */
+func (s *userService) Logout(userID, sessionID uint, tokenID string, tokenExpiresAt time.Time) error {
+	err := s.tokenRepository.RevokeAccessToken(&models.RevokedToken{
+		JTI:       tokenID,
+		UserID:    userID,
+		ExpiresAt: tokenExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.revokeSession(sessionID, time.Now())
+}
+
+/**
+ * @brief Logs a user out of every session.
+ *
+ * Every access token issued so far is rejected from now on and every session
+ * of the user is revoked together with its refresh tokens.
+ *
+ * @param userID The ID of the user logging out.
+ * @return An error if the logout fails.
+ */
+func (s *userService) LogoutAll(userID uint) error {
+	now := time.Now()
+	if err := s.userRepository.UpdateTokensValidAfter(userID, now); err != nil {
+		return err
+	}
+	if err := s.tokenRepository.RevokeUserRefreshTokens(userID, now); err != nil {
+		return err
+	}
+	return s.sessionRepository.RevokeUserSessions(userID, now)
+}
+
+/**
+ * @brief Logs a user out of every session and revokes their API keys.
+ *
+ * Used when the account may be compromised or is taken away from the user, so
+ * that no credential issued before keeps working.
+ *
+ * @param userID The ID of the user.
+ * @return An error if the revocation fails.
+ */
+func (s *userService) revokeCredentials(userID uint) error {
+	if err := s.LogoutAll(userID); err != nil {
+		return err
+	}
+	return s.apiKeyRepository.RevokeUserAPIKeys(userID, time.Now())
+}
+
+/**
+ * @brief Sends a single-use, time-limited password reset token to a user by email.
+ *
+ * Requesting a new token invalidates the previous one. Nothing is sent if no
+ * account uses the email, and no error is returned either, so that the response
+ * does not reveal which emails are registered. Delivery failures are only logged
+ * for the same reason.
+ *
+ * @param email The email of the account.
+ * @return An error if the reset token cannot be stored.
+ */
+func (s *userService) RequestPasswordReset(email string) error {
+	user, err := s.userRepository.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.sendPasswordResetEmail(user, "If you did not ask for it, you can ignore this email.")
+}
+
+/**
+ * @brief Stores a new password reset token for a user and sends it to them by email.
+ *
+ * Delivery failures are only logged, since the user can ask for a new link.
+ *
+ * @param user The user to send the link to.
+ * @param note A sentence appended to the email, explaining why it was sent.
+ * @return An error if the reset token cannot be generated or stored.
+ */
+func (s *userService) sendPasswordResetEmail(user *models.User, note string) error {
+	token, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(s.tokenConfig.PasswordResetTTL)
+	if err := s.userRepository.SetPasswordResetToken(user.ID, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\nUse the following link to choose a new password:\n\n%s\n\n"+
+			"The link can be used once and expires in %s. %s",
+			user.Username, tokenLink(s.tokenConfig.PasswordResetURL, token), s.tokenConfig.PasswordResetTTL, note),
+	})
+	if err != nil {
+		log.Printf("Failed to send password reset email to user %d: %v", user.ID, err)
+	}
+	return nil
+}
+
+/**
+ * @brief Sets a new password using a password reset token.
+ *
+ * The token is consumed, and the user is logged out of every session and their
+ * API keys are revoked, since whoever knew the previous password may still hold
+ * tokens or have created keys. Proving access to the email also lifts the login
+ * lockout of the account.
+ *
+ * @param token The reset token sent by email.
+ * @param password The new password.
+ * @return An error if the token is not valid or the reset fails.
+ */
+func (s *userService) ResetPassword(token, password string) error {
+	tokenHash := hashToken(token)
+	user, err := s.userRepository.GetUserByPasswordResetToken(tokenHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	if user.PasswordResetExpiresAt == nil || time.Now().After(*user.PasswordResetExpiresAt) {
+		return ErrInvalidResetToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	reset, err := s.userRepository.ResetPassword(user.ID, tokenHash, string(hashedPassword))
+	if err != nil {
+		return err
+	}
+	if !reset {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.loginGuard.Unlock(user.Email); err != nil {
+		log.Printf("Failed to lift the login lockout of user %d: %v", user.ID, err)
+	}
+
+	return s.revokeCredentials(user.ID)
+}
+
+/**
+ * @brief Removes from the revocation list the access tokens that have expired anyway.
+ *
+ * @return The number of purged entries and an error if the purge fails.
+ */
+func (s *userService) PurgeExpiredRevocations() (int64, error) {
+	return s.tokenRepository.DeleteExpiredRevocations(time.Now())
+}
+
+/**
+ * @brief Lifts the login lockout of a user and forgets their failed login attempts.
+ *
+ * @param actorID The ID of the admin lifting the lockout.
+ * @param id The ID of the user.
+ * @return An error if the user does not exist or the unlock fails.
+ */
+func (s *userService) UnlockUser(actorID uint, id string) error {
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	user, err := s.userRepository.GetUserByID(uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.loginGuard.Unlock(user.Email); err != nil {
+		return err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAccountUnlocked,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+	})
+	return nil
+}
+
+/**
+ * @brief Retrieves the most recent entries of the audit log.
+ *
+ * A missing or out of range limit is replaced by the default.
+ *
+ * @param action The action to filter by, or an empty string for every action.
+ * @param limit The maximum number of entries to return.
+ * @return The entries, newest first, and an error if the retrieval fails.
+ */
+func (s *userService) GetAuditEntries(action string, limit int) ([]models.AuditEntry, error) {
+	if limit < 1 {
+		limit = defaultAuditLimit
+	}
+	if limit > maxAuditLimit {
+		limit = maxAuditLimit
+	}
+	return s.auditRepository.GetAuditEntries(action, limit)
+}
+
+/**
+ * @brief Retrieves a page of the users matching a filter, oldest first.
+ *
+ * Missing or out of range pagination values are replaced by the defaults.
+ *
+ * @param filter The text, role and pagination to apply. Page and Limit are normalized in place.
+ * @return The requested page of users, the total number of matching users and an error if the retrieval fails.
+ */
+func (s *userService) SearchUsers(filter *models.UserFilter) ([]models.User, int64, error) {
+	if filter.Role != "" && filter.Role != models.RoleBuyer && filter.Role != models.RoleAdmin {
+		return nil, 0, ErrInvalidRole
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = defaultUserPageSize
+	}
+	if filter.Limit > maxUserPageSize {
+		filter.Limit = maxUserPageSize
+	}
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	return s.userRepository.SearchUsers(*filter)
+}
+
+/**
+ * @brief Retrieves a user by their ID.
+ *
+ * @param id The ID of the user.
+ * @return The user and an error if the user does not exist or the retrieval fails.
+ */
+func (s *userService) GetUser(id string) (*models.User, error) {
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.userRepository.GetUserByID(uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+/**
+ * @brief Updates the username and the email of a user.
+ *
+ * A new email has to be verified again, so a verification link is sent to it.
+ *
+ * @param actorID The ID of the admin making the change.
+ * @param id The ID of the user.
+ * @param update The new username and email; fields left out are kept.
+ * @return The updated user and an error if the user does not exist, the username or email is taken, or the update fails.
+ */
+func (s *userService) UpdateUser(actorID uint, id string, update *models.UpdateUserRequest) (*models.User, error) {
+	if update.Username == nil && update.Email == nil {
+		return nil, apperror.Validation([]models.FieldError{{Field: "body", Reason: "must change the username or the email"}})
+	}
+
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateProfile(actorID, user, update)
+}
+
+/**
+ * @brief Applies a change of username and/or email to a user and records it in the audit log.
+ *
+ * A new email has to be verified again, so a verification link is sent to it.
+ *
+ * @param actorID The ID of the user making the change.
+ * @param user The user to change.
+ * @param update The new username and email; fields left out are kept.
+ * @return The updated user and an error if the username or email is taken or the update fails.
+ */
+func (s *userService) updateProfile(actorID uint, user *models.User, update *models.UpdateUserRequest) (*models.User, error) {
+	var err error
+	var changes []string
+	if update.Username != nil && *update.Username != user.Username {
+		changes = append(changes, fmt.Sprintf("username %q -> %q", user.Username, *update.Username))
+		user.Username = *update.Username
+	}
+
+	var verificationToken string
+	if update.Email != nil && !strings.EqualFold(*update.Email, user.Email) {
+		verificationToken, err = generateToken()
+		if err != nil {
+			return nil, err
+		}
+		expiresAt := time.Now().Add(s.tokenConfig.EmailVerificationTTL)
+
+		changes = append(changes, fmt.Sprintf("email %q -> %q", user.Email, *update.Email))
+		user.Email = *update.Email
+		user.EmailVerifiedAt = nil
+		user.EmailVerificationTokenHash = hashToken(verificationToken)
+		user.EmailVerificationExpiresAt = &expiresAt
+	}
+
+	if len(changes) == 0 {
+		return user, nil
+	}
+
+	if err := s.userRepository.UpdateUserProfile(user); err != nil {
+		return nil, duplicateUserError(err)
+	}
+
+	if verificationToken != "" {
+		s.sendVerificationEmail(user, verificationToken)
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditUserUpdated,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+		Detail:  strings.Join(changes, ", "),
+	})
+	return user, nil
+}
+
+/**
+ * @brief Suspends a user, logs them out of every session and revokes their API keys.
+ *
+ * Suspended users cannot log in, refresh their tokens or use the tokens they
+ * still hold until they are reactivated.
+ *
+ * @param actorID The ID of the admin suspending the user.
+ * @param id The ID of the user.
+ * @param reason Why the user is suspended.
+ * @return The suspended user and an error if the user does not exist, is the admin themselves, is already suspended or the suspension fails.
+ */
+func (s *userService) SuspendUser(actorID uint, id, reason string) (*models.User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == actorID {
+		return nil, ErrCannotSuspendSelf
+	}
+	if user.SuspendedAt != nil {
+		return nil, ErrAlreadySuspended
+	}
+
+	now := time.Now()
+	if err := s.userRepository.SetUserSuspension(user.ID, &now, reason); err != nil {
+		return nil, err
+	}
+	if err := s.revokeCredentials(user.ID); err != nil {
+		return nil, err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAccountSuspended,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+		Detail:  reason,
+	})
+
+	user.SuspendedAt = &now
+	user.SuspendReason = reason
+	return user, nil
+}
+
+/**
+ * @brief Lifts the suspension of a user, who can log in again.
+ *
+ * @param actorID The ID of the admin reactivating the user.
+ * @param id The ID of the user.
+ * @return The reactivated user and an error if the user does not exist, is not suspended or the reactivation fails.
+ */
+func (s *userService) ReactivateUser(actorID uint, id string) (*models.User, error) {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.SuspendedAt == nil {
+		return nil, ErrNotSuspended
+	}
+
+	if err := s.userRepository.SetUserSuspension(user.ID, nil, ""); err != nil {
+		return nil, err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAccountReactivated,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+	})
+
+	user.SuspendedAt = nil
+	user.SuspendReason = ""
+	return user, nil
+}
+
+/**
+ * @brief Forces a user to choose a new password.
+ *
+ * The current password stops working, the user is logged out of every session,
+ * their API keys are revoked and a password reset link is sent to their email.
+ *
+ * @param actorID The ID of the admin forcing the reset.
+ * @param id The ID of the user.
+ * @return An error if the user does not exist or the reset fails.
+ */
+func (s *userService) ForcePasswordReset(actorID uint, id string) error {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return err
+	}
+
+	// Replace the password with one nobody knows, so that only the emailed link lets the user back in.
+	unusable, err := generateToken()
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(unusable), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepository.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.revokeCredentials(user.ID); err != nil {
+		return err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditPasswordResetForced,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+	})
+
+	return s.sendPasswordResetEmail(user, "An administrator asked you to choose a new password; your previous password no longer works.")
+}
+
+/**
+ * @brief Retrieves the account of the logged-in user.
+ *
+ * @param userID The ID of the user.
+ * @return The user and an error if the user does not exist or the retrieval fails.
+ */
+func (s *userService) GetProfile(userID uint) (*models.User, error) {
+	user, err := s.userRepository.GetUserByID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+/**
+ * @brief Changes the username and/or the email of the logged-in user.
+ *
+ * A new email has to be verified again, so a verification link is sent to it.
+ *
+ * @param userID The ID of the user.
+ * @param update The new username and email; fields left out are kept.
+ * @return The updated user and an error if the username or email is taken or the update fails.
+ */
+func (s *userService) UpdateProfile(userID uint, update *models.UpdateUserRequest) (*models.User, error) {
+	if update.Username == nil && update.Email == nil {
+		return nil, apperror.Validation([]models.FieldError{{Field: "body", Reason: "must change the username or the email"}})
+	}
+
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateProfile(userID, user, update)
+}
+
+/**
+ * @brief Changes the password of the logged-in user after checking the current one.
+ *
+ * The user is logged out of every session and their API keys are revoked, so
+ * that whoever knew the previous password cannot keep using the tokens or keys
+ * they obtained with it.
+ *
+ * @param userID The ID of the user.
+ * @param currentPassword The current password of the user.
+ * @param newPassword The new password.
+ * @return An error if the current password is wrong or the change fails.
+ */
+func (s *userService) ChangePassword(userID uint, currentPassword, newPassword string) error {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkPassword(user, currentPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.userRepository.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.revokeCredentials(user.ID); err != nil {
+		return err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditPasswordChanged,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+	})
+	return nil
+}
+
+/**
+ * @brief Starts the enrollment of the logged-in user in two-factor authentication.
+ *
+ * A new secret is generated for the user to add to their authenticator app. It
+ * only takes effect once confirmed with ConfirmTOTP; starting again replaces it.
+ *
+ * @param userID The ID of the user.
+ * @return The secret, the otpauth URL to show as a QR code, and an error if two-factor authentication is already enabled.
+ */
+func (s *userService) EnrollTOTP(userID uint) (secret, url string, err error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.TOTPEnabledAt != nil {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err = totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.twoFactorRepository.SetPendingTOTPSecret(user.ID, secret); err != nil {
+		return "", "", err
+	}
+
+	return secret, totp.URL(totpIssuer, user.Email, secret), nil
+}
+
+/**
+ * @brief Turns on two-factor authentication for the logged-in user with a code of their authenticator app.
+ *
+ * The code proves the user has the second factor at hand, so the current
+ * session counts as confirmed with it from its next refresh on.
+ *
+ * @param userID The ID of the user.
+ * @param sessionID The ID of the session the request was made in.
+ * @param code A code generated from the secret returned by EnrollTOTP.
+ * @return The recovery codes, which are shown only this once, or an error if the enrollment was not started or the code is wrong.
+ */
+func (s *userService) ConfirmTOTP(userID, sessionID uint, code string) ([]string, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, ErrTOTPEnrollmentNotStarted
+	}
+
+	now := time.Now()
+	step, valid := totp.Validate(user.TOTPPendingSecret, strings.ReplaceAll(strings.TrimSpace(code), " ", ""), now, 0)
+	if !valid {
+		return nil, ErrIncorrectTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.twoFactorRepository.EnableTOTP(user.ID, user.TOTPPendingSecret, step, now, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTOTPEnrollmentNotStarted
+	}
+
+	if err := s.sessionRepository.MarkSessionTwoFactor(sessionID, user.ID); err != nil {
+		return nil, err
+	}
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditTOTPEnabled,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+	})
+	return codes, nil
+}
+
+/**
+ * @brief Turns off two-factor authentication for the logged-in user after checking their password and a code.
+ *
+ * Admins cannot turn it off, since it is mandatory for them.
+ *
+ * @param userID The ID of the user.
+ * @param password The password of the user, left empty by users who never set one.
+ * @param code A code of the authenticator app, or one of the recovery codes.
+ * @return An error if two-factor authentication is not enabled, the user is an admin, or the password or the code is wrong.
+ */
+func (s *userService) DisableTOTP(userID uint, password, code string) error {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrTOTPNotEnabled
+	}
+	if user.Role == models.RoleAdmin {
+		return ErrTOTPRequired
+	}
+	if err := checkPassword(user, password); err != nil {
+		return err
+	}
+
+	valid, err := s.checkSecondFactor(user, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrIncorrectTwoFactorCode
+	}
+
+	if err := s.twoFactorRepository.DisableTOTP(user.ID); err != nil {
+		return err
+	}
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditTOTPDisabled,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+	})
+	return nil
+}
+
+/**
+ * @brief Replaces the recovery codes of the logged-in user after checking a code.
+ *
+ * @param userID The ID of the user.
+ * @param code A code of the authenticator app, or one of the recovery codes.
+ * @return The new recovery codes, which are shown only this once, or an error if two-factor authentication is not enabled or the code is wrong.
+ */
+func (s *userService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt == nil {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	valid, err := s.checkSecondFactor(user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrIncorrectTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.twoFactorRepository.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		return nil, err
+	}
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditRecoveryCodesIssued,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+	})
+	return codes, nil
+}
+
+/**
+ * @brief Turns off two-factor authentication for a user who lost their authenticator app and recovery codes.
+ *
+ * The user is logged out of every session, including the ones confirmed with
+ * the lost factor, and has to enroll again; admins cannot use their routes
+ * until they do.
+ *
+ * @param actorID The ID of the admin resetting the user.
+ * @param id The ID of the user.
+ * @return An error if the user does not exist, is the admin themselves, or has not enabled two-factor authentication.
+ */
+func (s *userService) ResetTOTP(actorID uint, id string) error {
+	user, err := s.GetUser(id)
+	if err != nil {
+		return err
+	}
+	if user.ID == actorID {
+		return ErrCannotResetOwnTOTP
+	}
+	if user.TOTPEnabledAt == nil {
+		return ErrTOTPNotEnabled
+	}
+
+	if err := s.twoFactorRepository.DisableTOTP(user.ID); err != nil {
+		return err
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return err
+	}
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditTOTPReset,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+	})
+	return nil
+}
+
+/**
+ * @brief Checks the password confirming a change to the account of a user.
+ *
+ * Users who signed up with an identity provider and never set a password have
+ * nothing to confirm, since they are already authenticated.
+ *
+ * @param user The user changing their account.
+ * @param password The password they confirmed the change with.
+ * @return ErrIncorrectPassword if the password is wrong, otherwise nil.
+ */
+func checkPassword(user *models.User, password string) error {
+	if user.Password == "" {
+		return nil
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return ErrIncorrectPassword
+	}
+	return nil
+}
+
+/**
+ * @brief Deletes the account of the logged-in user after checking their password.
+ *
+ * The personal data of the user is erased, they are logged out of every session
+ * and their API keys are revoked, but their orders are kept for accounting and
+ * keep pointing at the anonymized account. The last admin cannot delete their
+ * account.
+ *
+ * @param userID The ID of the user.
+ * @param password The password of the user, confirming the deletion.
+ * @return An error if the password is wrong, the user is the last admin or the deletion fails.
+ */
+func (s *userService) DeleteAccount(userID uint, password string) error {
+	user, err := s.GetProfile(userID)
+	if err != nil {
+		return err
+	}
+	if err := checkPassword(user, password); err != nil {
+		return err
+	}
+
+	err = s.userRepository.Transaction(func(repo repository.UserRepository) error {
+		if user.Role == models.RoleAdmin {
+			if err := ensureAnotherAdmin(repo); err != nil {
+				return err
+			}
+		}
+		return repo.AnonymizeUser(user.ID, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.revokeCredentials(user.ID); err != nil {
+		return err
+	}
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAccountDeleted,
+		UserID:  &user.ID,
+		ActorID: &user.ID,
+	})
+	return nil
+}
+
+/**
+ * @brief Deletes a user by their email.
+ *
+ * The user is only soft deleted, so that an admin can restore them, is logged
+ * out of every session and has their API keys revoked. The last admin cannot be
+ * deleted.
+ *
+ * @param actorID The ID of the admin deleting the user.
+ * @param email The email of the user to delete.
+ * @return An error if the user does not exist, is the last admin or the deletion fails.
+ */
+func (s *userService) DeleteUserByEmail(actorID uint, email string) error {
+	user, err := s.userRepository.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = s.userRepository.Transaction(func(repo repository.UserRepository) error {
+		if user.Role == models.RoleAdmin {
+			if err := ensureAnotherAdmin(repo); err != nil {
+				return err
+			}
+		}
+		return repo.DeleteUserByEmail(user.Email)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.revokeCredentials(user.ID); err != nil {
+		return err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAccountDeleted,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+	})
+	return nil
+}
+
+/**
+ * @brief Restores a deleted user, who can log in again.
+ *
+ * Tokens issued before the deletion stay revoked. Users whose personal data has
+ * been erased cannot be restored, and neither can users whose username or email
+ * has been taken by another account in the meantime.
+ *
+ * @param actorID The ID of the admin restoring the user.
+ * @param id The ID of the user.
+ * @return The restored user and an error if the user does not exist, is not deleted, is anonymized or the restore fails.
+ */
+func (s *userService) RestoreUser(actorID uint, id string) (*models.User, error) {
+	userID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := s.userRepository.GetUserByIDIncludingDeleted(uint(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !user.DeletedAt.Valid {
+		return nil, ErrNotDeleted
+	}
+	if user.AnonymizedAt != nil {
+		return nil, ErrAccountAnonymized
+	}
+
+	if err := s.userRepository.RestoreUser(user.ID); err != nil {
+		return nil, duplicateUserError(err)
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditAccountRestored,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+	})
+
+	user.DeletedAt = gorm.DeletedAt{}
+	return user, nil
+}
+
+/**
+ * @brief Permanently removes the users deleted before the given time.
+ *
+ * Users with orders are kept for accounting, with their personal data erased.
+ *
+ * @param before Users deleted before this time are purged.
+ * @return The number of removed users, the number of anonymized users and an error if the purge fails.
+ */
+func (s *userService) PurgeDeletedUsers(before time.Time) (purged, anonymized int64, err error) {
+	return s.userRepository.PurgeDeletedUsers(before)
+}
+
+/**
+ * @brief Changes the role of a user.
+ *
+ * The user is logged out of every session, so that no token keeps granting
+ * the previous role, and has to log in again. Demoting the last remaining admin
+ * is rejected so that the system always keeps one; the admins are locked while
+ * counting them, so that concurrent demotions cannot remove them all.
+ *
+ * @param actorID The ID of the admin changing the role.
+ * @param id The ID of the user.
+ * @param role The new role, either "buyer" or "admin".
+ * @return The updated user and an error if the change fails.
+ */
+func (s *userService) UpdateUserRole(actorID uint, id string, role string) (*models.User, error) {
+	if role != models.RoleBuyer && role != models.RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+
+	user, err := s.GetUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+
+	err = s.userRepository.Transaction(func(repo repository.UserRepository) error {
+		if user.Role == models.RoleAdmin {
+			if err := ensureAnotherAdmin(repo); err != nil {
+				return err
+			}
+		}
+		return repo.UpdateUserRole(user.ID, role)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.LogoutAll(user.ID); err != nil {
+		return nil, err
+	}
+
+	audit(s.auditRepository, &models.AuditEntry{
+		Action:  models.AuditRoleChanged,
+		UserID:  &user.ID,
+		ActorID: &actorID,
+		Detail:  user.Role + " -> " + role,
+	})
+
+	user.Role = role
+	return user, nil
+}
+
+/**
+ * @brief Makes sure another admin remains besides the one about to lose the role.
+ *
+ * The admins are locked until the end of the transaction of the repository.
+ *
+ * @param repo The user repository, bound to a transaction.
+ * @return ErrLastAdmin if there is no other admin, or an error if the count fails.
+ */
+func ensureAnotherAdmin(repo repository.UserRepository) error {
+	admins, err := repo.CountUsersByRoleForUpdate(models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+/**
+ * @brief Makes sure the configured admin account exists and has the admin role.
+ *
+ * If no user with the given email exists, it is created with the given username
+ * and password. An existing user is promoted to admin and keeps its password.
+ * Nothing is done if no email is configured.
+ *
+ * @param email The email of the admin account.
+ * @param username The username used if the account has to be created.
+ * @param password The password used if the account has to be created.
+ * @return An error if the admin account cannot be created or promoted.
+ */
+func (s *userService) BootstrapAdmin(email, username, password string) error {
+	if email == "" {
+		log.Printf("No admin account configured, skipping admin bootstrap")
+		return nil
+	}
+
+	user, err := s.userRepository.GetUserByEmail(email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if username == "" || password == "" {
+			return errors.New("admin username and password are required to create the admin account")
+		}
+		verifiedAt := time.Now()
+		return s.CreateUser(&models.User{
+			Username:        username,
+			Email:           email,
+			Password:        password,
+			Role:            models.RoleAdmin,
+			EmailVerifiedAt: &verifiedAt,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+	return s.userRepository.UpdateUserRole(user.ID, models.RoleAdmin)
 }
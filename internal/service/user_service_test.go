@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+/**
+ * @brief Checks that changing the password logs the user out everywhere and revokes their API keys.
+ */
+func TestChangePasswordRevokesCredentials(t *testing.T) {
+	store := newMemoryStore()
+	userService := newMemoryUserService(t, store, nil)
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("old password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &models.User{Username: "erin", Email: "erin@example.com", Password: string(hashed), Role: models.RoleBuyer}
+	store.addUser(user)
+	key := &models.APIKey{UserID: user.ID, Prefix: "nwk_000000000000"}
+	store.addAPIKey(key)
+
+	if _, err := userService.LoginUser(&models.LoginRequest{Email: user.Email, Password: "old password"}, models.ClientInfo{}); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+
+	if err := userService.ChangePassword(user.ID, "old password", "new password"); err != nil {
+		t.Fatalf("failed to change password: %v", err)
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("new password")) != nil {
+		t.Fatalf("expected the new password to be stored")
+	}
+	if user.TokensValidAfter == nil || time.Since(*user.TokensValidAfter) > time.Minute {
+		t.Fatalf("expected the access tokens of the user to be cut off, got %v", user.TokensValidAfter)
+	}
+	for _, session := range store.sessions {
+		if session.RevokedAt == nil {
+			t.Fatalf("expected session %d to be revoked", session.ID)
+		}
+	}
+	if key.RevokedAt == nil {
+		t.Fatalf("expected the API key to be revoked")
+	}
+}
+
+/**
+ * @brief Adds a user with a password to a store and logs them in.
+ *
+ * @param t The test.
+ * @param store The store.
+ * @param userService The service on top of the store.
+ * @return The tokens of the login.
+ */
+func loginMemoryUser(t *testing.T, store *memoryStore, userService UserService) *models.AuthTokens {
+	t.Helper()
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	user := &models.User{Username: "grace", Email: "grace@example.com", Password: string(hashed), Role: models.RoleBuyer}
+	store.addUser(user)
+
+	tokens, err := userService.LoginUser(&models.LoginRequest{Email: user.Email, Password: "password"}, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	return tokens
+}
+
+/**
+ * @brief Checks that refreshing rotates the refresh token and that the rotated token cannot be used again.
+ */
+func TestRefreshTokensRotates(t *testing.T) {
+	store := newMemoryStore()
+	userService := newMemoryUserService(t, store, nil)
+	login := loginMemoryUser(t, store, userService)
+
+	refreshed, err := userService.RefreshTokens(login.RefreshToken, models.ClientInfo{UserAgent: "test"})
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+	if refreshed.RefreshToken == login.RefreshToken || refreshed.AccessToken == login.AccessToken {
+		t.Fatalf("expected new tokens to be issued")
+	}
+	if len(store.refreshTokens) != 2 || store.refreshTokens[0].UsedAt == nil {
+		t.Fatalf("expected the first refresh token to be marked as used")
+	}
+	if store.sessions[0].UserAgent != "test" {
+		t.Fatalf("expected the activity of the session to be updated")
+	}
+
+	next, err := userService.RefreshTokens(refreshed.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("failed to refresh with the rotated token: %v", err)
+	}
+	if next.RefreshToken == refreshed.RefreshToken {
+		t.Fatalf("expected the refresh token to rotate again")
+	}
+}
+
+/**
+ * @brief Checks that replaying a used refresh token revokes the session, its refresh tokens and its access tokens.
+ */
+func TestRefreshTokensReuseRevokesSession(t *testing.T) {
+	store := newMemoryStore()
+	userService := newMemoryUserService(t, store, nil)
+	login := loginMemoryUser(t, store, userService)
+
+	refreshed, err := userService.RefreshTokens(login.RefreshToken, models.ClientInfo{})
+	if err != nil {
+		t.Fatalf("failed to refresh: %v", err)
+	}
+
+	if _, err := userService.RefreshTokens(login.RefreshToken, models.ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the replayed token to be rejected, got %v", err)
+	}
+
+	if store.sessions[0].RevokedAt == nil {
+		t.Fatalf("expected the session to be revoked")
+	}
+	for _, token := range store.refreshTokens {
+		if token.RevokedAt == nil {
+			t.Fatalf("expected refresh token %d to be revoked", token.ID)
+		}
+		if _, revoked := store.revokedTokens[token.AccessTokenID]; !revoked {
+			t.Fatalf("expected access token %s to be revoked", token.AccessTokenID)
+		}
+	}
+
+	if _, err := userService.RefreshTokens(refreshed.RefreshToken, models.ClientInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the latest refresh token to be rejected after the reuse, got %v", err)
+	}
+}
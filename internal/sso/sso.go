@@ -0,0 +1,187 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+/**
+ * @struct Config
+ * @brief Configuration of the OpenID Connect provider users can log in with.
+ *
+ * The issuer URL is used to discover the endpoints and signing keys of the
+ * provider. The client has to be registered at the provider with the redirect
+ * URL, which must point at the callback route of the API.
+ */
+type Config struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+/**
+ * @struct Identity
+ * @brief The user an OpenID Connect provider vouches for.
+ *
+ * This structure contains the issuer and subject, which together identify the
+ * user at the provider for good, and the profile the provider shares.
+ */
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	PreferredUsername string
+	Name              string
+}
+
+/**
+ * @interface Provider
+ * @brief Interface for logging users in with an OpenID Connect provider.
+ *
+ * Logins use the authorization code flow with PKCE: the user is sent to the
+ * provider with the challenge of a secret verifier, and the code the provider
+ * hands back is only exchanged for tokens together with the verifier.
+ */
+type Provider interface {
+	AuthCodeURL(state, nonce, verifier string) string
+	Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error)
+}
+
+/**
+ * @brief provider struct provides the implementation of Provider on top of a discovered OpenID Connect provider.
+ */
+type provider struct {
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+/**
+ * @brief NewProvider discovers an OpenID Connect provider and creates a Provider for it.
+ *
+ * @param ctx The context of the discovery request.
+ * @param config The configuration of the provider.
+ * @return A new Provider instance, or an error if the provider cannot be discovered.
+ */
+func NewProvider(ctx context.Context, config Config) (Provider, error) {
+	discovered, err := oidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+
+	return &provider{
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+/**
+ * @brief Builds the URL of the provider the user has to be sent to in order to log in.
+ *
+ * @param state The opaque value the provider hands back, tying the callback to this login.
+ * @param nonce The value the provider has to include in the ID token.
+ * @param verifier The PKCE verifier, of which only the S256 challenge is sent.
+ * @return The authorization URL.
+ */
+func (p *provider) AuthCodeURL(state, nonce, verifier string) string {
+	return p.oauth2.AuthCodeURL(state,
+		oidc.Nonce(nonce),
+		oauth2.SetAuthURLParam("code_challenge", Challenge(verifier)),
+		oauth2.SetAuthURLParam("code_challenge_method", "S256"),
+	)
+}
+
+/**
+ * @brief Exchanges an authorization code for the identity of the user.
+ *
+ * The ID token returned by the provider must be signed by the provider, issued
+ * to this client, not expired and carry the nonce of the login.
+ *
+ * @param ctx The context of the token request.
+ * @param code The authorization code handed back by the provider.
+ * @param verifier The PKCE verifier of the login.
+ * @param nonce The nonce of the login.
+ * @return The identity of the user, or an error if the exchange or the verification fails.
+ */
+func (p *provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.SetAuthURLParam("code_verifier", verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchanging the authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("the provider returned no ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying the ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("the ID token does not carry the nonce of the login")
+	}
+
+	var claims struct {
+		Email             string `json:"email"`
+		EmailVerified     bool   `json:"email_verified"`
+		PreferredUsername string `json:"preferred_username"`
+		Name              string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("reading the claims of the ID token: %w", err)
+	}
+
+	return &Identity{
+		Issuer:            idToken.Issuer,
+		Subject:           idToken.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		PreferredUsername: claims.PreferredUsername,
+		Name:              claims.Name,
+	}, nil
+}
+
+/**
+ * @brief Generates a random value for the state, nonce or PKCE verifier of a login.
+ *
+ * @return A URL-safe random value, or an error if no random bytes are available.
+ */
+func RandomValue() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+/**
+ * @brief Derives the S256 PKCE challenge of a verifier.
+ *
+ * @param verifier The PKCE verifier.
+ * @return The base64url-encoded SHA-256 hash of the verifier.
+ */
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
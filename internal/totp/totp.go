@@ -0,0 +1,201 @@
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+/**
+ * @brief Parameters of the codes, the defaults of RFC 6238 that every authenticator app supports.
+ */
+const (
+	period = 30 * time.Second
+	digits = 6
+)
+
+/**
+ * @brief Number of periods a code is still accepted before or after its own, to tolerate clock drift.
+ */
+const skew = 1
+
+/**
+ * @brief Number of random bytes of a secret, the length of an HMAC-SHA1 key.
+ */
+const secretLength = 20
+
+/**
+ * @brief Number of random bytes of a recovery code.
+ */
+const recoveryCodeLength = 10
+
+/**
+ * @brief Encoding of the secrets, without the padding authenticator apps do not expect.
+ */
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+/**
+ * @brief Generates a new random secret.
+ *
+ * @return The base32-encoded secret, or an error if no random bytes are available.
+ */
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+/**
+ * @brief Builds the otpauth URL authenticator apps read from a QR code to add an account.
+ *
+ * @param issuer The name of the service, shown by the app.
+ * @param account The name of the account, usually the email of the user.
+ * @param secret The base32-encoded secret.
+ * @return The otpauth URL.
+ */
+func URL(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(int(period.Seconds())))
+
+	return (&url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}).String()
+}
+
+/**
+ * @brief Computes the code of a secret for a time step.
+ *
+ * @param secret The base32-encoded secret.
+ * @param step The number of periods since the Unix epoch.
+ * @return The code, or an error if the secret is not valid base32.
+ */
+func Code(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", digits, value%1000000), nil
+}
+
+/**
+ * @brief Checks whether a string looks like a code rather than a recovery code.
+ *
+ * @param code The string entered by the user.
+ * @return True if it is made of exactly 6 digits.
+ */
+func IsCode(code string) bool {
+	if len(code) != digits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * @brief Validates a code against a secret.
+ *
+ * Codes of the periods right before and after the current one are accepted as
+ * well. A code is only accepted once: its step must be later than the step of
+ * the last accepted code, which the caller stores.
+ *
+ * @param secret The base32-encoded secret.
+ * @param code The code entered by the user.
+ * @param now The current time.
+ * @param lastStep The step of the last accepted code.
+ * @return The step of the code and true if it is valid, or false otherwise.
+ */
+func Validate(secret, code string, now time.Time, lastStep int64) (int64, bool) {
+	if !IsCode(code) {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(period.Seconds())
+	for step := current - skew; step <= current+skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		expected, err := Code(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+/**
+ * @brief Generates a new random recovery code.
+ *
+ * Recovery codes look like "abcd-efgh-ijkl-mnop", in lowercase base32, and can
+ * each be used once instead of a code when the authenticator app is not at hand.
+ *
+ * @return The recovery code, or an error if no random bytes are available.
+ */
+func GenerateRecoveryCode() (string, error) {
+	buf := make([]byte, recoveryCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return group(strings.ToLower(encoding.EncodeToString(buf))), nil
+}
+
+/**
+ * @brief Normalizes a recovery code entered by the user, so that it can be hashed and compared.
+ *
+ * @param code The recovery code entered by the user.
+ * @return The recovery code in lowercase, with its groups separated by single dashes.
+ */
+func NormalizeRecoveryCode(code string) string {
+	return group(strings.ToLower(strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)))
+}
+
+/**
+ * @brief Splits a recovery code into groups of 4 characters separated by dashes.
+ *
+ * @param code The recovery code without separators.
+ * @return The grouped recovery code.
+ */
+func group(code string) string {
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
@@ -0,0 +1,168 @@
+package totp
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+)
+
+/**
+ * @brief The secret of the test vectors of RFC 6238, "12345678901234567890" in base32.
+ */
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+/**
+ * @brief Checks the codes against the SHA1 test vectors of RFC 6238, truncated to 6 digits.
+ */
+func TestCodeRFC6238(t *testing.T) {
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tc := range cases {
+		got, err := Code(rfcSecret, tc.unix/30)
+		if err != nil {
+			t.Fatalf("failed to compute code: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("time %d: expected %s, got %s", tc.unix, tc.want, got)
+		}
+	}
+
+	if got, _ := Code("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1); got != "287082" {
+		t.Errorf("expected lowercase secrets to be accepted, got %s", got)
+	}
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Errorf("expected an invalid secret to be refused")
+	}
+}
+
+/**
+ * @brief Checks that codes of the previous, current and next periods are accepted, and no others.
+ */
+func TestValidateWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / 30
+
+	for offset := int64(-2); offset <= 2; offset++ {
+		code, err := Code(rfcSecret, current+offset)
+		if err != nil {
+			t.Fatalf("failed to compute code: %v", err)
+		}
+		step, valid := Validate(rfcSecret, code, now, 0)
+		want := offset >= -1 && offset <= 1
+		if valid != want {
+			t.Errorf("offset %d: expected valid %v, got %v", offset, want, valid)
+		}
+		if valid && step != current+offset {
+			t.Errorf("offset %d: expected step %d, got %d", offset, current+offset, step)
+		}
+	}
+
+	for _, code := range []string{"", "12345", "1234567", "abcdef", "12 456"} {
+		if _, valid := Validate(rfcSecret, code, now, 0); valid {
+			t.Errorf("%q: expected a malformed code to be refused", code)
+		}
+	}
+}
+
+/**
+ * @brief Checks that a code is refused once its step, or a later one, has been accepted.
+ */
+func TestValidateRejectsReplayedStep(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / 30
+
+	code, err := Code(rfcSecret, current)
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+	step, valid := Validate(rfcSecret, code, now, 0)
+	if !valid {
+		t.Fatalf("expected the code to be valid")
+	}
+
+	if _, valid := Validate(rfcSecret, code, now, step); valid {
+		t.Errorf("expected the code of the last accepted step to be refused")
+	}
+	if _, valid := Validate(rfcSecret, code, now.Add(30*time.Second), step); valid {
+		t.Errorf("expected the code to stay refused in the next period")
+	}
+
+	previous, err := Code(rfcSecret, current-1)
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+	if _, valid := Validate(rfcSecret, previous, now, step); valid {
+		t.Errorf("expected the code of an earlier step to be refused")
+	}
+
+	next, err := Code(rfcSecret, current+1)
+	if err != nil {
+		t.Fatalf("failed to compute code: %v", err)
+	}
+	if _, valid := Validate(rfcSecret, next, now, step); !valid {
+		t.Errorf("expected the code of a later step to be accepted")
+	}
+}
+
+/**
+ * @brief Checks the format of the generated recovery codes and the normalization of entered ones.
+ */
+func TestRecoveryCode(t *testing.T) {
+	code, err := GenerateRecoveryCode()
+	if err != nil {
+		t.Fatalf("failed to generate recovery code: %v", err)
+	}
+	if !regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`).MatchString(code) {
+		t.Fatalf("unexpected recovery code %q", code)
+	}
+	if IsCode(code) {
+		t.Fatalf("expected a recovery code not to be taken for a TOTP code")
+	}
+
+	cases := map[string]string{
+		"abcd-efgh-ijkl-mnop":    "abcd-efgh-ijkl-mnop",
+		"ABCDEFGHIJKLMNOP":       "abcd-efgh-ijkl-mnop",
+		" abcd efgh--ijkl mnop ": "abcd-efgh-ijkl-mnop",
+		"Abcd-Efgh-Ijkl-Mnop-Qr": "abcd-efgh-ijkl-mnop-qr",
+	}
+	for entered, want := range cases {
+		if got := NormalizeRecoveryCode(entered); got != want {
+			t.Errorf("%q: expected %q, got %q", entered, want, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks that generated secrets are valid base32 and that the otpauth URL carries the parameters of the codes.
+ */
+func TestSecretURL(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	if _, err := Code(secret, 1); err != nil || len(secret) != 32 {
+		t.Fatalf("expected a 32 character base32 secret, got %q: %v", secret, err)
+	}
+
+	parsed, err := url.Parse(URL("NewWorld", "dave@example.com", secret))
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	query := parsed.Query()
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/NewWorld:dave@example.com" {
+		t.Fatalf("unexpected URL %s", parsed)
+	}
+	if query.Get("secret") != secret || query.Get("digits") != "6" || query.Get("period") != "30" || query.Get("algorithm") != "SHA1" {
+		t.Fatalf("unexpected parameters %v", query)
+	}
+}
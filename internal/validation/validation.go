@@ -0,0 +1,173 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+	"github.com/go-playground/validator/v10"
+)
+
+/**
+ * @brief Bounds on the length of passwords.
+ *
+ * bcrypt only uses the first 72 bytes of a password, so longer ones are rejected
+ * rather than silently truncated.
+ */
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+/**
+ * @brief Usernames are 3 to 32 letters, digits, dots, dashes or underscores.
+ */
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,32}$`)
+
+/**
+ * @brief Validator shared by every request, configured with the custom rules of the API.
+ */
+var validate = newValidator()
+
+/**
+ * @brief Creates the validator and registers the custom rules.
+ *
+ * Fields are reported by their JSON name, so that errors match the request body.
+ *
+ * @return The configured validator.
+ */
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	v.RegisterValidation("username", func(fl validator.FieldLevel) bool {
+		return usernamePattern.MatchString(fl.Field().String())
+	})
+	v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return isStrongPassword(fl.Field().String())
+	})
+
+	return v
+}
+
+/**
+ * @brief Checks that a password is long enough and mixes letters and digits.
+ *
+ * @param password The password to check.
+ * @return True if the password is strong enough.
+ */
+func isStrongPassword(password string) bool {
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return false
+	}
+
+	var hasLetter, hasDigit bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
+/**
+ * @brief Validates a request against the rules declared in its validate tags.
+ *
+ * @param request A pointer to the request structure.
+ * @return The failing fields with the reason they were rejected, or nil if the request is valid.
+ */
+func Struct(request interface{}) []models.FieldError {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []models.FieldError{{Field: "body", Reason: "is not valid"}}
+	}
+
+	fieldErrors := make([]models.FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		fieldErrors = append(fieldErrors, models.FieldError{
+			Field:  fieldPath(fieldError.Namespace()),
+			Reason: reason(fieldError),
+		})
+	}
+	return fieldErrors
+}
+
+/**
+ * @brief Turns the namespace of a failing field into its path in the request body.
+ *
+ * @param namespace The namespace reported by the validator, e.g. "CheckoutRequest.orderItems[0].quantity".
+ * @return The path without the name of the request structure, e.g. "orderItems[0].quantity".
+ */
+func fieldPath(namespace string) string {
+	if i := strings.Index(namespace, "."); i >= 0 {
+		return namespace[i+1:]
+	}
+	return namespace
+}
+
+/**
+ * @brief Describes why a field failed a rule.
+ *
+ * @param fieldError The failing rule.
+ * @return A human readable reason.
+ */
+func reason(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "username":
+		return "must be 3 to 32 letters, digits, dots, dashes or underscores"
+	case "password":
+		return fmt.Sprintf("must be %d to %d characters long and contain letters and digits", minPasswordLength, maxPasswordLength)
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "gt":
+		return "must be greater than " + fieldError.Param()
+	case "min":
+		if fieldError.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at least %s items", fieldError.Param())
+		}
+		return fmt.Sprintf("must be at least %s", lengthOrValue(fieldError))
+	case "max":
+		if fieldError.Kind() == reflect.Slice {
+			return fmt.Sprintf("must contain at most %s items", fieldError.Param())
+		}
+		return fmt.Sprintf("must be at most %s", lengthOrValue(fieldError))
+	default:
+		return "is not valid"
+	}
+}
+
+/**
+ * @brief Describes the bound of a min or max rule.
+ *
+ * @param fieldError The failing rule.
+ * @return The bound, expressed in characters for strings.
+ */
+func lengthOrValue(fieldError validator.FieldError) string {
+	if fieldError.Kind() == reflect.String {
+		return fieldError.Param() + " characters long"
+	}
+	return fieldError.Param()
+}
@@ -0,0 +1,142 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ICOMP-UNC/newworld-gastonsegura2908.git/internal/models"
+)
+
+/**
+ * @brief Checks the usernames accepted by the username rule.
+ */
+func TestUsernameRule(t *testing.T) {
+	cases := []struct {
+		username string
+		want     bool
+	}{
+		{"bob", true},
+		{"alice.smith-01_x", true},
+		{strings.Repeat("a", 32), true},
+		{"ab", false},
+		{strings.Repeat("a", 33), false},
+		{"alice smith", false},
+		{"alice@example", false},
+		{"ålice", false},
+		{"", false},
+	}
+	for _, tc := range cases {
+		request := models.RegisterUserRequest{Username: tc.username, Email: "alice@example.com", Password: "password1"}
+		if got := Struct(&request) == nil; got != tc.want {
+			t.Errorf("%q: expected valid %v, got %v", tc.username, tc.want, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks the passwords accepted by the password rule.
+ */
+func TestPasswordRule(t *testing.T) {
+	cases := []struct {
+		password string
+		want     bool
+	}{
+		{"password1", true},
+		{"1234567a", true},
+		{"pässwörd1", true},
+		{strings.Repeat("a", 71) + "1", true},
+		{"passwo1", false},
+		{strings.Repeat("a", 72) + "1", false},
+		{"password", false},
+		{"12345678", false},
+		{"!!!!!!!!", false},
+	}
+	for _, tc := range cases {
+		if got := isStrongPassword(tc.password); got != tc.want {
+			t.Errorf("%q: expected %v, got %v", tc.password, tc.want, got)
+		}
+	}
+
+	fields := Struct(&models.RegisterUserRequest{Username: "alice", Email: "alice@example.com", Password: "password"})
+	want := []models.FieldError{{Field: "password", Reason: "must be 8 to 72 characters long and contain letters and digits"}}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected %+v, got %+v", want, fields)
+	}
+}
+
+/**
+ * @brief Checks that failing fields are reported by their path in the request body, with their reason.
+ */
+func TestStructFieldErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		request interface{}
+		want    []models.FieldError
+	}{
+		{
+			"valid request",
+			&models.CheckoutRequest{OrderItems: []models.OrderItemRequest{{ProductID: 1, Quantity: 1}}},
+			nil,
+		},
+		{
+			"nested item",
+			&models.CheckoutRequest{OrderItems: []models.OrderItemRequest{{ProductID: 1, Quantity: 1}, {ProductID: 2, Quantity: 0}}},
+			[]models.FieldError{{Field: "orderItems[1].quantity", Reason: "must be greater than 0"}},
+		},
+		{
+			"missing items",
+			&models.CheckoutRequest{},
+			[]models.FieldError{{Field: "orderItems", Reason: "is required"}},
+		},
+		{
+			"too many items",
+			&models.CheckoutRequest{OrderItems: make([]models.OrderItemRequest, 51)},
+			[]models.FieldError{{Field: "orderItems", Reason: "must contain at most 50 items"}},
+		},
+		{
+			"several fields",
+			&models.RegisterUserRequest{Username: "a", Email: "not an email"},
+			[]models.FieldError{
+				{Field: "username", Reason: "must be 3 to 32 letters, digits, dots, dashes or underscores"},
+				{Field: "email", Reason: "must be a valid email address"},
+				{Field: "password", Reason: "is required"},
+			},
+		},
+		{
+			"string length and number bounds",
+			&models.CreateAPIKeyRequest{Name: strings.Repeat("a", 101), Scopes: []string{models.ScopeOrdersRead, "admin"}, ExpiresInDays: 400},
+			[]models.FieldError{
+				{Field: "name", Reason: "must be at most 100 characters long"},
+				{Field: "scopes[1]", Reason: "must be one of: offers:read, orders:read, orders:write"},
+				{Field: "expires_in_days", Reason: "must be at most 365"},
+			},
+		},
+		{
+			"oneof",
+			&models.OrderStatusUpdateRequest{Status: "lost"},
+			[]models.FieldError{{Field: "status", Reason: "must be one of: preparing, processing, shipped, delivered, cancelled"}},
+		},
+	}
+	for _, tc := range cases {
+		if got := Struct(tc.request); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %+v, got %+v", tc.name, tc.want, got)
+		}
+	}
+}
+
+/**
+ * @brief Checks that the name of the request structure is stripped from the namespace of a field.
+ */
+func TestFieldPath(t *testing.T) {
+	cases := map[string]string{
+		"CheckoutRequest.orderItems[0].quantity": "orderItems[0].quantity",
+		"RegisterRequest.email":                  "email",
+		"email":                                  "email",
+	}
+	for namespace, want := range cases {
+		if got := fieldPath(namespace); got != want {
+			t.Errorf("%q: expected %q, got %q", namespace, want, got)
+		}
+	}
+}